@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/types"
+)
+
+// process returns the process to create with the definition
+func (definition *ProcessDefinition) process() *Process {
+	return &Process{
+		IdProcess:   definition.IdProcess,
+		Type:        definition.Type,
+		Name:        definition.Name,
+		Description: definition.Description,
+		DateFrom:    definition.DateFrom,
+		DateTo:      definition.DateTo,
+		TimeFrom:    definition.TimeFrom,
+		TimeTo:      definition.TimeTo,
+		DaysOff:     definition.DaysOff,
+		Monitor:     definition.Monitor,
+		Status:      definition.Status,
+	}
+}
+
+// validateDefinitions validates every definition, the fields of the errors are prefixed with the definition
+func validateDefinitions(definitions []*ProcessDefinition) error {
+	if len(definitions) == 0 {
+		return &ValidationError{Fields: []*FieldError{{Field: "processes", Code: validationRequired, Message: "is required"}}}
+	}
+
+	validation := &ValidationError{}
+	ids := make(map[string]bool, len(definitions))
+
+	for i, definition := range definitions {
+		prefix := fmt.Sprintf("processes[%d]", i)
+		if definition == nil {
+			validation.Fields = append(validation.Fields, &FieldError{Field: prefix, Code: validationRequired, Message: "is required"})
+			continue
+		}
+
+		if err := validate(definition); err != nil {
+			for _, field := range err.(*ValidationError).Fields {
+				name := prefix
+				if field.Field != "" {
+					name += "." + field.Field
+				}
+				validation.Fields = append(validation.Fields, &FieldError{Field: name, Code: field.Code, Message: field.Message})
+			}
+		}
+
+		if ids[definition.IdProcess] {
+			validation.Fields = append(validation.Fields, &FieldError{Field: prefix + ".id_process", Code: "duplicated", Message: "is duplicated"})
+		}
+		ids[definition.IdProcess] = true
+	}
+
+	if len(validation.Fields) > 0 {
+		return validation
+	}
+
+	return nil
+}
+
+// planProcesses compares the processes with their definitions, returning the changes to match them.
+// The status isn't compared, it's only set when creating. The processes without definition are deleted when pruning
+func planProcesses(processes ListProcess, definitions []*ProcessDefinition, prune bool) []*ProcessChange {
+	current := make(map[string]*Process, len(processes))
+	for _, process := range processes {
+		current[process.IdProcess] = process
+	}
+
+	changes := make([]*ProcessChange, 0)
+	defined := make(map[string]bool, len(definitions))
+
+	for _, definition := range definitions {
+		defined[definition.IdProcess] = true
+
+		process, ok := current[definition.IdProcess]
+		if !ok {
+			changes = append(changes, &ProcessChange{Op: ChangeCreate, IdProcess: definition.IdProcess, definition: definition})
+			continue
+		}
+
+		if fields := diffProcess(process, definition); len(fields) > 0 {
+			changes = append(changes, &ProcessChange{
+				Op:         ChangeUpdate,
+				IdProcess:  process.IdProcess,
+				Version:    process.Version,
+				Fields:     fields,
+				definition: definition,
+				process:    process,
+			})
+		}
+	}
+
+	if prune {
+		deleted := make([]*ProcessChange, 0)
+		for _, process := range processes {
+			if !defined[process.IdProcess] {
+				deleted = append(deleted, &ProcessChange{Op: ChangeDelete, IdProcess: process.IdProcess, Version: process.Version, process: process})
+			}
+		}
+
+		sort.Slice(deleted, func(i, j int) bool { return deleted[i].IdProcess < deleted[j].IdProcess })
+		changes = append(changes, deleted...)
+	}
+
+	return changes
+}
+
+// diffProcess returns the fields of the process that are different on the definition
+func diffProcess(process *Process, definition *ProcessDefinition) []*FieldChange {
+	fields := make([]*FieldChange, 0)
+	compare := func(field string, from, to interface{}) {
+		if fmt.Sprint(from) != fmt.Sprint(to) {
+			fields = append(fields, &FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	compare("type", process.Type, definition.Type)
+	compare("name", process.Name, definition.Name)
+	compare("description", process.Description, definition.Description)
+	compare("date_from", optional(process.DateFrom), optional(definition.DateFrom))
+	compare("date_to", optional(process.DateTo), optional(definition.DateTo))
+	compare("time_from", optional(process.TimeFrom), optional(definition.TimeFrom))
+	compare("time_to", optional(process.TimeTo), optional(definition.TimeTo))
+	compare("days_off", daysOff(process.DaysOff), daysOff(definition.DaysOff))
+	compare("monitor", process.Monitor, definition.Monitor)
+
+	return fields
+}
+
+// optional returns the value, or nil when it isn't set
+func optional[T ~string](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return string(*value)
+}
+
+// daysOff returns the days sorted, or nil when there aren't days off
+func daysOff(days *types.ListDay) interface{} {
+	if days == nil || len(*days) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(*days))
+	for _, day := range *days {
+		sorted = append(sorted, string(day))
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// applyChange applies a change of the plan, an update keeps the status of the process
+func (interactor *Interactor) applyChange(change *ProcessChange) error {
+	switch change.Op {
+	case ChangeCreate:
+		return interactor.CreateProcess(change.definition.process())
+	case ChangeUpdate:
+		process := change.definition.process()
+		process.Status = change.process.Status
+		process.Version = change.process.Version
+		if err := interactor.UpdateProcess(process); err != nil {
+			return err
+		}
+		change.Version = process.Version
+		return nil
+	default:
+		return interactor.DeleteProcess(change.IdProcess, change.process.Version)
+	}
+}
+
+// changeError tells which change failed, keeping the code of the error
+func changeError(change *ProcessChange, err error) error {
+	return errors.New(errors.LevelError, errorCode(err), "error applying the %s of process %s: %s", change.Op, change.IdProcess, err)
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultRetries   = 3
+	DefaultRetryWait = 200 * time.Millisecond
+	DefaultHeartbeat = 30 * time.Second
+)
+
+// Client is a client of the monitor api
+type Client struct {
+	url        string
+	httpClient *http.Client
+	headers    http.Header
+	timeout    time.Duration
+	retries    int
+	retryWait  time.Duration
+}
+
+// NewClient ...
+func NewClient(url string, options ...ClientOption) *Client {
+	client := &Client{
+		url:        strings.TrimRight(url, "/"),
+		httpClient: http.DefaultClient,
+		headers:    make(http.Header),
+		timeout:    DefaultTimeout,
+		retries:    DefaultRetries,
+		retryWait:  DefaultRetryWait,
+	}
+
+	client.Reconfigure(options...)
+
+	return client
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	headers     http.Header
+	contentType string
+	body        interface{}
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do executes a request, retrying it when it fails with a network or server error.
+// The requests that change the processes are sent with an idempotency key, so they are safe to retry
+func (client *Client) do(ctx context.Context, req *request) (*response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if raw, ok := req.body.([]byte); ok {
+			body = raw
+		} else if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+
+	headers := make(http.Header)
+	for key, values := range client.headers {
+		headers[key] = values
+	}
+	for key, values := range req.headers {
+		headers[key] = values
+	}
+
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		headers.Set("Content-Type", contentType)
+	}
+
+	if req.method != http.MethodGet && headers.Get(HeaderIdempotencyKey) == "" {
+		headers.Set(HeaderIdempotencyKey, newIdempotencyKey())
+	}
+
+	address := client.url + req.path
+	if len(req.query) > 0 {
+		address += "?" + req.query.Encode()
+	}
+
+	var resp *response
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = client.attempt(ctx, req.method, address, headers, body)
+		if attempt >= client.retries || !retryable(resp, err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(client.retryWait << uint(attempt)):
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.status >= http.StatusBadRequest {
+		return resp, newError(resp.status, resp.body)
+	}
+
+	return resp, nil
+}
+
+func (client *Client) attempt(ctx context.Context, method, address string, headers http.Header, body []byte) (*response, error) {
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, method, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header = headers.Clone()
+
+	httpResponse, err := client.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{
+		status: httpResponse.StatusCode,
+		header: httpResponse.Header,
+		body:   responseBody,
+	}, nil
+}
+
+// retryable checks if a request failed with a transient error
+func retryable(resp *response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch {
+	case resp.status == http.StatusTooManyRequests, resp.status >= http.StatusInternalServerError:
+		return true
+	case resp.status == http.StatusConflict:
+		return IsCode(newError(resp.status, resp.body), ErrorCodeIdempotencyInProgress)
+	default:
+		return false
+	}
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,683 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+	"github.com/joaosoft/monitor"
+	"github.com/joaosoft/types"
+)
+
+// TestMain runs the tests on the root of the repository, where the monitor finds its configuration
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newMonitor starts an in process monitor with the memory storage, returning a client to it
+func newMonitor(t *testing.T) *Client {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("error getting a free port: %s", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	m, err := monitor.NewMonitor(
+		monitor.WithConfiguration(&monitor.MonitorConfig{Host: address}),
+		monitor.WithManager(manager.NewManager(manager.WithRunInBackground(true))),
+		monitor.WithLogLevel(logger.ErrorLevel),
+		monitor.WithStorage(monitor.NewStorageMemory(nil)),
+	)
+	if err != nil {
+		t.Fatalf("error creating the monitor: %s", err)
+	}
+
+	if err := m.Start(); err != nil {
+		t.Fatalf("error starting the monitor: %s", err)
+	}
+	t.Cleanup(func() { m.Stop() })
+
+	// the web server is started on background
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", address); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return NewClient(fmt.Sprintf("http://%s", address), WithRetries(0, 0))
+}
+
+func newProcess(id string) *Process {
+	status := StatusStopped
+	return &Process{
+		IdProcess: id,
+		Type:      "job",
+		Name:      "process " + id,
+		Monitor:   "team",
+		Status:    &status,
+	}
+}
+
+// TestWireTypes checks that the types of the client have the same json fields of the types of the monitor
+func TestWireTypes(t *testing.T) {
+	tests := []struct {
+		client  interface{}
+		monitor interface{}
+	}{
+		{Process{}, monitor.Process{}},
+		{DeleteProcessesResponse{}, monitor.DeleteProcessesResponse{}},
+		{BatchOperationRequest{}, monitor.BatchOperationRequest{}},
+		{BatchOperationResult{}, monitor.BatchOperationResult{}},
+		{BatchProcessesResponse{}, monitor.BatchProcessesResponse{}},
+		{batchProcessesBody{}, monitor.BatchProcessesRequest{}.Body},
+		{processStatusBody{}, monitor.UpdateProcessStatusRequest{}.Body},
+		{ProcessDefinition{}, monitor.ProcessDefinition{}},
+		{ApplyProcessesResponse{}, monitor.ApplyProcessesResponse{}},
+		{ProcessChange{}, monitor.ProcessChange{}},
+		{FieldChange{}, monitor.FieldChange{}},
+		{applyProcessesBody{}, monitor.ApplyProcessesRequest{}.Body},
+		{ImportProcessesResponse{}, monitor.ImportProcessesResponse{}},
+		{ProcessWindow{}, monitor.ProcessWindow{}},
+		{SimulateProcessResponse{}, monitor.SimulateProcessResponse{}},
+		{ScheduleInterval{}, monitor.ScheduleInterval{}},
+		{simulateProcessBody{}, monitor.SimulateProcessRequest{}.Body},
+		{ProcessHistory{}, monitor.ProcessHistory{}},
+		{ProcessRun{}, monitor.ProcessRun{}},
+		{StatusPageResponse{}, monitor.StatusPageResponse{}},
+		{StatusPageGroupResponse{}, monitor.StatusPageGroupResponse{}},
+		{ProcessStatus{}, monitor.ProcessStatus{}},
+		{Problem{}, monitor.Problem{}},
+		{FieldError{}, monitor.FieldError{}},
+	}
+
+	fields := func(value interface{}) []string {
+		var names []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			if field := typ.Field(i); field.PkgPath == "" {
+				names = append(names, strings.Split(field.Tag.Get("json"), ",")[0])
+			}
+		}
+		return names
+	}
+
+	for _, test := range tests {
+		if client, monitor := fields(test.client), fields(test.monitor); !reflect.DeepEqual(client, monitor) {
+			t.Errorf("%T has the fields %v, expected %v", test.client, client, monitor)
+		}
+	}
+}
+
+func TestProcesses(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	if err := client.CreateProcess(ctx, newProcess("a")); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if err := client.CreateProcess(ctx, newProcess("b")); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	if err := client.CreateProcess(ctx, newProcess("a")); !IsCode(err, ErrorCodeProcessAlreadyExists) {
+		t.Errorf("expected %s, got %v", ErrorCodeProcessAlreadyExists, err)
+	}
+
+	process, err := client.GetProcess(ctx, "a")
+	if err != nil {
+		t.Fatalf("error getting process: %s", err)
+	}
+	if process.Name != "process a" || process.Version != 1 {
+		t.Errorf("unexpected process %+v", process)
+	}
+
+	processes, err := client.GetProcesses(ctx, url.Values{"id_process": {"b"}})
+	if err != nil {
+		t.Fatalf("error getting processes: %s", err)
+	}
+	if len(processes) != 1 || processes[0].IdProcess != "b" {
+		t.Errorf("unexpected processes %+v", processes)
+	}
+
+	process.Name = "renamed"
+	if err := client.UpdateProcess(ctx, process); err != nil {
+		t.Fatalf("error updating process: %s", err)
+	}
+	if process.Version != 2 {
+		t.Errorf("expected version 2, got %d", process.Version)
+	}
+
+	process.Version = 1
+	if err := client.UpdateProcess(ctx, process); !IsVersionMismatch(err) {
+		t.Errorf("expected a version mismatch, got %v", err)
+	}
+
+	version, err := client.PatchProcess(ctx, "a", map[string]interface{}{"description": "patched"}, 2)
+	if err != nil {
+		t.Fatalf("error patching process: %s", err)
+	}
+	if version != 3 {
+		t.Errorf("expected version 3, got %d", version)
+	}
+
+	if err := client.DeleteProcess(ctx, "a", 0); err != nil {
+		t.Fatalf("error deleting process: %s", err)
+	}
+	if _, err := client.GetProcess(ctx, "a"); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if err := client.RestoreProcess(ctx, "a"); err != nil {
+		t.Fatalf("error restoring process: %s", err)
+	}
+
+	response, err := client.DeleteProcesses(ctx, url.Values{"type": {"job"}}, "")
+	if !IsCode(err, ErrorCodeConfirmationRequired) || response == nil || response.Count != 2 {
+		t.Fatalf("expected a confirmation for 2 processes, got %+v %v", response, err)
+	}
+	if response, err = client.DeleteProcesses(ctx, url.Values{"type": {"job"}}, response.Confirmation); err != nil || response.Count != 2 {
+		t.Errorf("expected 2 deleted processes, got %+v %v", response, err)
+	}
+}
+
+func TestProcessStatus(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	if err := client.UpdateProcessStatus(ctx, "missing", StatusRunning); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if err := client.CreateProcess(ctx, newProcess("a")); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	if err := client.CheckProcessStatus(ctx, "a", StatusRunning); err != nil {
+		t.Errorf("expected the process to be able to start, got %s", err)
+	}
+	if err := client.UpdateProcessStatus(ctx, "a", StatusRunning); err != nil {
+		t.Fatalf("error starting process: %s", err)
+	}
+	if err := client.UpdateProcessStatus(ctx, "a", StatusRunning); !IsCode(err, ErrorCodeAlreadyRunning) {
+		t.Errorf("expected %s, got %v", ErrorCodeAlreadyRunning, err)
+	}
+	if err := client.UpdateProcessStatus(ctx, "a", StatusStopped); err != nil {
+		t.Errorf("error stopping process: %s", err)
+	}
+
+	yesterday := types.Date(time.Now().AddDate(0, 0, -1).Format("02-01-2006"))
+	closed := newProcess("closed")
+	closed.DateTo = &yesterday
+	if err := client.CreateProcess(ctx, closed); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if err := client.CheckProcessStatus(ctx, "closed", StatusRunning); !IsCode(err, ErrorCodeWindowClosed) {
+		t.Errorf("expected %s, got %v", ErrorCodeWindowClosed, err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	client := newMonitor(t)
+
+	err := client.CreateProcess(context.Background(), &Process{IdProcess: "a"})
+	e, ok := err.(*Error)
+	if !ok || e.Code != ErrorCodeValidationFailed || e.Status != http.StatusBadRequest {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(e.Errors) != 2 {
+		t.Errorf("expected the errors of type and name, got %+v", e.Errors)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	client := newMonitor(t)
+
+	response, err := client.Batch(context.Background(), BatchModeAtomic, []*BatchOperationRequest{
+		{Op: BatchOperationCreate, Body: []byte(`{"id_process": "a", "type": "job", "name": "a"}`)},
+		{Op: BatchOperationStatus, IdProcess: "a", Status: StatusRunning},
+	})
+	if err != nil {
+		t.Fatalf("error executing batch: %s", err)
+	}
+	if response.Succeeded != 2 || response.Failed != 0 {
+		t.Errorf("unexpected batch response %+v", response)
+	}
+
+	// a null operation fails without failing the others
+	response, err = client.Batch(context.Background(), BatchModeBestEffort, []*BatchOperationRequest{
+		nil,
+		{Op: BatchOperationCreate, Body: []byte(`{"id_process": "b", "type": "job", "name": "b"}`)},
+	})
+	if err != nil {
+		t.Fatalf("error executing batch: %s", err)
+	}
+	if response.Succeeded != 1 || response.Failed != 1 || response.Results[0].Error == nil || response.Results[0].Error.Code != ErrorCodeValidationFailed {
+		t.Errorf("expected the null operation to fail the validation, got %+v", response)
+	}
+}
+
+func TestApplyProcesses(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"kept", "changed", "unmanaged"} {
+		if err := client.CreateProcess(ctx, &Process{IdProcess: id, Type: "job", Name: id}); err != nil {
+			t.Fatalf("error creating process %s: %s", id, err)
+		}
+	}
+
+	timeFrom := types.Time("08:00:00")
+	definitions := []*ProcessDefinition{
+		{IdProcess: "kept", Type: "job", Name: "kept"},
+		{IdProcess: "changed", Type: "job", Name: "new name", TimeFrom: &timeFrom},
+		{IdProcess: "created", Type: "job", Name: "created"},
+	}
+
+	plan, err := client.PlanProcesses(ctx, definitions, true)
+	if err != nil {
+		t.Fatalf("error planning: %s", err)
+	}
+	if !plan.DryRun || plan.Created != 1 || plan.Updated != 1 || plan.Deleted != 1 || len(plan.Changes) != 3 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if fields := plan.Changes[0].Fields; plan.Changes[0].IdProcess != "changed" || len(fields) != 2 || fields[0].Field != "name" || fields[1].Field != "time_from" {
+		t.Errorf("unexpected update %+v", plan.Changes[0])
+	}
+	if _, err := client.GetProcess(ctx, "created"); !IsNotFound(err) {
+		t.Errorf("the plan shouldn't create the process, got %v", err)
+	}
+
+	// without prune the unmanaged processes are kept
+	applied, err := client.ApplyProcesses(ctx, definitions, false)
+	if err != nil {
+		t.Fatalf("error applying: %s", err)
+	}
+	if applied.DryRun || applied.Created != 1 || applied.Updated != 1 || applied.Deleted != 0 {
+		t.Fatalf("unexpected apply %+v", applied)
+	}
+
+	if process, err := client.GetProcess(ctx, "changed"); err != nil || process.Name != "new name" {
+		t.Errorf("the process wasn't updated, got %+v, %v", process, err)
+	}
+	if _, err := client.GetProcess(ctx, "unmanaged"); err != nil {
+		t.Errorf("the unmanaged process should be kept, got %s", err)
+	}
+
+	if plan, err = client.PlanProcesses(ctx, definitions, false); err != nil || len(plan.Changes) != 0 {
+		t.Errorf("expected no changes after applying, got %+v, %v", plan, err)
+	}
+
+	// a duplicated definition is invalid
+	_, err = client.ApplyProcesses(ctx, append(definitions, definitions[0]), true)
+	if !IsCode(err, ErrorCodeValidationFailed) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestSimulateProcess(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	timeFrom, timeTo := types.Time("08:00:00"), types.Time("17:59:59")
+	daysOff := types.ListDay{"saturday", "sunday"}
+	if err := client.CreateProcess(ctx, &Process{IdProcess: "office", Type: "job", Name: "office", TimeFrom: &timeFrom, TimeTo: &timeTo, DaysOff: &daysOff}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	// from monday to monday
+	from := time.Date(2024, 3, 11, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 7)
+
+	simulation, err := client.SimulateProcess(ctx, "office", nil, from, to)
+	if err != nil {
+		t.Fatalf("error simulating: %s", err)
+	}
+	if len(simulation.Allowed) != 5 || len(simulation.Blocked) == 0 {
+		t.Fatalf("expected 5 allowed intervals, got %+v", simulation.Allowed)
+	}
+	for _, interval := range simulation.Allowed {
+		if interval.To.Sub(interval.From) != 10*time.Hour || interval.From.Hour() != 8 {
+			t.Errorf("expected the intervals from 08:00 to 18:00, got %s to %s", interval.From, interval.To)
+		}
+	}
+	for _, interval := range simulation.Blocked {
+		if len(interval.Reasons) == 0 {
+			t.Errorf("expected the reasons of the blocked interval from %s to %s", interval.From, interval.To)
+		}
+	}
+
+	// without days off it can also be started on the weekend
+	simulation, err = client.SimulateProcess(ctx, "office", &ProcessWindow{DaysOff: &types.ListDay{}}, from, to)
+	if err != nil {
+		t.Fatalf("error simulating: %s", err)
+	}
+	if len(simulation.Allowed) != 7 {
+		t.Errorf("expected 7 allowed intervals, got %d", len(simulation.Allowed))
+	}
+
+	if _, err := client.SimulateProcess(ctx, "missing", nil, from, to); !IsNotFound(err) {
+		t.Errorf("expected the process to not be found, got %v", err)
+	}
+	if _, err := client.SimulateProcess(ctx, "", nil, from, to); !IsCode(err, ErrorCodeValidationFailed) {
+		t.Errorf("expected a validation error without process, got %v", err)
+	}
+	if _, err := client.SimulateProcess(ctx, "office", nil, to, from); !IsCode(err, ErrorCodeValidationFailed) {
+		t.Errorf("expected a validation error with the range inverted, got %v", err)
+	}
+}
+
+func TestProcessSchedule(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	yesterday := types.Date(time.Now().AddDate(0, 0, -1).Format("02-01-2006"))
+	client.CreateProcess(ctx, &Process{IdProcess: "open", Type: "job", Name: "open"})
+	client.CreateProcess(ctx, &Process{IdProcess: "closed", Type: "job", Name: "closed", DateTo: &yesterday})
+
+	process, err := client.GetProcess(ctx, "open")
+	if err != nil || process.CanExecuteNow != nil || process.NextWindowStart != nil {
+		t.Fatalf("expected the schedule only when included, got %+v, %v", process, err)
+	}
+
+	process, err = client.GetProcess(ctx, "open", IncludeSchedule)
+	if err != nil || process.CanExecuteNow == nil || !*process.CanExecuteNow || process.NextWindowStart == nil || process.NextWindowEnd != nil {
+		t.Fatalf("expected the process to be startable until any time, got %+v, %v", process, err)
+	}
+
+	// a running process can't be started, but its window is open
+	client.UpdateProcessStatus(ctx, "open", StatusRunning)
+	processes, err := client.GetProcesses(ctx, url.Values{"include": []string{IncludeSchedule}})
+	if err != nil || len(processes) != 2 {
+		t.Fatalf("error getting processes: %v", err)
+	}
+	for _, process := range processes {
+		if process.CanExecuteNow == nil || *process.CanExecuteNow {
+			t.Errorf("expected the process %s to not be startable", process.IdProcess)
+		}
+		if (process.NextWindowStart != nil) != (process.IdProcess == "open") {
+			t.Errorf("unexpected next window of the process %s, got %v", process.IdProcess, process.NextWindowStart)
+		}
+	}
+
+	if _, err := client.GetProcess(ctx, "open", "history"); !IsCode(err, ErrorCodeValidationFailed) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	timeFrom, timeTo := types.Time("08:00:00"), types.Time("18:00:00")
+	daysOff := types.ListDay{"saturday", "sunday"}
+	processes := []*Process{
+		{IdProcess: "backup", Type: "cron", Name: "Backup, nightly", TimeFrom: &timeFrom, TimeTo: &timeTo, DaysOff: &daysOff},
+		{IdProcess: "report", Type: "job", Name: "Report"},
+	}
+	for _, process := range processes {
+		if err := client.CreateProcess(ctx, process); err != nil {
+			t.Fatalf("error creating process %s: %s", process.IdProcess, err)
+		}
+	}
+
+	data, err := client.ExportProcesses(ctx, FileFormatCSV, url.Values{"type": []string{"cron"}})
+	if err != nil {
+		t.Fatalf("error exporting: %s", err)
+	}
+	expected := "id_process,type,name,description,date_from,date_to,time_from,time_to,days_off,monitor,status\n" +
+		"backup,cron,\"Backup, nightly\",,,,08:00:00,18:00:00,\"saturday,sunday\",,\n"
+	if string(data) != expected {
+		t.Errorf("unexpected csv export\n%s", data)
+	}
+
+	// by default the existing processes fail the import
+	_, err = client.ImportProcesses(ctx, FileFormatCSV, data, "", false)
+	if !IsCode(err, ErrorCodeProcessAlreadyExists) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+
+	yaml, err := client.ExportProcesses(ctx, FileFormatYAML, nil)
+	if err != nil {
+		t.Fatalf("error exporting: %s", err)
+	}
+	yaml = append([]byte(strings.Replace(string(yaml), "name: Report", "name: Weekly report", 1)), "- id_process: new\n  type: job\n  name: New\n"...)
+
+	skipped, err := client.ImportProcesses(ctx, FileFormatYAML, yaml, ConflictSkip, false)
+	if err != nil || skipped.Created != 1 || skipped.Updated != 0 || strings.Join(skipped.Skipped, ",") != "backup,report" {
+		t.Fatalf("unexpected import %+v, %v", skipped, err)
+	}
+
+	overwritten, err := client.ImportProcesses(ctx, FileFormatYAML, yaml, ConflictOverwrite, true)
+	if err != nil || !overwritten.DryRun || overwritten.Updated != 1 || overwritten.Changes[0].IdProcess != "report" {
+		t.Fatalf("unexpected import %+v, %v", overwritten, err)
+	}
+	if process, _ := client.GetProcess(ctx, "report"); process.Name != "Report" {
+		t.Errorf("the dry run shouldn't update the process, got %s", process.Name)
+	}
+
+	if _, err = client.ImportProcesses(ctx, FileFormatCSV, []byte("id_process,schedule\nx,daily\n"), ConflictSkip, false); !IsCode(err, ErrorCodeInvalidBody) {
+		t.Errorf("expected an invalid body, got %v", err)
+	}
+}
+
+func TestProcessHistory(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	if err := client.CreateProcess(ctx, &Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	client.UpdateProcessStatus(ctx, "job", StatusRunning)
+	client.UpdateProcessStatusWithMessage(ctx, "job", StatusFailed, "disk full")
+	client.UpdateProcessStatus(ctx, "job", StatusRunning)
+
+	history, err := client.GetProcessHistory(ctx, "job")
+	if err != nil || len(history) != 4 || history[0].Operation != HistoryInsert || history[3].Version != 4 {
+		t.Fatalf("unexpected history %+v, %v", history, err)
+	}
+
+	runs, err := client.GetProcessRuns(ctx, "job")
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %+v, %v", runs, err)
+	}
+	if runs[0].Status != StatusRunning || runs[0].EndedAt != nil {
+		t.Errorf("expected the last run to be running, got %+v", runs[0])
+	}
+	if runs[1].Status != StatusFailed || runs[1].Message != "disk full" || runs[1].EndedAt == nil {
+		t.Errorf("expected the first run to have failed, got %+v", runs[1])
+	}
+
+	if _, err := client.GetProcessRuns(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("expected the process to not be found, got %v", err)
+	}
+
+	resp, err := http.Get(client.url + "/dashboard")
+	if err != nil {
+		t.Fatalf("error getting the dashboard: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if data, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || !strings.Contains(string(data), "<title>monitor</title>") {
+		t.Errorf("expected the dashboard, got %d", resp.StatusCode)
+	}
+}
+
+func TestStatusPage(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	if err := client.CreateProcess(ctx, &Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	for _, status := range []Status{StatusRunning, StatusStopped, StatusRunning, StatusFailed} {
+		if err := client.UpdateProcessStatus(ctx, "job", status); err != nil {
+			t.Fatalf("error updating the status to %s: %s", status, err)
+		}
+	}
+
+	page, err := client.GetStatusPage(ctx)
+	if err != nil {
+		t.Fatalf("error getting the status page: %s", err)
+	}
+	if page.Title != monitor.DefaultStatusPageTitle || len(page.Groups) != 1 || len(page.Groups[0].Processes) != 1 {
+		t.Fatalf("expected the process on the default group, got %+v", page)
+	}
+	if status := page.Groups[0].Processes[0]; status.LastRunStatus == nil || *status.LastRunStatus != StatusFailed || status.LastSuccessAt == nil {
+		t.Errorf("expected the last run failed after a success, got %+v", status)
+	}
+
+	badges := []struct {
+		path   string
+		status int
+		text   string
+	}{
+		{path: "/badge/job.svg", status: http.StatusOK, text: "job: failed"},
+		{path: "/badge/job.svg?show=last_success&label=backup", status: http.StatusOK, text: "backup: just now"},
+		{path: "/badge/missing.svg", status: http.StatusNotFound, text: "missing: not found"},
+		{path: "/badge/job.svg?show=invalid", status: http.StatusBadRequest},
+	}
+	for _, badge := range badges {
+		resp, err := http.Get(client.url + badge.path)
+		if err != nil {
+			t.Fatalf("error getting the badge %s: %s", badge.path, err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != badge.status || !strings.Contains(string(data), badge.text) {
+			t.Errorf("expected the badge %s to be %d with %q, got %d %s", badge.path, badge.status, badge.text, resp.StatusCode, data)
+		}
+		if badge.status != http.StatusBadRequest && !strings.HasPrefix(resp.Header.Get("Content-Type"), monitor.ContentTypeSVG) {
+			t.Errorf("expected the badge %s to be a svg, got %s", badge.path, resp.Header.Get("Content-Type"))
+		}
+	}
+
+	resp, err := http.Get(client.url + "/status")
+	if err != nil {
+		t.Fatalf("error getting the status page: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the status page, got %d", resp.StatusCode)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var attempts int32
+	keys := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys[r.Header.Get(HeaderIdempotencyKey)] = true
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetries(3, time.Millisecond))
+	if err := client.CreateProcess(context.Background(), newProcess("a")); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if len(keys) != 1 {
+		t.Errorf("expected the retries to keep the idempotency key, got %v", keys)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetries(0, 0), WithTimeout(20*time.Millisecond))
+	if _, err := client.GetProcess(context.Background(), "a"); err == nil {
+		t.Error("expected a timeout")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewClient(server.URL).GetProcess(ctx, "a"); err == nil {
+		t.Error("expected the canceled context to fail the request")
+	}
+}
+func TestRun(t *testing.T) {
+	client := newMonitor(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"succeeded", "failed", "lost"} {
+		if err := client.CreateProcess(ctx, newProcess(id)); err != nil {
+			t.Fatalf("error creating process: %s", err)
+		}
+	}
+
+	err := client.Run(ctx, "succeeded", func(ctx context.Context) error {
+		process, err := client.GetProcess(ctx, "succeeded")
+		if err != nil || *process.Status != StatusRunning || process.HeartbeatAt == nil {
+			t.Errorf("expected the process running with a heartbeat, got %+v %v", process, err)
+		}
+
+		if err := client.Run(ctx, "succeeded", func(ctx context.Context) error { return nil }); !IsCode(err, ErrorCodeAlreadyRunning) {
+			t.Errorf("expected %s, got %v", ErrorCodeAlreadyRunning, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("error running process: %s", err)
+	}
+	if process, _ := client.GetProcess(ctx, "succeeded"); *process.Status != StatusStopped {
+		t.Errorf("expected the process stopped, got %s", *process.Status)
+	}
+
+	failure := fmt.Errorf("failure")
+	if err := client.Run(ctx, "failed", func(ctx context.Context) error { return failure }); err != failure {
+		t.Errorf("expected the error of the function, got %v", err)
+	}
+	if process, _ := client.GetProcess(ctx, "failed"); *process.Status != StatusFailed || process.Message != "failure" {
+		t.Errorf("expected the process failed with the error, got %s %q", *process.Status, process.Message)
+	}
+
+	err = client.Run(ctx, "lost", func(runCtx context.Context) error {
+		// stopped by someone else, the context of the run is canceled by the next heartbeat
+		if err := client.UpdateProcessStatus(ctx, "lost", StatusStopped); err != nil {
+			t.Errorf("error stopping process: %s", err)
+		}
+
+		select {
+		case <-runCtx.Done():
+			return runCtx.Err()
+		case <-time.After(2 * time.Second):
+			t.Error("expected the context to be canceled")
+			return nil
+		}
+	}, WithHeartbeat(10*time.Millisecond))
+	if !IsLeaseLost(err) {
+		t.Errorf("expected the lease to be lost, got %v", err)
+	}
+	if process, _ := client.GetProcess(ctx, "lost"); *process.Status != StatusStopped {
+		t.Errorf("expected the status to be kept, got %s", *process.Status)
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+const (
+	DefaultURL = "http://localhost:8001"
+
+	HeaderETag               = "ETag"
+	HeaderIfMatch            = "If-Match"
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeYAML       = "application/yaml"
+	ContentTypeCSV        = "text/csv"
+
+	StatusStopped Status = "stopped"
+	StatusRunning Status = "running"
+	StatusFailed  Status = "failed"
+
+	BatchModeAtomic     BatchMode = "atomic"
+	BatchModeBestEffort BatchMode = "best_effort"
+
+	BatchOperationCreate BatchOperationType = "create"
+	BatchOperationUpdate BatchOperationType = "update"
+	BatchOperationStatus BatchOperationType = "status"
+
+	MaxBatchOperations = 1000
+
+	IncludeSchedule = "schedule"
+
+	ChangeCreate ChangeOperation = "create"
+	ChangeUpdate ChangeOperation = "update"
+	ChangeDelete ChangeOperation = "delete"
+
+	HistoryInsert HistoryOperation = "I"
+	HistoryUpdate HistoryOperation = "U"
+	HistoryDelete HistoryOperation = "D"
+
+	FileFormatJSON FileFormat = "json"
+	FileFormatYAML FileFormat = "yaml"
+	FileFormatCSV  FileFormat = "csv"
+
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+const (
+	ErrorCodeInternal              ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeInvalidBody           ErrorCode = "INVALID_BODY"
+	ErrorCodeValidationFailed      ErrorCode = "VALIDATION_FAILED"
+	ErrorCodeRouteNotFound         ErrorCode = "ROUTE_NOT_FOUND"
+	ErrorCodeProcessNotFound       ErrorCode = "PROCESS_NOT_FOUND"
+	ErrorCodeProcessAlreadyExists  ErrorCode = "PROCESS_ALREADY_EXISTS"
+	ErrorCodeAlreadyRunning        ErrorCode = "ALREADY_RUNNING"
+	ErrorCodeWindowClosed          ErrorCode = "WINDOW_CLOSED"
+	ErrorCodeLeaseLost             ErrorCode = "LEASE_LOST"
+	ErrorCodeVersionMismatch       ErrorCode = "VERSION_MISMATCH"
+	ErrorCodeVersionRequired       ErrorCode = "VERSION_REQUIRED"
+	ErrorCodeUnsupportedPatch      ErrorCode = "UNSUPPORTED_PATCH"
+	ErrorCodeInvalidPatch          ErrorCode = "INVALID_PATCH"
+	ErrorCodeBatchTooLarge         ErrorCode = "BATCH_TOO_LARGE"
+	ErrorCodeBatchAborted          ErrorCode = "BATCH_ABORTED"
+	ErrorCodeIdempotencyKeyInvalid ErrorCode = "IDEMPOTENCY_KEY_INVALID"
+	ErrorCodeIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	ErrorCodeIdempotencyMismatch   ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeInvalidFilter         ErrorCode = "INVALID_FILTER"
+	ErrorCodeFilterRequired        ErrorCode = "FILTER_REQUIRED"
+	ErrorCodeConfirmationRequired  ErrorCode = "CONFIRMATION_REQUIRED"
+)
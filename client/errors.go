@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrLeaseLost is returned by Run when the process stopped running while the function was executing
+var ErrLeaseLost = errors.New("the process isn't running anymore, the lease was lost")
+
+// Error is an error returned by the monitor, with the problem details of the response
+type Error struct {
+	Problem
+}
+
+// Error ...
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+	}
+	if e.Detail != "" {
+		return fmt.Sprintf("%s (%d %s): %s", e.Code, e.Status, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Code, e.Status, e.Title)
+}
+
+// IsCode checks if the error was returned by the monitor with the code
+func IsCode(err error, code ErrorCode) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Code == code
+	}
+	return false
+}
+
+// IsNotFound checks if the process wasn't found
+func IsNotFound(err error) bool {
+	return IsCode(err, ErrorCodeProcessNotFound)
+}
+
+// IsLeaseLost checks if the process isn't running anymore, like when it was stopped by someone else
+func IsLeaseLost(err error) bool {
+	return err == ErrLeaseLost || IsCode(err, ErrorCodeLeaseLost)
+}
+
+// IsVersionMismatch checks if the process was changed in the meantime
+func IsVersionMismatch(err error) bool {
+	return IsCode(err, ErrorCodeVersionMismatch)
+}
+
+// newError reads the error of a response, the responses without problem details don't have a code
+func newError(status int, body []byte) *Error {
+	e := &Error{}
+	if err := json.Unmarshal(body, &e.Problem); err != nil || e.Code == "" {
+		e.Problem = Problem{Detail: string(body)}
+	}
+
+	e.Status = status
+	if e.Title == "" {
+		e.Title = http.StatusText(status)
+	}
+
+	return e
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// ClientOption ...
+type ClientOption func(client *Client)
+
+// Reconfigure ...
+func (client *Client) Reconfigure(options ...ClientOption) {
+	for _, option := range options {
+		option(client)
+	}
+}
+
+// WithHTTPClient ...
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(client *Client) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of each attempt of a request
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(client *Client) {
+		client.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the wait before the first retry,
+// that doubles on each retry
+func WithRetries(retries int, wait time.Duration) ClientOption {
+	return func(client *Client) {
+		client.retries = retries
+		client.retryWait = wait
+	}
+}
+
+// WithHeader adds a header to every request, like the credentials
+func WithHeader(key, value string) ClientOption {
+	return func(client *Client) {
+		client.headers.Set(key, value)
+	}
+}
+
+// RunOption ...
+type RunOption func(config *runConfig)
+
+// WithHeartbeat sets the interval of the heartbeats sent while the function runs
+func WithHeartbeat(interval time.Duration) RunOption {
+	return func(config *runConfig) {
+		config.heartbeat = interval
+	}
+}
+
+// WithLease sets how long the heartbeats can fail before the lease is lost,
+// by default three heartbeat intervals
+func WithLease(lease time.Duration) RunOption {
+	return func(config *runConfig) {
+		config.lease = lease
+	}
+}
@@ -0,0 +1,374 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaosoft/types"
+)
+
+const pathProcesses = "/api/v1/processes"
+
+// processBody is the body of the create and update requests
+type processBody struct {
+	IdProcess   string         `json:"id_process,omitempty"`
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	DateFrom    *types.Date    `json:"date_from,omitempty"`
+	DateTo      *types.Date    `json:"date_to,omitempty"`
+	TimeFrom    *types.Time    `json:"time_from,omitempty"`
+	TimeTo      *types.Time    `json:"time_to,omitempty"`
+	DaysOff     *types.ListDay `json:"days_off,omitempty"`
+	Monitor     string         `json:"monitor"`
+	Status      *Status        `json:"status,omitempty"`
+}
+
+func newProcessBody(process *Process) *processBody {
+	return &processBody{
+		IdProcess:   process.IdProcess,
+		Type:        process.Type,
+		Name:        process.Name,
+		Description: process.Description,
+		DateFrom:    process.DateFrom,
+		DateTo:      process.DateTo,
+		TimeFrom:    process.TimeFrom,
+		TimeTo:      process.TimeTo,
+		DaysOff:     process.DaysOff,
+		Monitor:     process.Monitor,
+		Status:      process.Status,
+	}
+}
+
+func processPath(idProcess string, segments ...string) string {
+	return pathProcesses + "/" + url.PathEscape(idProcess) + strings.Join(segments, "")
+}
+
+// GetProcess returns the process, with the version to send on the updates.
+// With IncludeSchedule it also returns if the process can be started now and its next window
+func (client *Client) GetProcess(ctx context.Context, idProcess string, include ...string) (*Process, error) {
+	var query url.Values
+	if len(include) > 0 {
+		query = url.Values{"include": []string{strings.Join(include, ",")}}
+	}
+
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: processPath(idProcess), query: query})
+	if err != nil {
+		return nil, err
+	}
+
+	process := &Process{}
+	if err := json.Unmarshal(resp.body, process); err != nil {
+		return nil, err
+	}
+
+	return process, nil
+}
+
+// GetProcesses returns the processes, filtered by id_process, type, name, monitor and status.
+// With include=schedule on the filter, it also returns if they can be started now and their next window
+func (client *Client) GetProcesses(ctx context.Context, filter url.Values) (ListProcess, error) {
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: pathProcesses, query: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make(ListProcess, 0)
+	if err := json.Unmarshal(resp.body, &processes); err != nil {
+		return nil, err
+	}
+
+	return processes, nil
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (client *Client) GetProcessHistory(ctx context.Context, idProcess string) (ListProcessHistory, error) {
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: processPath(idProcess, "/history")})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(ListProcessHistory, 0)
+	if err := json.Unmarshal(resp.body, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetProcessRuns returns the runs of the process, from running to stopped or failed, the last first
+func (client *Client) GetProcessRuns(ctx context.Context, idProcess string) (ListProcessRun, error) {
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: processPath(idProcess, "/runs")})
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make(ListProcessRun, 0)
+	if err := json.Unmarshal(resp.body, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// CreateProcess ...
+func (client *Client) CreateProcess(ctx context.Context, process *Process) error {
+	_, err := client.do(ctx, &request{method: http.MethodPost, path: pathProcesses, body: newProcessBody(process)})
+	return err
+}
+
+// UpdateProcess updates the process when it's still on its version, any version is accepted when it's zero.
+// The version of the process is updated
+func (client *Client) UpdateProcess(ctx context.Context, process *Process) error {
+	body := newProcessBody(process)
+	body.IdProcess = ""
+
+	resp, err := client.do(ctx, &request{
+		method:  http.MethodPut,
+		path:    processPath(process.IdProcess),
+		headers: ifMatch(process.Version),
+		body:    body,
+	})
+	if err != nil {
+		return err
+	}
+
+	process.Version = etagVersion(resp.header)
+	return nil
+}
+
+// PatchProcess applies a json merge patch to the process when it's still on the version,
+// any version is accepted when it's zero. Returns the new version of the process
+func (client *Client) PatchProcess(ctx context.Context, idProcess string, patch map[string]interface{}, version int64) (int64, error) {
+	resp, err := client.do(ctx, &request{
+		method:      http.MethodPatch,
+		path:        processPath(idProcess),
+		headers:     ifMatch(version),
+		contentType: ContentTypeMergePatch,
+		body:        patch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return etagVersion(resp.header), nil
+}
+
+// UpdateProcessStatus changes the status of the process, a process can only be started inside of its window
+func (client *Client) UpdateProcessStatus(ctx context.Context, idProcess string, status Status) error {
+	_, err := client.do(ctx, &request{method: http.MethodPut, path: processPath(idProcess, "/status/", url.PathEscape(string(status)))})
+	return err
+}
+
+// UpdateProcessStatusWithMessage changes the status of the process with a message, like the error of a failed process
+func (client *Client) UpdateProcessStatusWithMessage(ctx context.Context, idProcess string, status Status, message string) error {
+	body := &processStatusBody{Message: message}
+
+	_, err := client.do(ctx, &request{method: http.MethodPut, path: processPath(idProcess, "/status/", url.PathEscape(string(status))), body: body})
+	return err
+}
+
+// HeartbeatProcess records that the running process is alive, fails with the code LEASE_LOST when it isn't running anymore
+func (client *Client) HeartbeatProcess(ctx context.Context, idProcess string) error {
+	_, err := client.do(ctx, &request{method: http.MethodPost, path: processPath(idProcess, "/heartbeat")})
+	return err
+}
+
+// CheckProcessStatus checks if the status of the process can be changed
+func (client *Client) CheckProcessStatus(ctx context.Context, idProcess string, status Status) error {
+	_, err := client.do(ctx, &request{method: http.MethodPut, path: processPath(idProcess, "/status/", url.PathEscape(string(status)), "/check")})
+	return err
+}
+
+// DeleteProcess deletes the process when it's still on the version, any version is accepted when it's zero
+func (client *Client) DeleteProcess(ctx context.Context, idProcess string, version int64) error {
+	_, err := client.do(ctx, &request{method: http.MethodDelete, path: processPath(idProcess), headers: ifMatch(version)})
+	return err
+}
+
+// DeleteProcesses deletes the filtered processes. Without a valid confirmation nothing is deleted and
+// the error has the code CONFIRMATION_REQUIRED, with the response having the confirmation to send
+func (client *Client) DeleteProcesses(ctx context.Context, filter url.Values, confirmation string) (*DeleteProcessesResponse, error) {
+	query := url.Values{}
+	for key, values := range filter {
+		query[key] = values
+	}
+	if confirmation != "" {
+		query.Set("confirmation", confirmation)
+	}
+
+	resp, err := client.do(ctx, &request{method: http.MethodDelete, path: pathProcesses, query: query})
+	if resp == nil {
+		return nil, err
+	}
+
+	response := &DeleteProcessesResponse{}
+	if resp.status == http.StatusPreconditionRequired {
+		if errUnmarshal := json.Unmarshal(resp.body, response); errUnmarshal != nil {
+			return nil, errUnmarshal
+		}
+
+		e := &Error{}
+		e.Status = http.StatusPreconditionRequired
+		e.Code = ErrorCodeConfirmationRequired
+		e.Title = http.StatusText(http.StatusPreconditionRequired)
+		e.Detail = "the deletion must be confirmed with the returned confirmation token"
+		return response, e
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(resp.body, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RestoreProcess restores a deleted process
+func (client *Client) RestoreProcess(ctx context.Context, idProcess string) error {
+	_, err := client.do(ctx, &request{method: http.MethodPost, path: processPath(idProcess, "/restore")})
+	return err
+}
+
+// Batch executes the operations, on atomic mode none is applied when one fails
+func (client *Client) Batch(ctx context.Context, mode BatchMode, operations []*BatchOperationRequest) (*BatchProcessesResponse, error) {
+	body := &batchProcessesBody{Mode: mode, Operations: operations}
+
+	resp, err := client.do(ctx, &request{method: http.MethodPost, path: pathProcesses + ":batch", body: body})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &BatchProcessesResponse{}
+	if err := json.Unmarshal(resp.body, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// PlanProcesses returns the changes needed to match the definitions, without applying them
+func (client *Client) PlanProcesses(ctx context.Context, definitions []*ProcessDefinition, prune bool) (*ApplyProcessesResponse, error) {
+	return client.applyProcesses(ctx, definitions, prune, true)
+}
+
+// ApplyProcesses creates, updates and, when pruning, deletes the processes to match the definitions.
+// The changes are applied on a single transaction, none is applied when one fails
+func (client *Client) ApplyProcesses(ctx context.Context, definitions []*ProcessDefinition, prune bool) (*ApplyProcessesResponse, error) {
+	return client.applyProcesses(ctx, definitions, prune, false)
+}
+
+func (client *Client) applyProcesses(ctx context.Context, definitions []*ProcessDefinition, prune bool, dryRun bool) (*ApplyProcessesResponse, error) {
+	body := &applyProcessesBody{Processes: definitions, Prune: prune, DryRun: dryRun}
+
+	resp, err := client.do(ctx, &request{method: http.MethodPost, path: pathProcesses + "/apply", body: body})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ApplyProcessesResponse{}
+	if err := json.Unmarshal(resp.body, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// SimulateProcess returns when the process could be started between from and to, with the fields set on the window
+// replacing the ones of the process. Without the id of a process, only the window is simulated
+func (client *Client) SimulateProcess(ctx context.Context, idProcess string, window *ProcessWindow, from, to time.Time) (*SimulateProcessResponse, error) {
+	body := &simulateProcessBody{IdProcess: idProcess, Process: window, From: from, To: to}
+
+	resp, err := client.do(ctx, &request{method: http.MethodPost, path: pathProcesses + "/simulate", body: body})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SimulateProcessResponse{}
+	if err := json.Unmarshal(resp.body, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ExportProcesses returns the filtered processes as a json, yaml or csv file
+func (client *Client) ExportProcesses(ctx context.Context, format FileFormat, filter url.Values) ([]byte, error) {
+	query := url.Values{"format": []string{string(format)}}
+	for key, values := range filter {
+		query[key] = values
+	}
+
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: pathProcesses + "/export", query: query})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.body, nil
+}
+
+// ImportProcesses creates the processes of a json, yaml or csv file on a single transaction, the existing ones
+// are skipped, overwritten or fail the import by the policy, fail by default. On dry run they are only planned
+func (client *Client) ImportProcesses(ctx context.Context, format FileFormat, data []byte, onConflict ConflictPolicy, dryRun bool) (*ImportProcessesResponse, error) {
+	query := url.Values{"format": []string{string(format)}}
+	if onConflict != "" {
+		query.Set("on_conflict", string(onConflict))
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	// the file is uploaded as multipart form data, because the server drops the new lines of the body
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": []string{fmt.Sprintf(`form-data; name="file"; filename="processes.%s"`, format)},
+		"Content-Type":        []string{format.ContentType()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.do(ctx, &request{method: http.MethodPost, path: pathProcesses + "/import", query: query, contentType: writer.FormDataContentType(), body: body.Bytes()})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ImportProcessesResponse{}
+	if err := json.Unmarshal(resp.body, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func ifMatch(version int64) http.Header {
+	if version <= 0 {
+		return nil
+	}
+
+	return http.Header{HeaderIfMatch: []string{fmt.Sprintf(`"%d"`, version)}}
+}
+
+func etagVersion(header http.Header) int64 {
+	version, _ := strconv.ParseInt(strings.Trim(header.Get(HeaderETag), `"`), 10, 64)
+	return version
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type runConfig struct {
+	heartbeat time.Duration
+	lease     time.Duration
+}
+
+// Run executes the function as the process: it starts the process, sends heartbeats while the function runs
+// and then stops the process, or sets it as failed with the error of the function.
+// The context of the function is canceled when the lease is lost, because the process was stopped by someone else
+// or the heartbeats failed for longer than the lease, then the status isn't changed and ErrLeaseLost is returned
+func (client *Client) Run(ctx context.Context, idProcess string, fn func(ctx context.Context) error, options ...RunOption) error {
+	config := &runConfig{heartbeat: DefaultHeartbeat}
+	for _, option := range options {
+		option(config)
+	}
+	if config.lease <= 0 {
+		config.lease = 3 * config.heartbeat
+	}
+
+	if err := client.UpdateProcessStatus(ctx, idProcess, StatusRunning); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heartbeat := client.startHeartbeat(runCtx, cancel, idProcess, config)
+
+	// a panic is recorded as a failure before going on
+	panicked := true
+	defer func() {
+		if panicked {
+			recovered := recover()
+			if !heartbeat.stop() {
+				client.finish(idProcess, fmt.Errorf("panic: %v", recovered))
+			}
+			panic(recovered)
+		}
+	}()
+
+	err := fn(runCtx)
+	panicked = false
+
+	if heartbeat.stop() {
+		return ErrLeaseLost
+	}
+
+	return client.finish(idProcess, err)
+}
+
+// finish stops the process, or sets it as failed with the error. It's done even when the context
+// of the run was canceled, so the process isn't left running
+func (client *Client) finish(idProcess string, err error) error {
+	ctx := context.Background()
+
+	if err == nil {
+		return client.UpdateProcessStatus(ctx, idProcess, StatusStopped)
+	}
+
+	if errStatus := client.UpdateProcessStatusWithMessage(ctx, idProcess, StatusFailed, err.Error()); errStatus != nil {
+		return fmt.Errorf("%w, and setting the process as failed: %s", err, errStatus)
+	}
+
+	return err
+}
+
+type heartbeat struct {
+	done    chan struct{}
+	stopped chan struct{}
+	lost    bool
+}
+
+// startHeartbeat sends the heartbeats until it's stopped, canceling the run when the lease is lost
+func (client *Client) startHeartbeat(ctx context.Context, cancel context.CancelFunc, idProcess string, config *runConfig) *heartbeat {
+	heartbeat := &heartbeat{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	alive := time.Now()
+	beat := func() bool {
+		err := client.HeartbeatProcess(ctx, idProcess)
+		switch {
+		case err == nil:
+			alive = time.Now()
+		case IsLeaseLost(err), IsNotFound(err), time.Since(alive) > config.lease:
+			heartbeat.lost = true
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	// the first heartbeat is sent before the function starts
+	if !beat() {
+		close(heartbeat.stopped)
+		return heartbeat
+	}
+
+	go func() {
+		defer close(heartbeat.stopped)
+
+		ticker := time.NewTicker(config.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-heartbeat.done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if !beat() {
+				return
+			}
+		}
+	}()
+
+	return heartbeat
+}
+
+// stop stops the heartbeats, returns if the lease was lost
+func (heartbeat *heartbeat) stop() bool {
+	close(heartbeat.done)
+	<-heartbeat.stopped
+	return heartbeat.lost
+}
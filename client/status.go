@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+const pathStatus = "/api/v1/status"
+
+// GetStatusPage returns the public status of the processes of each group of the status page
+func (client *Client) GetStatusPage(ctx context.Context) (*StatusPageResponse, error) {
+	resp, err := client.do(ctx, &request{method: http.MethodGet, path: pathStatus})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &StatusPageResponse{}
+	if err := json.Unmarshal(resp.body, page); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
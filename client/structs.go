@@ -0,0 +1,252 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/joaosoft/types"
+)
+
+// the types of the api are declared again on the client, so it doesn't depend on the monitor service
+
+type Status string
+
+type ErrorCode string
+
+type BatchMode string
+
+type BatchOperationType string
+
+type ChangeOperation string
+
+type HistoryOperation string
+
+// FileFormat is the format of the exported and imported processes
+type FileFormat string
+
+// ConflictPolicy tells what to do when an imported process already exists
+type ConflictPolicy string
+
+// ContentType returns the content type of the format
+func (format FileFormat) ContentType() string {
+	switch format {
+	case FileFormatCSV:
+		return ContentTypeCSV
+	case FileFormatYAML:
+		return ContentTypeYAML
+	default:
+		return "application/json"
+	}
+}
+
+type Process struct {
+	IdProcess   string         `json:"id_process"`
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Description string         `json:"description"`
+	DateFrom    *types.Date    `json:"date_from"`
+	DateTo      *types.Date    `json:"date_to"`
+	TimeFrom    *types.Time    `json:"time_from"`
+	TimeTo      *types.Time    `json:"time_to"`
+	DaysOff     *types.ListDay `json:"days_off"`
+	Monitor     string         `json:"monitor"`
+	Status      *Status        `json:"status"`
+	Message     string         `json:"message"`
+	HeartbeatAt *time.Time     `json:"heartbeat_at"`
+	Version     int64          `json:"version"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+
+	// computed when including the schedule
+	CanExecuteNow   *bool      `json:"can_execute_now,omitempty"`
+	NextWindowStart *time.Time `json:"next_window_start,omitempty"`
+	NextWindowEnd   *time.Time `json:"next_window_end,omitempty"`
+}
+
+type ListProcess []*Process
+
+// ProcessHistory is a version of a process, recorded when it was inserted, updated or deleted
+type ProcessHistory struct {
+	Process
+	DeletedAt   *time.Time       `json:"deleted_at"`
+	Operation   HistoryOperation `json:"operation"`
+	OperationAt time.Time        `json:"operation_at"`
+}
+
+type ListProcessHistory []*ProcessHistory
+
+// ProcessRun is a run of a process, from when it was running until it was stopped or failed
+type ProcessRun struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Status    Status     `json:"status"`
+	Message   string     `json:"message"`
+}
+
+type ListProcessRun []*ProcessRun
+
+// StatusPageResponse is the public status of the processes of each group of the status page
+type StatusPageResponse struct {
+	Title       string                     `json:"title"`
+	Groups      []*StatusPageGroupResponse `json:"groups"`
+	UptimeSince time.Time                  `json:"uptime_since"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+}
+
+type StatusPageGroupResponse struct {
+	Name      string           `json:"name"`
+	Processes []*ProcessStatus `json:"processes"`
+}
+
+// ProcessStatus is the public status of a process, with the outcome of its last finished run
+// and the percentage of the uptime period it wasn't failed
+type ProcessStatus struct {
+	IdProcess     string     `json:"id_process"`
+	Name          string     `json:"name"`
+	Status        *Status    `json:"status"`
+	LastRunStatus *Status    `json:"last_run_status"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	Uptime        float64    `json:"uptime"`
+}
+
+// ProcessDefinition is a process as created, also used to declare the processes to apply
+type ProcessDefinition struct {
+	IdProcess   string         `json:"id_process"`
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	DateFrom    *types.Date    `json:"date_from"`
+	DateTo      *types.Date    `json:"date_to"`
+	TimeFrom    *types.Time    `json:"time_from"`
+	TimeTo      *types.Time    `json:"time_to"`
+	DaysOff     *types.ListDay `json:"days_off"`
+	Monitor     string         `json:"monitor"`
+	Status      *Status        `json:"status"`
+}
+
+type ApplyProcessesResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Deleted int              `json:"deleted"`
+	Changes []*ProcessChange `json:"changes"`
+}
+
+type ImportProcessesResponse struct {
+	OnConflict ConflictPolicy   `json:"on_conflict"`
+	DryRun     bool             `json:"dry_run"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	Skipped    []string         `json:"skipped"`
+	Changes    []*ProcessChange `json:"changes"`
+}
+
+// ProcessChange is a change needed to match the definitions, with the version of the process
+// (the new version after applying an update)
+type ProcessChange struct {
+	Op        ChangeOperation `json:"op"`
+	IdProcess string          `json:"id_process"`
+	Version   int64           `json:"version,omitempty"`
+	Fields    []*FieldChange  `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type DeleteProcessesResponse struct {
+	Count        int64  `json:"count"`
+	Confirmation string `json:"confirmation,omitempty"`
+}
+
+type BatchOperationRequest struct {
+	Op        BatchOperationType `json:"op"`
+	IdProcess string             `json:"id_process"`
+	Version   int64              `json:"version"`
+	Status    Status             `json:"status"`
+	Message   string             `json:"message"`
+	Body      json.RawMessage    `json:"body"`
+}
+
+type BatchOperationResult struct {
+	Index     int                `json:"index"`
+	Op        BatchOperationType `json:"op"`
+	IdProcess string             `json:"id_process,omitempty"`
+	Status    int                `json:"status"`
+	Version   int64              `json:"version,omitempty"`
+	Error     *Problem           `json:"error,omitempty"`
+}
+
+type BatchProcessesResponse struct {
+	Mode      BatchMode               `json:"mode"`
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Results   []*BatchOperationResult `json:"results"`
+}
+
+// Problem is the problem details (RFC 7807) of an error response
+// ProcessWindow is the window of a process, when it can be started
+type ProcessWindow struct {
+	DateFrom *types.Date    `json:"date_from"`
+	DateTo   *types.Date    `json:"date_to"`
+	TimeFrom *types.Time    `json:"time_from"`
+	TimeTo   *types.Time    `json:"time_to"`
+	DaysOff  *types.ListDay `json:"days_off"`
+}
+
+type SimulateProcessResponse struct {
+	IdProcess string              `json:"id_process,omitempty"`
+	Process   *ProcessWindow      `json:"process"`
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Allowed   []*ScheduleInterval `json:"allowed"`
+	Blocked   []*ScheduleInterval `json:"blocked"`
+}
+
+// ScheduleInterval is an interval where a process can be started, or can't with the reasons
+type ScheduleInterval struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+type Problem struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Status   int           `json:"status"`
+	Code     ErrorCode     `json:"code"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Errors   []*FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type batchProcessesBody struct {
+	Mode       BatchMode                `json:"mode"`
+	Operations []*BatchOperationRequest `json:"operations"`
+}
+
+type applyProcessesBody struct {
+	Processes []*ProcessDefinition `json:"processes"`
+	Prune     bool                 `json:"prune"`
+	DryRun    bool                 `json:"dry_run"`
+}
+
+type simulateProcessBody struct {
+	IdProcess string         `json:"id_process"`
+	Process   *ProcessWindow `json:"process"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+}
+
+type processStatusBody struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock gives the current time, so the execution windows can be evaluated at any time,
+// and the channels of the timers, so the background tasks run on the time of the clock
+type Clock interface {
+	Now() time.Time
+	After(duration time.Duration) <-chan time.Time
+}
+
+// systemClock is the clock of the system
+type systemClock struct{}
+
+// Now ...
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After ...
+func (systemClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+// FakeClock is a clock that only changes when it's set or advanced, to be used on tests.
+// Its timers fire when the clock reaches them
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	mux    sync.Mutex
+}
+
+type fakeTimer struct {
+	at      time.Time
+	channel chan time.Time
+}
+
+// NewFakeClock ...
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now ...
+func (clock *FakeClock) Now() time.Time {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	return clock.now
+}
+
+// Set changes the current time of the clock
+func (clock *FakeClock) Set(now time.Time) {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	clock.now = now
+	clock.fire()
+}
+
+// Advance moves the clock forward by the duration
+func (clock *FakeClock) Advance(duration time.Duration) {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	clock.now = clock.now.Add(duration)
+	clock.fire()
+}
+
+// After returns a channel that receives the time when the clock is advanced by the duration
+func (clock *FakeClock) After(duration time.Duration) <-chan time.Time {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	timer := &fakeTimer{at: clock.now.Add(duration), channel: make(chan time.Time, 1)}
+	clock.timers = append(clock.timers, timer)
+	clock.fire()
+
+	return timer.channel
+}
+
+// Timers returns how many timers are waiting for the clock
+func (clock *FakeClock) Timers() int {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	return len(clock.timers)
+}
+
+// fire sends the time to the timers that were reached
+func (clock *FakeClock) fire() {
+	waiting := clock.timers[:0]
+	for _, timer := range clock.timers {
+		if timer.at.After(clock.now) {
+			waiting = append(waiting, timer)
+			continue
+		}
+		timer.channel <- clock.now
+	}
+	clock.timers = waiting
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joaosoft/monitor/client"
+)
+
+// exit codes, besides the exit code of the command
+const (
+	ExitUsage       = 64  // invalid usage
+	ExitUnavailable = 69  // the monitor couldn't be reached or failed
+	ExitTempFail    = 75  // the process can't run now, it's already running or outside of its window, or the lease was lost
+	ExitNotFound    = 78  // the process doesn't exist on the monitor
+	ExitCannotRun   = 127 // the command couldn't be started
+)
+
+const (
+	EnvURL   = "MONITOR_URL"
+	EnvToken = "MONITOR_TOKEN"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is the command being run under the control of the monitor
+type command struct {
+	args     []string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	grace    time.Duration
+	output   *tailBuffer
+	signals  chan os.Signal
+	exitCode int
+}
+
+// run executes the command as the process, returning the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monitor-run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "monitor-run runs a command as a process of the monitor")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Usage:")
+		fmt.Fprintln(stderr, "  monitor-run --process <id> [flags] -- <command> [arguments]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Flags:")
+		flags.PrintDefaults()
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Exit codes:")
+		fmt.Fprintln(stderr, "  the exit code of the command, or 128 + signal when it was killed by a signal")
+		fmt.Fprintf(stderr, "  %d invalid usage\n", ExitUsage)
+		fmt.Fprintf(stderr, "  %d the monitor couldn't be reached or failed\n", ExitUnavailable)
+		fmt.Fprintf(stderr, "  %d the process can't run now or the lease was lost\n", ExitTempFail)
+		fmt.Fprintf(stderr, "  %d the process doesn't exist\n", ExitNotFound)
+		fmt.Fprintf(stderr, "  %d the command couldn't be started\n", ExitCannotRun)
+	}
+
+	idProcess := flags.String("process", "", "id of the process")
+	url := flags.String("url", "", "url of the monitor, or the env "+EnvURL)
+	token := flags.String("token", "", "token sent as bearer authorization, or the env "+EnvToken)
+	heartbeat := flags.Duration("heartbeat", client.DefaultHeartbeat, "interval of the heartbeats")
+	lease := flags.Duration("lease", 0, "how long the heartbeats can fail before the command is terminated, by default three heartbeats")
+	grace := flags.Duration("grace", 10*time.Second, "how long to wait for the command after terminating it, before killing it")
+	lines := flags.Int("tail", 20, "lines of the output recorded when the command fails")
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return ExitUsage
+	}
+
+	if *idProcess == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	var options []client.ClientOption
+	if *token = firstNotEmpty(*token, os.Getenv(EnvToken)); *token != "" {
+		options = append(options, client.WithHeader("Authorization", "Bearer "+*token))
+	}
+	c := client.NewClient(firstNotEmpty(*url, os.Getenv(EnvURL), client.DefaultURL), options...)
+
+	// the signals are caught before starting the process, so the process is set as failed,
+	// instead of being left running, when monitor-run is signaled before the command runs
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	cmd := &command{
+		args:    flags.Args(),
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		grace:   *grace,
+		output:  newTailBuffer(*lines),
+		signals: signals,
+	}
+
+	// starting the process checks if it can be executed, it fails when it's already running or outside of its window
+	started := false
+	var errCommand error
+	err := c.Run(context.Background(), *idProcess, func(ctx context.Context) error {
+		started = true
+		errCommand = cmd.execute(ctx)
+		return errCommand
+	}, client.WithHeartbeat(*heartbeat), client.WithLease(*lease))
+
+	code, message := exitCode(*idProcess, err, errCommand, started, cmd.exitCode)
+	if message != "" {
+		fmt.Fprintf(stderr, "monitor-run: %s\n", message)
+	}
+
+	return code
+}
+
+// exitCode returns the exit code of the run of the process, with the message explaining it
+func exitCode(idProcess string, err, errCommand error, started bool, commandExitCode int) (int, string) {
+	switch {
+	case err == nil:
+		return 0, ""
+	case client.IsLeaseLost(err):
+		return ExitTempFail, fmt.Sprintf("the process %s isn't running anymore, the command was terminated", idProcess)
+	case started:
+		// the output of the command already tells why it failed, unless it couldn't be started
+		if err != errCommand || commandExitCode == ExitCannotRun {
+			return commandExitCode, err.Error()
+		}
+		return commandExitCode, ""
+	case client.IsCode(err, client.ErrorCodeAlreadyRunning), client.IsCode(err, client.ErrorCodeWindowClosed):
+		return ExitTempFail, fmt.Sprintf("the process %s can't run now: %s", idProcess, err)
+	case client.IsNotFound(err):
+		return ExitNotFound, fmt.Sprintf("the process %s doesn't exist", idProcess)
+	default:
+		return ExitUnavailable, fmt.Sprintf("error starting the process %s: %s", idProcess, err)
+	}
+}
+
+// execute runs the command, forwarding the signals received to it. The error describes how the command
+// failed, with the tail of its output.
+// When the context is canceled the command is terminated and, after the grace period, killed
+func (c *command) execute(ctx context.Context) error {
+	// a signal received while the process was being started fails it, without running the command
+	select {
+	case sig := <-c.signals:
+		c.exitCode = signalExitCode(sig)
+		return fmt.Errorf("received the signal %s before running the command", sig)
+	default:
+	}
+
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Stdin = c.stdin
+	cmd.Stdout = io.MultiWriter(c.stdout, c.output)
+	cmd.Stderr = io.MultiWriter(c.stderr, c.output)
+	cmd.Cancel = func() error { return terminate(cmd.Process) }
+	cmd.WaitDelay = c.grace
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		c.exitCode = ExitCannotRun
+		return err
+	}
+
+	stop := c.forwardSignals(cmd.Process)
+	err := cmd.Wait()
+	stop()
+
+	if err == nil {
+		return nil
+	}
+
+	description := err.Error()
+	c.exitCode = ExitCannotRun
+	if cmd.ProcessState != nil {
+		if code, exited := exitStatus(cmd.ProcessState); code >= 0 {
+			c.exitCode, description = code, exited
+		}
+	}
+
+	if tail := c.output.String(); tail != "" {
+		return fmt.Errorf("%s, the output ended with:\n%s", description, tail)
+	}
+
+	return errors.New(description)
+}
+
+// forwardSignals sends the signals received to the command, until it's stopped
+func (c *command) forwardSignals(process *os.Process) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-c.signals:
+				_ = process.Signal(sig)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// signalExitCode returns the exit code of a signal, 128 + signal like the shells
+func signalExitCode(sig os.Signal) int {
+	if number, ok := sig.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+	return ExitCannotRun
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/joaosoft/monitor/client"
+)
+
+func TestExitCode(t *testing.T) {
+	problem := func(status int, code client.ErrorCode) error {
+		e := &client.Error{}
+		e.Status, e.Code = status, code
+		return e
+	}
+	errCommand := errors.New("exit code 3")
+
+	tests := []struct {
+		name            string
+		err             error
+		started         bool
+		commandExitCode int
+		code            int
+		message         string
+	}{
+		{name: "succeeded", code: 0},
+		{name: "lease lost", err: client.ErrLeaseLost, started: true, code: ExitTempFail, message: "isn't running anymore"},
+		{name: "command failed", err: errCommand, started: true, commandExitCode: 3, code: 3},
+		{name: "command not started", err: errCommand, started: true, commandExitCode: ExitCannotRun, code: ExitCannotRun, message: "exit code 3"},
+		{name: "status not recorded", err: errors.New("exit code 3, and setting the process as failed"), started: true, commandExitCode: 3, code: 3, message: "setting the process as failed"},
+		{name: "already running", err: problem(http.StatusConflict, client.ErrorCodeAlreadyRunning), code: ExitTempFail, message: "can't run now"},
+		{name: "window closed", err: problem(http.StatusConflict, client.ErrorCodeWindowClosed), code: ExitTempFail, message: "can't run now"},
+		{name: "not found", err: problem(http.StatusNotFound, client.ErrorCodeProcessNotFound), code: ExitNotFound, message: "doesn't exist"},
+		{name: "unavailable", err: errors.New("connection refused"), code: ExitUnavailable, message: "error starting the process"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, message := exitCode("job", test.err, errCommand, test.started, test.commandExitCode)
+			if code != test.code {
+				t.Errorf("expected the exit code %d, got %d", test.code, code)
+			}
+			if (test.message == "") != (message == "") || !strings.Contains(message, test.message) {
+				t.Errorf("expected the message with %q, got %q", test.message, message)
+			}
+		})
+	}
+}
+
+func TestSignalBeforeRunning(t *testing.T) {
+	cmd := &command{args: []string{"true"}, output: newTailBuffer(1), signals: make(chan os.Signal, 1)}
+	cmd.signals <- syscall.SIGTERM
+
+	if err := cmd.execute(context.Background()); err == nil || cmd.exitCode != 143 {
+		t.Errorf("expected the command to fail with the exit code 143, got %d %v", cmd.exitCode, err)
+	}
+}
+
+func TestSignalExitCode(t *testing.T) {
+	if code := signalExitCode(syscall.SIGINT); code != 130 {
+		t.Errorf("expected the exit code 130, got %d", code)
+	}
+	if code := signalExitCode(os.Kill); code != 137 {
+		t.Errorf("expected the exit code 137, got %d", code)
+	}
+}
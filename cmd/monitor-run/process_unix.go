@@ -0,0 +1,32 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are sent to the command
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// setProcessGroup starts the command on its own process group, so the signals of the terminal
+// reach it only once, forwarded by monitor-run
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminate(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
+
+// exitStatus returns the exit code of the command, 128 + signal when it was killed by a signal like the shells
+func exitStatus(state *os.ProcessState) (int, string) {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), fmt.Sprintf("killed by signal %s", status.Signal())
+	}
+
+	return state.ExitCode(), fmt.Sprintf("exit code %d", state.ExitCode())
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		code        int
+		description string
+	}{
+		{name: "exited", script: "exit 3", code: 3, description: "exit code 3"},
+		{name: "killed", script: "kill -TERM $$", code: 143, description: "killed by signal terminated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", test.script)
+			if err := cmd.Run(); err == nil {
+				t.Fatalf("expected the command to fail")
+			}
+
+			if code, description := exitStatus(cmd.ProcessState); code != test.code || description != test.description {
+				t.Errorf("expected %d %q, got %d %q", test.code, test.description, code, description)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals are sent to the command
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the command, windows can't deliver other signals
+func terminate(process *os.Process) error {
+	return process.Kill()
+}
+
+func exitStatus(state *os.ProcessState) (int, string) {
+	return state.ExitCode(), fmt.Sprintf("exit code %d", state.ExitCode())
+}
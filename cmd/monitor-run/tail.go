@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// maxLineLength truncates the long lines kept by the tail
+const maxLineLength = 512
+
+// tailBuffer keeps the last lines written, from both stdout and stderr
+type tailBuffer struct {
+	lines   []string
+	max     int
+	partial bytes.Buffer
+	mux     sync.Mutex
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write ...
+func (tail *tailBuffer) Write(p []byte) (int, error) {
+	tail.mux.Lock()
+	defer tail.mux.Unlock()
+
+	for _, b := range p {
+		if b == '\n' {
+			tail.add(tail.partial.String())
+			tail.partial.Reset()
+			continue
+		}
+
+		if tail.partial.Len() < maxLineLength {
+			tail.partial.WriteByte(b)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (tail *tailBuffer) add(line string) {
+	if tail.max <= 0 {
+		return
+	}
+
+	tail.lines = append(tail.lines, line)
+	if len(tail.lines) > tail.max {
+		tail.lines = tail.lines[len(tail.lines)-tail.max:]
+	}
+}
+
+// String returns the last lines, with the last line even when it isn't terminated
+func (tail *tailBuffer) String() string {
+	tail.mux.Lock()
+	defer tail.mux.Unlock()
+
+	lines := tail.lines
+	if tail.partial.Len() > 0 {
+		lines = append(append([]string{}, lines...), tail.partial.String())
+		if len(lines) > tail.max {
+			lines = lines[len(lines)-tail.max:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
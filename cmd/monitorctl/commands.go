@@ -0,0 +1,583 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joaosoft/monitor/client"
+)
+
+func listCommand(cli *cli, args []string) error {
+	flags := cli.flags("list")
+	filter := filterFlag{}
+	flags.Var(filter, "filter", "filter by id_process, type, name, monitor or status, as key=value (repeatable)")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	processes, err := cli.client.GetProcesses(cli.ctx, url.Values(filter))
+	if err != nil {
+		return err
+	}
+
+	return cli.printer.processes(processes)
+}
+
+func getCommand(cli *cli, args []string) error {
+	flags := cli.flags("get")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	id, err := processId(cli)
+	if err != nil {
+		return err
+	}
+
+	process, err := cli.client.GetProcess(cli.ctx, id, client.IncludeSchedule)
+	if err != nil {
+		return err
+	}
+
+	return cli.printer.process(process)
+}
+
+func createCommand(cli *cli, args []string) error {
+	flags := cli.flags("create")
+	file := flags.String("f", "", "json or yaml file with the process, or processes, - for the stdin")
+	fields := &processFlags{}
+	fields.register(flags, true)
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	documents := []*document{fields.document()}
+	if *file != "" {
+		var err error
+		if documents, err = readDocuments(cli, *file); err != nil {
+			return err
+		}
+	}
+
+	for _, document := range documents {
+		if err := cli.client.CreateProcess(cli.ctx, document.process()); err != nil {
+			return fmt.Errorf("error creating process %s: %w", document.IdProcess, err)
+		}
+		cli.printer.message("process %s created", document.IdProcess)
+	}
+
+	return nil
+}
+
+func updateCommand(cli *cli, args []string) error {
+	flags := cli.flags("update")
+	file := flags.String("f", "", "json or yaml file with the process, - for the stdin")
+	version := flags.Int64("version", 0, "update only when the process is still on the version")
+	fields := &processFlags{}
+	fields.register(flags, false)
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	id, err := processId(cli)
+	if err != nil {
+		return err
+	}
+
+	if *file != "" {
+		documents, err := readDocuments(cli, *file)
+		if err != nil {
+			return err
+		}
+		if len(documents) != 1 {
+			return newUsageError("expected a single process on %s", *file)
+		}
+
+		process := documents[0].process()
+		process.IdProcess = id
+		process.Version = *version
+		if err := cli.client.UpdateProcess(cli.ctx, process); err != nil {
+			return err
+		}
+
+		cli.printer.message("process %s updated to version %d", id, process.Version)
+		return nil
+	}
+
+	patch := fields.patch(flags)
+	if len(patch) == 0 {
+		return newUsageError("expected a file or the fields to update")
+	}
+
+	newVersion, err := cli.client.PatchProcess(cli.ctx, id, patch, *version)
+	if err != nil {
+		return err
+	}
+
+	cli.printer.message("process %s updated to version %d", id, newVersion)
+	return nil
+}
+
+func deleteCommand(cli *cli, args []string) error {
+	flags := cli.flags("delete")
+	version := flags.Int64("version", 0, "delete only when the process is still on the version")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	id, err := processId(cli)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.client.DeleteProcess(cli.ctx, id, *version); err != nil {
+		return err
+	}
+
+	cli.printer.message("process %s deleted", id)
+	return nil
+}
+
+func startCommand(cli *cli, args []string) error {
+	return statusCommand(cli, "start", client.StatusRunning, args)
+}
+
+func stopCommand(cli *cli, args []string) error {
+	return statusCommand(cli, "stop", client.StatusStopped, args)
+}
+
+func statusCommand(cli *cli, name string, status client.Status, args []string) error {
+	flags := cli.flags(name)
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	id, err := processId(cli)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.client.UpdateProcessStatus(cli.ctx, id, status); err != nil {
+		return err
+	}
+
+	cli.printer.message("process %s %s", id, status)
+	return nil
+}
+
+func checkCommand(cli *cli, args []string) error {
+	flags := cli.flags("check")
+	status := flags.String("status", string(client.StatusRunning), "status to check")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	id, err := processId(cli)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.client.CheckProcessStatus(cli.ctx, id, client.Status(*status)); err != nil {
+		return err
+	}
+
+	cli.printer.message("process %s can be %s", id, *status)
+	return nil
+}
+
+// event is a change of a process, found by tail
+type event struct {
+	Time      time.Time     `json:"time"`
+	Event     string        `json:"event"`
+	IdProcess string        `json:"id_process"`
+	Status    client.Status `json:"status,omitempty"`
+	Version   int64         `json:"version,omitempty"`
+	Message   string        `json:"message,omitempty"`
+}
+
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventStatus  = "status"
+	EventDeleted = "deleted"
+)
+
+// tailCommand polls the processes, writing their changes until it's interrupted
+func tailCommand(cli *cli, args []string) error {
+	flags := cli.flags("tail")
+	interval := flags.Duration("interval", 2*time.Second, "interval between polls")
+	filter := filterFlag{}
+	flags.Var(filter, "filter", "filter by id_process, type, name, monitor or status, as key=value (repeatable)")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var known map[string]*client.Process
+	for {
+		processes, err := cli.client.GetProcesses(cli.ctx, url.Values(filter))
+		switch {
+		case cli.ctx.Err() != nil:
+			return nil
+		case err != nil:
+			// keeps following, the monitor may be restarting
+			fmt.Fprintf(cli.stderr, "error: %s\n", err)
+		case known == nil:
+			known = indexProcesses(processes)
+			cli.printer.message("following %d processes", len(known))
+		default:
+			current := indexProcesses(processes)
+			for _, e := range changes(known, current) {
+				if err := cli.printer.event(e); err != nil {
+					return err
+				}
+			}
+			known = current
+		}
+
+		select {
+		case <-cli.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func indexProcesses(processes client.ListProcess) map[string]*client.Process {
+	index := make(map[string]*client.Process, len(processes))
+	for _, process := range processes {
+		index[process.IdProcess] = process
+	}
+	return index
+}
+
+// changes returns the events between two polls
+func changes(previous, current map[string]*client.Process) []*event {
+	now := time.Now()
+	events := make([]*event, 0)
+
+	for id, process := range current {
+		e := &event{Time: now, IdProcess: id, Version: process.Version, Message: process.Message}
+		if process.Status != nil {
+			e.Status = *process.Status
+		}
+
+		old, ok := previous[id]
+		switch {
+		case !ok:
+			e.Event = EventCreated
+		case old.Version == process.Version:
+			continue
+		case status(old) != status(process):
+			e.Event = EventStatus
+		default:
+			e.Event = EventUpdated
+		}
+		events = append(events, e)
+	}
+
+	for id := range previous {
+		if _, ok := current[id]; !ok {
+			events = append(events, &event{Time: now, Event: EventDeleted, IdProcess: id})
+		}
+	}
+
+	return events
+}
+
+func exportCommand(cli *cli, args []string) error {
+	flags := cli.flags("export")
+	file := flags.String("f", "", "file to write, by default the stdout")
+	filter := filterFlag{}
+	flags.Var(filter, "filter", "filter by id_process, type, name, monitor or status, as key=value (repeatable)")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	processes, err := cli.client.GetProcesses(cli.ctx, url.Values(filter))
+	if err != nil {
+		return err
+	}
+
+	documents := make([]*document, 0, len(processes))
+	for _, process := range processes {
+		documents = append(documents, newDocument(process))
+	}
+
+	// the format is given by the extension of the file or by the output, json by default
+	format := cli.output
+	if strings.HasSuffix(*file, ".yaml") || strings.HasSuffix(*file, ".yml") {
+		format = OutputYAML
+	} else if strings.HasSuffix(*file, ".json") || format != OutputYAML {
+		format = OutputJSON
+	}
+
+	out := cli.stdout
+	if *file != "" && *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	printer, err := newPrinter(format, out)
+	if err != nil {
+		return err
+	}
+
+	return printer.print(documents, nil, nil)
+}
+
+func importCommand(cli *cli, args []string) error {
+	flags := cli.flags("import")
+	file := flags.String("f", "", "json or yaml file with the processes, - for the stdin")
+	mode := flags.String("mode", string(client.BatchModeAtomic), "atomic, to apply all or none, or best_effort")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return newUsageError("expected the file to import")
+	}
+
+	documents, err := readDocuments(cli, *file)
+	if err != nil {
+		return err
+	}
+
+	processes, err := cli.client.GetProcesses(cli.ctx, nil)
+	if err != nil {
+		return err
+	}
+	existing := indexProcesses(processes)
+
+	// the existing processes are updated and the others created
+	operations := make([]*client.BatchOperationRequest, 0, len(documents))
+	for _, document := range documents {
+		operation := &client.BatchOperationRequest{Op: client.BatchOperationCreate, IdProcess: document.IdProcess}
+		if _, ok := existing[document.IdProcess]; ok {
+			operation.Op = client.BatchOperationUpdate
+		}
+
+		if operation.Body, err = document.body(); err != nil {
+			return err
+		}
+		operations = append(operations, operation)
+	}
+
+	results := make([]*client.BatchOperationResult, 0, len(operations))
+	failed := 0
+	for start := 0; start < len(operations); start += client.MaxBatchOperations {
+		end := start + client.MaxBatchOperations
+		if end > len(operations) {
+			end = len(operations)
+		}
+
+		response, err := cli.client.Batch(cli.ctx, client.BatchMode(*mode), operations[start:end])
+		if err != nil {
+			return err
+		}
+
+		for _, result := range response.Results {
+			result.Index += start
+			results = append(results, result)
+		}
+		failed += response.Failed
+	}
+
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		message := http.StatusText(int(result.Status))
+		if result.Error != nil {
+			message = fmt.Sprintf("%s: %s", result.Error.Code, result.Error.Detail)
+		}
+		rows = append(rows, []string{fmt.Sprintf("%d", result.Index), result.IdProcess, string(result.Op), message})
+	}
+
+	if err := cli.printer.print(results, []string{"INDEX", "ID", "OPERATION", "RESULT"}, rows); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d processes weren't imported", failed, len(results))
+	}
+
+	return nil
+}
+
+func planCommand(cli *cli, args []string) error {
+	return definitionsCommand(cli, "plan", args)
+}
+
+func applyCommand(cli *cli, args []string) error {
+	return definitionsCommand(cli, "apply", args)
+}
+
+// definitionsCommand plans or applies the process definitions of a file, the changes are applied on a single transaction
+func definitionsCommand(cli *cli, name string, args []string) error {
+	flags := cli.flags(name)
+	file := flags.String("f", "", "json or yaml file with the process definitions, - for the stdin")
+	prune := flags.Bool("prune", false, "delete the processes that aren't on the file")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return newUsageError("expected the file with the process definitions")
+	}
+
+	documents, err := readDocuments(cli, *file)
+	if err != nil {
+		return err
+	}
+
+	definitions := make([]*client.ProcessDefinition, 0, len(documents))
+	for _, document := range documents {
+		definitions = append(definitions, document.definition())
+	}
+
+	apply := cli.client.PlanProcesses
+	if name == "apply" {
+		apply = cli.client.ApplyProcesses
+	}
+
+	response, err := apply(cli.ctx, definitions, *prune)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.printer.changes(response); err != nil {
+		return err
+	}
+
+	switch {
+	case len(response.Changes) == 0:
+		cli.printer.message("no changes, the processes match the definitions")
+	case response.DryRun:
+		cli.printer.message("plan: %d to create, %d to update, %d to delete", response.Created, response.Updated, response.Deleted)
+	default:
+		cli.printer.message("applied: %d created, %d updated, %d deleted", response.Created, response.Updated, response.Deleted)
+	}
+
+	return nil
+}
+
+// migration is the result of a migration, with the lines that couldn't be translated
+type migration struct {
+	*client.ApplyProcessesResponse
+	Issues []*legacyIssue `json:"issues"`
+}
+
+// migrateCommand translates crontab files and systemd timers to processes, the .timer files are read as systemd timers
+func migrateCommand(cli *cli, args []string) error {
+	flags := cli.flags("migrate")
+	system := flags.Bool("system", false, "read the crontab files as system crontabs, with the user after the schedule")
+	dryRun := flags.Bool("dry-run", false, "only show the changes, without applying them")
+	if err := cli.parse(flags, args); err != nil {
+		return err
+	}
+
+	if len(cli.args) == 0 {
+		return newUsageError("expected the crontab or systemd timer files, - for the stdin")
+	}
+
+	definitions := make([]*client.ProcessDefinition, 0)
+	issues := make([]*legacyIssue, 0)
+
+	for _, file := range cli.args {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(cli.stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", file, err)
+		}
+
+		var parsed []*client.ProcessDefinition
+		var skipped []*legacyIssue
+		if strings.HasSuffix(file, ".timer") {
+			parsed, skipped = parseSystemdTimer(file, data)
+		} else {
+			parsed, skipped = parseCrontab(file, data, *system)
+		}
+
+		definitions = append(definitions, parsed...)
+		issues = append(issues, skipped...)
+	}
+
+	if len(definitions) == 0 {
+		cli.printer.issues(issues)
+		return fmt.Errorf("there aren't schedules that can be translated")
+	}
+
+	apply := cli.client.ApplyProcesses
+	if *dryRun {
+		apply = cli.client.PlanProcesses
+	}
+
+	response, err := apply(cli.ctx, definitions, false)
+	if err != nil {
+		return err
+	}
+
+	if cli.printer.format != OutputTable {
+		return cli.printer.print(&migration{ApplyProcessesResponse: response, Issues: issues}, nil, nil)
+	}
+
+	if err := cli.printer.changes(response); err != nil {
+		return err
+	}
+	cli.printer.issues(issues)
+
+	if response.DryRun {
+		cli.printer.message("plan: %d to create, %d to update, %d skipped", response.Created, response.Updated, len(issues))
+	} else {
+		cli.printer.message("migrated: %d created, %d updated, %d skipped", response.Created, response.Updated, len(issues))
+	}
+
+	return nil
+}
+
+func processId(cli *cli) (string, error) {
+	if len(cli.args) != 1 {
+		return "", newUsageError("expected the id of the process")
+	}
+	return cli.args[0], nil
+}
+
+// exitCode returns the exit code of an error
+func exitCode(err error) int {
+	var usage *usageError
+	if errors.As(err, &usage) {
+		return ExitUsage
+	}
+
+	var e *client.Error
+	if errors.As(err, &e) {
+		switch e.Status {
+		case http.StatusNotFound:
+			return ExitNotFound
+		case http.StatusConflict:
+			return ExitConflict
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return ExitInvalid
+		case http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+			return ExitVersionMismatch
+		}
+	}
+
+	return ExitError
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/joaosoft/monitor/client"
+)
+
+func TestChanges(t *testing.T) {
+	running, stopped := client.StatusRunning, client.StatusStopped
+	previous := map[string]*client.Process{
+		"same":    {IdProcess: "same", Version: 1},
+		"started": {IdProcess: "started", Version: 1, Status: &stopped},
+		"renamed": {IdProcess: "renamed", Version: 1, Name: "old"},
+		"deleted": {IdProcess: "deleted", Version: 1},
+	}
+	current := map[string]*client.Process{
+		"same":    {IdProcess: "same", Version: 1},
+		"started": {IdProcess: "started", Version: 2, Status: &running},
+		"renamed": {IdProcess: "renamed", Version: 2, Name: "new"},
+		"created": {IdProcess: "created", Version: 1},
+	}
+
+	var events []string
+	for _, e := range changes(previous, current) {
+		events = append(events, fmt.Sprintf("%s:%s:%s", e.IdProcess, e.Event, e.Status))
+	}
+	sort.Strings(events)
+
+	if expected := "created:created: deleted:deleted: renamed:updated: started:status:running"; strings.Join(events, " ") != expected {
+		t.Errorf("expected the events %s, got %s", expected, strings.Join(events, " "))
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	problem := func(status int) error {
+		e := &client.Error{}
+		e.Status = status
+		return e
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "usage", err: newUsageError("missing the id"), code: ExitUsage},
+		{name: "not found", err: problem(http.StatusNotFound), code: ExitNotFound},
+		{name: "conflict", err: problem(http.StatusConflict), code: ExitConflict},
+		{name: "invalid", err: problem(http.StatusBadRequest), code: ExitInvalid},
+		{name: "unprocessable", err: problem(http.StatusUnprocessableEntity), code: ExitInvalid},
+		{name: "version mismatch", err: problem(http.StatusPreconditionFailed), code: ExitVersionMismatch},
+		{name: "version required", err: problem(http.StatusPreconditionRequired), code: ExitVersionMismatch},
+		{name: "wrapped", err: fmt.Errorf("importing: %w", problem(http.StatusNotFound)), code: ExitNotFound},
+		{name: "internal", err: problem(http.StatusInternalServerError), code: ExitError},
+		{name: "unavailable", err: fmt.Errorf("connection refused"), code: ExitError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := exitCode(test.err); code != test.code {
+				t.Errorf("expected the exit code %d, got %d", test.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/joaosoft/monitor/client"
+)
+
+const (
+	EnvURL    = "MONITOR_URL"
+	EnvToken  = "MONITOR_TOKEN"
+	EnvConfig = "MONITORCTL_CONFIG"
+
+	defaultConfigFile = ".monitorctl.json"
+)
+
+// Config is the config file of monitorctl
+type Config struct {
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Output string `json:"output"`
+}
+
+// cli has the state shared by the commands
+type cli struct {
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	url        string
+	token      string
+	configFile string
+	output     string
+	args       []string
+
+	client  *client.Client
+	printer *printer
+}
+
+// flags returns the flags of a command, with the flags of every command
+func (cli *cli) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	flags.StringVar(&cli.url, "url", "", "url of the monitor")
+	flags.StringVar(&cli.token, "token", "", "token sent as bearer authorization")
+	flags.StringVar(&cli.configFile, "config", "", "json config file")
+	flags.StringVar(&cli.output, "output", "", "table, json or yaml")
+	flags.StringVar(&cli.output, "o", "", "table, json or yaml")
+	return flags
+}
+
+// parse parses the flags of the command and sets up the client, the flags have precedence over the env,
+// that has precedence over the config file
+func (cli *cli) parse(flags *flag.FlagSet, args []string) error {
+	// the flags can be given before or after the arguments
+	for {
+		if err := flags.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return err
+			}
+			return newUsageError("%s", err)
+		}
+
+		if args = flags.Args(); len(args) == 0 {
+			break
+		}
+		cli.args = append(cli.args, args[0])
+		args = args[1:]
+	}
+
+	config, err := cli.config()
+	if err != nil {
+		return err
+	}
+
+	if cli.url == "" {
+		cli.url = firstNotEmpty(os.Getenv(EnvURL), config.URL, client.DefaultURL)
+	}
+	if cli.token == "" {
+		cli.token = firstNotEmpty(os.Getenv(EnvToken), config.Token)
+	}
+	if cli.output == "" {
+		cli.output = firstNotEmpty(config.Output, OutputTable)
+	}
+
+	printer, err := newPrinter(cli.output, cli.stdout)
+	if err != nil {
+		return err
+	}
+	cli.printer = printer
+
+	var options []client.ClientOption
+	if cli.token != "" {
+		options = append(options, client.WithHeader("Authorization", "Bearer "+cli.token))
+	}
+	cli.client = client.NewClient(cli.url, options...)
+
+	return nil
+}
+
+// config reads the config file, that is optional unless it was explicitly given
+func (cli *cli) config() (*Config, error) {
+	config := &Config{}
+
+	file := firstNotEmpty(cli.configFile, os.Getenv(EnvConfig))
+	explicit := file != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return config, nil
+		}
+		file = filepath.Join(home, defaultConfigFile)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return config, nil
+		}
+		return nil, fmt.Errorf("error reading config file %s: %s", file, err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %s", file, err)
+	}
+
+	return config, nil
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
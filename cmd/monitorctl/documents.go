@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joaosoft/monitor/client"
+	"github.com/joaosoft/types"
+	"gopkg.in/yaml.v3"
+)
+
+// document is a process on the files of create, update, import and export
+type document struct {
+	IdProcess   string         `json:"id_process"`
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	DateFrom    *types.Date    `json:"date_from,omitempty"`
+	DateTo      *types.Date    `json:"date_to,omitempty"`
+	TimeFrom    *types.Time    `json:"time_from,omitempty"`
+	TimeTo      *types.Time    `json:"time_to,omitempty"`
+	DaysOff     *types.ListDay `json:"days_off,omitempty"`
+	Monitor     string         `json:"monitor,omitempty"`
+	Status      *client.Status `json:"status,omitempty"`
+}
+
+func newDocument(process *client.Process) *document {
+	return &document{
+		IdProcess:   process.IdProcess,
+		Type:        process.Type,
+		Name:        process.Name,
+		Description: process.Description,
+		DateFrom:    process.DateFrom,
+		DateTo:      process.DateTo,
+		TimeFrom:    process.TimeFrom,
+		TimeTo:      process.TimeTo,
+		DaysOff:     process.DaysOff,
+		Monitor:     process.Monitor,
+		Status:      process.Status,
+	}
+}
+
+func (document *document) process() *client.Process {
+	return &client.Process{
+		IdProcess:   document.IdProcess,
+		Type:        document.Type,
+		Name:        document.Name,
+		Description: document.Description,
+		DateFrom:    document.DateFrom,
+		DateTo:      document.DateTo,
+		TimeFrom:    document.TimeFrom,
+		TimeTo:      document.TimeTo,
+		DaysOff:     document.DaysOff,
+		Monitor:     document.Monitor,
+		Status:      document.Status,
+	}
+}
+
+func (document *document) definition() *client.ProcessDefinition {
+	return &client.ProcessDefinition{
+		IdProcess:   document.IdProcess,
+		Type:        document.Type,
+		Name:        document.Name,
+		Description: document.Description,
+		DateFrom:    document.DateFrom,
+		DateTo:      document.DateTo,
+		TimeFrom:    document.TimeFrom,
+		TimeTo:      document.TimeTo,
+		DaysOff:     document.DaysOff,
+		Monitor:     document.Monitor,
+		Status:      document.Status,
+	}
+}
+
+// body returns the body of the document on a batch operation
+func (document *document) body() (json.RawMessage, error) {
+	return json.Marshal(document)
+}
+
+// readDocuments reads a json or yaml file with a process, a list of processes or
+// the list on the key processes, like the definitions of plan and apply
+func readDocuments(cli *cli, file string) ([]*document, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(cli.stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", file, err)
+	}
+
+	// json is also yaml, so both are read as yaml and converted to json
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", file, err)
+	}
+	if definitions, ok := value.(map[string]interface{}); ok && definitions["processes"] != nil {
+		value = definitions["processes"]
+	}
+	if _, ok := value.([]interface{}); !ok {
+		value = []interface{}{value}
+	}
+
+	if data, err = json.Marshal(value); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", file, err)
+	}
+
+	documents := make([]*document, 0)
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", file, err)
+	}
+
+	return documents, nil
+}
+
+// processFlags are the fields of a process given as flags
+type processFlags struct {
+	id          string
+	typ         string
+	name        string
+	description string
+	monitor     string
+	dateFrom    string
+	dateTo      string
+	timeFrom    string
+	timeTo      string
+	daysOff     string
+	status      string
+}
+
+func (fields *processFlags) register(flags *flag.FlagSet, withId bool) {
+	if withId {
+		flags.StringVar(&fields.id, "id", "", "id of the process")
+	}
+	flags.StringVar(&fields.typ, "type", "", "type of the process")
+	flags.StringVar(&fields.name, "name", "", "name of the process")
+	flags.StringVar(&fields.description, "description", "", "description of the process")
+	flags.StringVar(&fields.monitor, "monitor", "", "who monitors the process")
+	flags.StringVar(&fields.dateFrom, "date-from", "", "first date of the window, as dd-mm-yyyy")
+	flags.StringVar(&fields.dateTo, "date-to", "", "last date of the window, as dd-mm-yyyy")
+	flags.StringVar(&fields.timeFrom, "time-from", "", "first time of the window, as hh:mm:ss")
+	flags.StringVar(&fields.timeTo, "time-to", "", "last time of the window, as hh:mm:ss")
+	flags.StringVar(&fields.daysOff, "days-off", "", "days outside of the window, separated by commas")
+	flags.StringVar(&fields.status, "status", "", "stopped, running or failed")
+}
+
+func (fields *processFlags) document() *document {
+	document := &document{
+		IdProcess:   fields.id,
+		Type:        fields.typ,
+		Name:        fields.name,
+		Description: fields.description,
+		Monitor:     fields.monitor,
+	}
+
+	if fields.dateFrom != "" {
+		document.DateFrom = (*types.Date)(&fields.dateFrom)
+	}
+	if fields.dateTo != "" {
+		document.DateTo = (*types.Date)(&fields.dateTo)
+	}
+	if fields.timeFrom != "" {
+		document.TimeFrom = (*types.Time)(&fields.timeFrom)
+	}
+	if fields.timeTo != "" {
+		document.TimeTo = (*types.Time)(&fields.timeTo)
+	}
+	if fields.daysOff != "" {
+		document.DaysOff = listDay(fields.daysOff)
+	}
+	if fields.status != "" {
+		document.Status = (*client.Status)(&fields.status)
+	}
+
+	return document
+}
+
+// patch returns a json merge patch with the given flags, an empty value removes the field
+func (fields *processFlags) patch(flags *flag.FlagSet) map[string]interface{} {
+	values := map[string]*string{
+		"type":        &fields.typ,
+		"name":        &fields.name,
+		"description": &fields.description,
+		"monitor":     &fields.monitor,
+		"date-from":   &fields.dateFrom,
+		"date-to":     &fields.dateTo,
+		"time-from":   &fields.timeFrom,
+		"time-to":     &fields.timeTo,
+		"days-off":    &fields.daysOff,
+		"status":      &fields.status,
+	}
+
+	patch := make(map[string]interface{})
+	flags.Visit(func(f *flag.Flag) {
+		value, ok := values[f.Name]
+		if !ok {
+			return
+		}
+
+		field := strings.Replace(f.Name, "-", "_", -1)
+		switch {
+		case *value == "":
+			patch[field] = nil
+		case f.Name == "days-off":
+			patch[field] = listDay(*value)
+		default:
+			patch[field] = *value
+		}
+	})
+
+	return patch
+}
+
+func listDay(value string) *types.ListDay {
+	days := make(types.ListDay, 0)
+	for _, day := range strings.Split(value, ",") {
+		if day = strings.TrimSpace(day); day != "" {
+			days = append(days, types.Day(strings.ToLower(day)))
+		}
+	}
+	return &days
+}
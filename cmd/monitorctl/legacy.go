@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaosoft/monitor/client"
+	"github.com/joaosoft/types"
+)
+
+const (
+	ProcessTypeCron    = "cron"
+	ProcessTypeSystemd = "systemd"
+)
+
+// legacyIssue is a line of a crontab or systemd timer that couldn't be translated to a process
+type legacyIssue struct {
+	Source string `json:"source"`
+	Line   int    `json:"line,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// legacySchedule is the window of a legacy schedule, the smallest one including every run
+type legacySchedule struct {
+	days     map[time.Weekday]bool // nil on every day
+	timeFrom string                // empty on any time
+	timeTo   string
+}
+
+var (
+	cronShortcuts = map[string]string{
+		"@hourly":   "0 * * * *",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@weekly":   "0 0 * * 0",
+	}
+
+	weekdayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+		"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6,
+	}
+
+	systemdShortcuts = map[string]string{
+		"minutely": "*-*-* *:*:00",
+		"hourly":   "*-*-* *:00:00",
+		"daily":    "*-*-* 00:00:00",
+		"weekly":   "Mon *-*-* 00:00:00",
+	}
+
+	cronVariable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+	slugInvalid  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// parseCrontab translates the entries of a crontab to process definitions, with the type cron and an id
+// generated from the command and the line, so importing the same crontab again gives the same ids.
+// The system crontabs, like /etc/crontab, have the user before the command.
+// The entries with a day of the month or a month, and @reboot, can't be translated and are returned as issues
+func parseCrontab(source string, data []byte, system bool) ([]*client.ProcessDefinition, []*legacyIssue) {
+	definitions := make([]*client.ProcessDefinition, 0)
+	issues := make([]*legacyIssue, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || cronVariable.MatchString(line) {
+			continue
+		}
+
+		definition, err := parseCronLine(line, system)
+		if err != nil {
+			issues = append(issues, &legacyIssue{Source: source, Line: number, Text: line, Reason: err.Error()})
+			continue
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, issues
+}
+
+func parseCronLine(line string, system bool) (*client.ProcessDefinition, error) {
+	fields := strings.Fields(line)
+	skip := 5
+	if strings.HasPrefix(fields[0], "@") {
+		expanded, ok := cronShortcuts[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("%s can't be translated to a window", fields[0])
+		}
+		fields, skip = strings.Fields(expanded), 1
+	}
+	if system {
+		skip++
+	}
+
+	command := skipFields(line, skip)
+	if len(fields) < 5 || command == "" {
+		return nil, fmt.Errorf("expected the schedule and the command")
+	}
+
+	if !anyValue(fields[2]) || !anyValue(fields[3]) {
+		return nil, fmt.Errorf("the day of the month and the month can't be translated to a window")
+	}
+
+	minutes, err := cronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minute: %s", err)
+	}
+	hours, err := cronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hour: %s", err)
+	}
+	weekdays, err := cronField(fields[4], 0, 7, weekdayNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day of the week: %s", err)
+	}
+
+	schedule := &legacySchedule{days: weekdaySet(weekdays)}
+	schedule.window(hours, minutes, nil)
+
+	executable := path.Base(strings.Fields(command)[0])
+	return schedule.definition(legacyId(ProcessTypeCron, executable, line), ProcessTypeCron, command, line), nil
+}
+
+// skipFields returns the line after the first fields
+func skipFields(line string, count int) string {
+	for i := 0; i < count; i++ {
+		line = strings.TrimLeft(line, " \t")
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			return ""
+		}
+		line = line[end:]
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// parseSystemdTimer translates a systemd timer unit to a process definition, with the type systemd and the id
+// from the name of the unit. The OnCalendar expressions of the timer are joined on a single window.
+// The monotonic timers, like OnBootSec, and the calendars with dates can't be translated and are returned as issues
+func parseSystemdTimer(source string, data []byte) ([]*client.ProcessDefinition, []*legacyIssue) {
+	issues := make([]*legacyIssue, 0)
+
+	unit := strings.TrimSuffix(path.Base(source), ".timer")
+	name, calendars := unit, make([]string, 0)
+	var schedule *legacySchedule
+
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case section == "Unit" && key == "Description":
+			name = value
+		case section == "Timer" && key == "Unit":
+			unit = strings.TrimSuffix(value, ".service")
+		case section == "Timer" && key == "OnCalendar":
+			calendar, err := parseCalendar(value)
+			if err != nil {
+				issues = append(issues, &legacyIssue{Source: source, Line: number, Text: line, Reason: err.Error()})
+				continue
+			}
+			schedule = schedule.join(calendar)
+			calendars = append(calendars, line)
+		case section == "Timer" && strings.HasPrefix(key, "On"):
+			issues = append(issues, &legacyIssue{Source: source, Line: number, Text: line, Reason: "monotonic timers can't be translated to a window"})
+		}
+	}
+
+	if schedule == nil {
+		if len(issues) == 0 {
+			issues = append(issues, &legacyIssue{Source: source, Reason: "the timer hasn't an OnCalendar"})
+		}
+		return []*client.ProcessDefinition{}, issues
+	}
+
+	definition := schedule.definition(legacyId(ProcessTypeSystemd, unit, ""), ProcessTypeSystemd, name, strings.Join(calendars, "; "))
+	return []*client.ProcessDefinition{definition}, issues
+}
+
+// parseCalendar translates an OnCalendar expression, as [weekdays] [date] [time]
+func parseCalendar(value string) (*legacySchedule, error) {
+	expression := strings.ToLower(strings.TrimSpace(value))
+	switch expression {
+	case "monthly", "quarterly", "semiannually", "yearly", "annually":
+		return nil, fmt.Errorf("%s can't be translated to a window", expression)
+	}
+	if expanded, ok := systemdShortcuts[expression]; ok {
+		expression = strings.ToLower(expanded)
+	}
+
+	schedule := &legacySchedule{}
+	times := "00:00:00"
+	for i, token := range strings.Fields(expression) {
+		switch {
+		case i == 0 && token[0] >= 'a' && token[0] <= 'z':
+			weekdays, err := cronField(strings.Replace(token, "..", "-", -1), 0, 7, weekdayNames)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weekdays: %s", err)
+			}
+			schedule.days = weekdaySet(weekdays)
+		case strings.Contains(token, ":"):
+			times = token
+		case strings.Contains(token, "-"):
+			for _, part := range strings.Split(token, "-") {
+				if part != "*" {
+					return nil, fmt.Errorf("the date %s can't be translated to a window", token)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s can't be translated to a window", token)
+		}
+	}
+
+	components := strings.Split(times, ":")
+	if len(components) < 2 || len(components) > 3 {
+		return nil, fmt.Errorf("invalid time %s", times)
+	}
+	if len(components) == 2 {
+		components = append(components, "00")
+	}
+
+	var sets [3]map[int]bool
+	limits := [3]int{23, 59, 59}
+	for i, component := range components {
+		// systemd repeats with /, like cron, and has ranges with ..
+		set, err := cronField(strings.Replace(component, "..", "-", -1), 0, limits[i], nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %s: %s", times, err)
+		}
+		sets[i] = set
+	}
+
+	schedule.window(sets[0], sets[1], sets[2])
+	return schedule, nil
+}
+
+// cronField expands a field, as a list of values, ranges and steps, to its values
+func cronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %s", stepPart)
+			}
+		}
+
+		from, to := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = fieldValue(first, min, max, names); err != nil {
+				return nil, err
+			}
+			to = from
+			if isRange {
+				if to, err = fieldValue(last, min, max, names); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				to = max
+			}
+			if to < from {
+				return nil, fmt.Errorf("invalid range %s", rangePart)
+			}
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func fieldValue(value string, min, max int, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("invalid value %s", value)
+	}
+
+	return number, nil
+}
+
+// anyValue checks if a field of a crontab accepts every value
+func anyValue(field string) bool {
+	return field == "*" || field == "*/1"
+}
+
+// weekdaySet returns the days of the values, nil on every day
+func weekdaySet(values map[int]bool) map[time.Weekday]bool {
+	days := make(map[time.Weekday]bool)
+	for value := range values {
+		days[time.Weekday(value%7)] = true
+	}
+
+	if len(days) == 7 {
+		return nil
+	}
+	return days
+}
+
+// window sets the window from the first to the last run of the day, any time when it runs every minute
+func (schedule *legacySchedule) window(hours, minutes, seconds map[int]bool) {
+	if len(hours) == 24 && len(minutes) == 60 {
+		return
+	}
+
+	first, last := -1, -1
+	for hour := range hours {
+		for minute := range minutes {
+			run := hour*60 + minute
+			if first < 0 || run < first {
+				first = run
+			}
+			if run > last {
+				last = run
+			}
+		}
+	}
+
+	second := 0
+	if len(seconds) > 0 {
+		second = 59
+		for value := range seconds {
+			if value < second {
+				second = value
+			}
+		}
+	}
+
+	schedule.timeFrom = fmt.Sprintf("%02d:%02d:%02d", first/60, first%60, second)
+	schedule.timeTo = fmt.Sprintf("%02d:%02d:59", last/60, last%60)
+}
+
+// join returns a schedule including both schedules
+func (schedule *legacySchedule) join(other *legacySchedule) *legacySchedule {
+	if schedule == nil {
+		return other
+	}
+
+	joined := &legacySchedule{}
+	if schedule.days != nil && other.days != nil {
+		joined.days = make(map[time.Weekday]bool)
+		for day := range schedule.days {
+			joined.days[day] = true
+		}
+		for day := range other.days {
+			joined.days[day] = true
+		}
+	}
+
+	if schedule.timeFrom != "" && other.timeFrom != "" {
+		joined.timeFrom, joined.timeTo = schedule.timeFrom, schedule.timeTo
+		if other.timeFrom < joined.timeFrom {
+			joined.timeFrom = other.timeFrom
+		}
+		if other.timeTo > joined.timeTo {
+			joined.timeTo = other.timeTo
+		}
+	}
+
+	return joined
+}
+
+func (schedule *legacySchedule) definition(id, typ, name, description string) *client.ProcessDefinition {
+	definition := &client.ProcessDefinition{
+		IdProcess:   id,
+		Type:        typ,
+		Name:        name,
+		Description: description,
+	}
+
+	if schedule.timeFrom != "" {
+		timeFrom, timeTo := types.Time(schedule.timeFrom), types.Time(schedule.timeTo)
+		definition.TimeFrom, definition.TimeTo = &timeFrom, &timeTo
+	}
+
+	if schedule.days != nil {
+		daysOff := make(types.ListDay, 0)
+		for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+			if !schedule.days[day] {
+				daysOff = append(daysOff, types.Day(strings.ToLower(day.String())))
+			}
+		}
+		definition.DaysOff = &daysOff
+	}
+
+	return definition
+}
+
+// legacyId returns an id with the type and the name, and a hash of the line when there's one
+func legacyId(typ, name, line string) string {
+	id := typ + "-" + strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if line == "" {
+		return id
+	}
+
+	hash := sha1.Sum([]byte(line))
+	return id + "-" + hex.EncodeToString(hash[:4])
+}
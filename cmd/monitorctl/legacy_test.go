@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/joaosoft/monitor/client"
+	"github.com/joaosoft/types"
+)
+
+var timeFormat = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}$`)
+
+// legacyWindow is the translated window of a definition, to compare on the tests
+type legacyWindow struct {
+	TimeFrom string
+	TimeTo   string
+	DaysOff  string
+}
+
+// validDefinition checks the fields validated by the monitor
+func validDefinition(definition *client.ProcessDefinition) bool {
+	if definition.IdProcess == "" || definition.Type == "" || definition.Name == "" {
+		return false
+	}
+	for _, value := range []*types.Time{definition.TimeFrom, definition.TimeTo} {
+		if value != nil && !timeFormat.MatchString(string(*value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func definitionWindow(definition *client.ProcessDefinition) legacyWindow {
+	var w legacyWindow
+	if definition.TimeFrom != nil {
+		w.TimeFrom, w.TimeTo = string(*definition.TimeFrom), string(*definition.TimeTo)
+	}
+	if definition.DaysOff != nil {
+		days := make([]string, 0, len(*definition.DaysOff))
+		for _, day := range *definition.DaysOff {
+			days = append(days, string(day))
+		}
+		w.DaysOff = strings.Join(days, ",")
+	}
+	return w
+}
+
+func TestParseCrontab(t *testing.T) {
+	tests := []struct {
+		line    string
+		system  bool
+		window  legacyWindow
+		command string
+		issue   string
+	}{
+		{line: "0 2 * * * /usr/bin/backup --full", window: legacyWindow{"02:00:00", "02:00:59", ""}, command: "/usr/bin/backup --full"},
+		{line: "*/15 * * * * ./poll.sh", window: legacyWindow{"00:00:00", "23:45:59", ""}, command: "./poll.sh"},
+		{line: "* * * * * ./always.sh", window: legacyWindow{}, command: "./always.sh"},
+		{line: "30 9-17 * * 1-5 report", window: legacyWindow{"09:30:00", "17:30:59", "saturday,sunday"}, command: "report"},
+		{line: "0 6,18 * * sat,sun cleanup", window: legacyWindow{"06:00:00", "18:00:59", "monday,tuesday,wednesday,thursday,friday"}, command: "cleanup"},
+		{line: "5 * * * 7 job", window: legacyWindow{"00:05:00", "23:05:59", "monday,tuesday,wednesday,thursday,friday,saturday"}, command: "job"},
+		{line: "@daily   /opt/nightly", window: legacyWindow{"00:00:00", "00:00:59", ""}, command: "/opt/nightly"},
+		{line: "@weekly root /opt/weekly", system: true, window: legacyWindow{"00:00:00", "00:00:59", "monday,tuesday,wednesday,thursday,friday,saturday"}, command: "/opt/weekly"},
+		{line: "0 4 * * * root run-parts /etc/cron.daily", system: true, window: legacyWindow{"04:00:00", "04:00:59", ""}, command: "run-parts /etc/cron.daily"},
+		{line: "0 0 1 * * monthly", issue: "day of the month"},
+		{line: "@reboot start", issue: "@reboot"},
+		{line: "0 25 * * * invalid", issue: "invalid hour"},
+		{line: "0 2 * *", issue: "expected the schedule and the command"},
+	}
+
+	for _, test := range tests {
+		definitions, issues := parseCrontab("crontab", []byte("# comment\nSHELL=/bin/sh\n\n"+test.line), test.system)
+
+		if test.issue != "" {
+			if len(definitions) != 0 || len(issues) != 1 || !strings.Contains(issues[0].Reason, test.issue) || issues[0].Line != 4 {
+				t.Errorf("%q: expected the issue %q, got %+v and %d definitions", test.line, test.issue, issues, len(definitions))
+			}
+			continue
+		}
+
+		if len(definitions) != 1 || len(issues) != 0 {
+			t.Errorf("%q: expected a definition, got %d definitions and issues %+v", test.line, len(definitions), issues)
+			continue
+		}
+
+		definition := definitions[0]
+		if w := definitionWindow(definition); w != test.window {
+			t.Errorf("%q: expected the window %+v, got %+v", test.line, test.window, w)
+		}
+		if definition.Name != test.command || definition.Type != ProcessTypeCron || definition.Description != test.line {
+			t.Errorf("%q: unexpected definition %+v", test.line, definition)
+		}
+		if !validDefinition(definition) {
+			t.Errorf("%q: invalid definition %+v", test.line, definition)
+		}
+	}
+
+	// the same line gives the same id, so the import can be repeated
+	first, _ := parseCrontab("a", []byte("0 2 * * * /usr/bin/backup"), false)
+	second, _ := parseCrontab("b", []byte("0 2 * * * /usr/bin/backup\n0 3 * * * /usr/bin/backup"), false)
+	if first[0].IdProcess != second[0].IdProcess || second[0].IdProcess == second[1].IdProcess || !strings.HasPrefix(first[0].IdProcess, "cron-backup-") {
+		t.Errorf("unexpected ids %s, %s and %s", first[0].IdProcess, second[0].IdProcess, second[1].IdProcess)
+	}
+}
+
+func TestParseSystemdTimer(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		window   legacyWindow
+		issue    string
+	}{
+		{name: "daily", calendar: "OnCalendar=daily", window: legacyWindow{"00:00:00", "00:00:59", ""}},
+		{name: "weekdays", calendar: "OnCalendar=Mon..Fri *-*-* 02:30", window: legacyWindow{"02:30:00", "02:30:59", "saturday,sunday"}},
+		{name: "weekend", calendar: "OnCalendar=Sat,Sun 10:00:15", window: legacyWindow{"10:00:15", "10:00:59", "monday,tuesday,wednesday,thursday,friday"}},
+		{name: "range", calendar: "OnCalendar=mon-wed 08..18:00", window: legacyWindow{"08:00:00", "18:00:59", "thursday,friday,saturday,sunday"}},
+		{name: "joined", calendar: "OnCalendar=Mon 06:00\nOnCalendar=Tue 20:00", window: legacyWindow{"06:00:00", "20:00:59", "wednesday,thursday,friday,saturday,sunday"}},
+		{name: "repeated", calendar: "OnCalendar=*:0/15", window: legacyWindow{"00:00:00", "23:45:59", ""}},
+		{name: "minutely", calendar: "OnCalendar=minutely", window: legacyWindow{}},
+		{name: "monthly", calendar: "OnCalendar=monthly", issue: "monthly"},
+		{name: "date", calendar: "OnCalendar=*-*-01 00:00", issue: "date"},
+		{name: "monotonic", calendar: "OnBootSec=15min", issue: "monotonic"},
+		{name: "empty", calendar: "Persistent=true", issue: "hasn't an OnCalendar"},
+	}
+
+	for _, test := range tests {
+		data := "[Unit]\nDescription=Timer " + test.name + "\n\n[Timer]\n" + test.calendar + "\n\n[Install]\nWantedBy=timers.target\n"
+		definitions, issues := parseSystemdTimer("/etc/systemd/system/"+test.name+".timer", []byte(data))
+
+		if test.issue != "" {
+			if len(definitions) != 0 || len(issues) == 0 || !strings.Contains(issues[0].Reason, test.issue) {
+				t.Errorf("%s: expected the issue %q, got %+v and %d definitions", test.name, test.issue, issues, len(definitions))
+			}
+			continue
+		}
+
+		if len(definitions) != 1 || len(issues) != 0 {
+			t.Errorf("%s: expected a definition, got %d definitions and issues %+v", test.name, len(definitions), issues)
+			continue
+		}
+
+		definition := definitions[0]
+		if w := definitionWindow(definition); w != test.window {
+			t.Errorf("%s: expected the window %+v, got %+v", test.name, test.window, w)
+		}
+		if definition.IdProcess != "systemd-"+test.name || definition.Name != "Timer "+test.name || definition.Type != ProcessTypeSystemd {
+			data, _ := json.Marshal(definition)
+			t.Errorf("%s: unexpected definition %s", test.name, data)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// exit codes, for scripting
+const (
+	ExitOK              = 0
+	ExitError           = 1
+	ExitUsage           = 2
+	ExitNotFound        = 3
+	ExitConflict        = 4
+	ExitInvalid         = 5
+	ExitVersionMismatch = 6
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(cli *cli, args []string) error
+}
+
+var commands = map[string]*command{
+	"list":    {"list [--filter key=value]...", "list the processes", listCommand},
+	"get":     {"get <id>", "get a process", getCommand},
+	"create":  {"create (-f file | --id id --type type --name name [fields])", "create a process", createCommand},
+	"update":  {"update <id> (-f file | [fields]) [--version version]", "update a process, only the given fields when without file", updateCommand},
+	"delete":  {"delete <id> [--version version]", "delete a process", deleteCommand},
+	"start":   {"start <id>", "set a process as running", startCommand},
+	"stop":    {"stop <id>", "set a process as stopped", stopCommand},
+	"check":   {"check <id> [--status status]", "check if the status of a process can be changed", checkCommand},
+	"tail":    {"tail [--interval duration] [--filter key=value]...", "follow the changes of the processes", tailCommand},
+	"export":  {"export [-f file] [--filter key=value]...", "export the processes as json or yaml", exportCommand},
+	"import":  {"import -f file [--mode atomic|best_effort]", "create or update the processes of a json or yaml file", importCommand},
+	"plan":    {"plan -f file [--prune]", "show the changes needed to match the process definitions of a json or yaml file", planCommand},
+	"apply":   {"apply -f file [--prune]", "create, update and, with prune, delete the processes to match the definitions", applyCommand},
+	"migrate": {"migrate [--system] [--dry-run] file...", "create or update the processes of crontab files and systemd .timer units", migrateCommand},
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command, returning the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return ExitOK
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		usage(stderr)
+		return ExitUsage
+	}
+
+	cli := &cli{ctx: ctx, stdin: stdin, stdout: stdout, stderr: stderr}
+	if err := command.run(cli, args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return exitCode(err)
+	}
+
+	return ExitOK
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "monitorctl is the command line tool of the monitor")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  monitorctl <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-75s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags of every command:")
+	fmt.Fprintln(w, "  --url url           url of the monitor, or the env MONITOR_URL")
+	fmt.Fprintln(w, "  --token token       token sent as bearer authorization, or the env MONITOR_TOKEN")
+	fmt.Fprintln(w, "  --config file       json config file with the url, token and output, or the env MONITORCTL_CONFIG")
+	fmt.Fprintln(w, "                      (by default ~/.monitorctl.json)")
+	fmt.Fprintln(w, "  -o, --output format table, json or yaml")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintln(w, "  0 success, 1 error, 2 invalid usage, 3 not found,")
+	fmt.Fprintln(w, "  4 conflict (like already running or outside of the window), 5 invalid process, 6 version mismatch")
+}
+
+// usageError is an invalid use of a command
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// filterFlag is a repeatable key=value flag
+type filterFlag map[string][]string
+
+func (f filterFlag) String() string {
+	return ""
+}
+
+func (f filterFlag) Set(value string) error {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 || split[0] == "" {
+		return fmt.Errorf("invalid filter %q, expected key=value", value)
+	}
+
+	f[split[0]] = append(f[split[0]], split[1])
+	return nil
+}
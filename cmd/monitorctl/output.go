@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joaosoft/monitor/client"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// printer writes the results on the output format
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter(format string, out io.Writer) (*printer, error) {
+	switch format {
+	case OutputTable, OutputJSON, OutputYAML:
+		return &printer{format: format, out: out}, nil
+	default:
+		return nil, newUsageError("invalid output %q, expected table, json or yaml", format)
+	}
+}
+
+// print writes the value as json or yaml, or as a table with the rows
+func (printer *printer) print(value interface{}, header []string, rows [][]string) error {
+	switch printer.format {
+	case OutputJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(printer.out, string(data))
+		return err
+
+	case OutputYAML:
+		data, err := toYAML(value)
+		if err != nil {
+			return err
+		}
+		_, err = printer.out.Write(data)
+		return err
+
+	default:
+		w := tabwriter.NewWriter(printer.out, 0, 0, 3, ' ', 0)
+		if header != nil {
+			fmt.Fprintln(w, strings.Join(header, "\t"))
+		}
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+var processesHeader = []string{"ID", "TYPE", "NAME", "MONITOR", "STATUS", "VERSION", "HEARTBEAT", "UPDATED"}
+
+func (printer *printer) processes(processes client.ListProcess) error {
+	rows := make([][]string, 0, len(processes))
+	for _, process := range processes {
+		rows = append(rows, []string{
+			process.IdProcess,
+			process.Type,
+			process.Name,
+			process.Monitor,
+			status(process),
+			fmt.Sprintf("%d", process.Version),
+			timestamp(process.HeartbeatAt),
+			timestamp(&process.UpdatedAt),
+		})
+	}
+
+	return printer.print(processes, processesHeader, rows)
+}
+
+func (printer *printer) process(process *client.Process) error {
+	rows := [][]string{
+		{"ID:", process.IdProcess},
+		{"Type:", process.Type},
+		{"Name:", process.Name},
+		{"Description:", process.Description},
+		{"Monitor:", process.Monitor},
+		{"Status:", status(process)},
+		{"Message:", process.Message},
+		{"Window:", window(process)},
+		{"Can start:", canStart(process)},
+		{"Next window:", nextWindow(process)},
+		{"Version:", fmt.Sprintf("%d", process.Version)},
+		{"Heartbeat:", timestamp(process.HeartbeatAt)},
+		{"Created:", timestamp(&process.CreatedAt)},
+		{"Updated:", timestamp(&process.UpdatedAt)},
+	}
+
+	return printer.print(process, nil, rows)
+}
+
+// event writes an event of tail, as a line or a json line or yaml document, so it can be streamed
+func (printer *printer) event(e *event) error {
+	switch printer.format {
+	case OutputJSON:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(printer.out, string(data))
+		return err
+
+	case OutputYAML:
+		data, err := toYAML(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(printer.out, "---\n%s", data)
+		return err
+
+	default:
+		status, version := string(e.Status), "-"
+		if status == "" {
+			status = "-"
+		}
+		if e.Version > 0 {
+			version = fmt.Sprintf("v%d", e.Version)
+		}
+		_, err := fmt.Fprintf(printer.out, "%s  %-8s  %-30s  %-8s  %-5s  %s\n",
+			e.Time.Local().Format(time.RFC3339), e.Event, e.IdProcess, status, version, e.Message)
+		return err
+	}
+}
+
+var changeSymbols = map[client.ChangeOperation]string{client.ChangeCreate: "+", client.ChangeUpdate: "~", client.ChangeDelete: "-"}
+
+// changes writes the changes of plan and apply, on the table output like a diff with the changed fields
+func (printer *printer) changes(response *client.ApplyProcessesResponse) error {
+	if printer.format != OutputTable {
+		return printer.print(response, nil, nil)
+	}
+
+	for _, change := range response.Changes {
+		fmt.Fprintf(printer.out, "%s %s %s\n", changeSymbols[change.Op], change.Op, change.IdProcess)
+		for _, field := range change.Fields {
+			from, _ := json.Marshal(field.From)
+			to, _ := json.Marshal(field.To)
+			fmt.Fprintf(printer.out, "    %s: %s -> %s\n", field.Field, from, to)
+		}
+	}
+
+	return nil
+}
+
+// issues writes the lines of a migration that couldn't be translated, on the table output
+func (printer *printer) issues(issues []*legacyIssue) {
+	if printer.format != OutputTable {
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(printer.out, "! skipped %s:%d %s\n", issue.Source, issue.Line, issue.Reason)
+		fmt.Fprintf(printer.out, "    %s\n", issue.Text)
+	}
+}
+
+// message writes a message, that is only written on the table output
+func (printer *printer) message(format string, args ...interface{}) {
+	if printer.format == OutputTable {
+		fmt.Fprintf(printer.out, format+"\n", args...)
+	}
+}
+
+// toYAML converts the value to yaml with the json names and order of the fields
+func toYAML(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+	blockStyle(node)
+
+	return yaml.Marshal(node)
+}
+
+// blockStyle clears the json styles of the nodes, the strings with colons are kept quoted
+// because the yaml 1.1 parsers read times, like 08:00:00, as numbers
+func blockStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, ":") {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func status(process *client.Process) string {
+	if process.Status == nil {
+		return "-"
+	}
+	return string(*process.Status)
+}
+
+func timestamp(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func window(process *client.Process) string {
+	var parts []string
+	if process.DateFrom != nil || process.DateTo != nil {
+		parts = append(parts, fmt.Sprintf("dates %s to %s", orAny(process.DateFrom), orAny(process.DateTo)))
+	}
+	if process.TimeFrom != nil || process.TimeTo != nil {
+		parts = append(parts, fmt.Sprintf("times %s to %s", orAny(process.TimeFrom), orAny(process.TimeTo)))
+	}
+	if process.DaysOff != nil && len(*process.DaysOff) > 0 {
+		days := make([]string, 0, len(*process.DaysOff))
+		for _, day := range *process.DaysOff {
+			days = append(days, string(day))
+		}
+		parts = append(parts, "off on "+strings.Join(days, ", "))
+	}
+
+	if len(parts) == 0 {
+		return "always"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func orAny[T ~string](value *T) string {
+	if value == nil {
+		return "any"
+	}
+	return string(*value)
+}
+
+func canStart(process *client.Process) string {
+	switch {
+	case process.CanExecuteNow == nil:
+		return "-"
+	case *process.CanExecuteNow:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+func nextWindow(process *client.Process) string {
+	switch {
+	case process.NextWindowStart == nil:
+		return "none in the next year"
+	case process.NextWindowEnd == nil:
+		return fmt.Sprintf("%s onwards", timestamp(process.NextWindowStart))
+	default:
+		return fmt.Sprintf("%s to %s", timestamp(process.NextWindowStart), timestamp(process.NextWindowEnd))
+	}
+}
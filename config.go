@@ -14,12 +14,37 @@ type AppConfig struct {
 
 // MonitorConfig ...
 type MonitorConfig struct {
-	Host string           `json:"host"`
-	Db   manager.DBConfig `json:"db"`
-	Migration         *migration.MigrationConfig `json:"migration"`
-	Log  struct {
+	Host      string                     `json:"host"`
+	Db        manager.DBConfig           `json:"db"`
+	Migration *migration.MigrationConfig `json:"migration"`
+	Log       struct {
 		Level string `json:"level"`
 	} `json:"log"`
+	RequireIfMatch bool `json:"require_if_match"`
+	Purge          struct {
+		Retention string `json:"retention"`
+		Interval  string `json:"interval"`
+	} `json:"purge"`
+	Idempotency struct {
+		TTL string `json:"ttl"`
+	} `json:"idempotency"`
+	Heartbeat struct {
+		Lease string `json:"lease"`
+	} `json:"heartbeat"`
+	Shutdown struct {
+		Drain string `json:"drain"`
+	} `json:"shutdown"`
+	StatusPage struct {
+		Title  string             `json:"title"`
+		Uptime string             `json:"uptime"`
+		Groups []*StatusPageGroup `json:"groups"`
+	} `json:"status_page"`
+}
+
+// StatusPageGroup is a group of the status page, with the processes selected by the filters of the processes list
+type StatusPageGroup struct {
+	Name   string              `json:"name"`
+	Filter map[string][]string `json:"filter"`
 }
 
 // NewConfig ...
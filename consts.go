@@ -1,8 +1,77 @@
 package monitor
 
+import "time"
+
 const (
 	DefaultURL = "http://localhost:8001"
 
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+	DriverMySQL    = "mysql"
+
+	HeaderETag               = "ETag"
+	HeaderIfMatch            = "If-Match"
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	HeaderCacheControl       = "Cache-Control"
+	HeaderAuthorization      = "Authorization"
+
+	MaxIdempotencyKeyLength = 255
+
+	dateLayout         = "02-01-2006"
+	sortableDateLayout = "2006-01-02"
+	timeLayout         = "15:04:05"
+
+	DefaultPurgeRetention = 30 * 24 * time.Hour
+	DefaultPurgeInterval  = time.Hour
+	DefaultIdempotencyTTL = 24 * time.Hour
+	DefaultHeartbeatLease = 90 * time.Second
+	DefaultShutdownDrain  = 0
+
+	DefaultStatusPageTitle  = "Processes"
+	DefaultStatusPageGroup  = "All processes"
+	DefaultStatusPageUptime = 30 * 24 * time.Hour
+
+	MessageLeaseExpired = "the lease expired, no heartbeat was received in time"
+
+	healthTimeout = 2 * time.Second
+
 	StatusStopped Status = "stopped"
 	StatusRunning Status = "running"
+	StatusFailed  Status = "failed"
+
+	BatchModeAtomic     BatchMode = "atomic"
+	BatchModeBestEffort BatchMode = "best_effort"
+
+	BatchOperationCreate BatchOperationType = "create"
+	BatchOperationUpdate BatchOperationType = "update"
+	BatchOperationStatus BatchOperationType = "status"
+
+	MaxBatchOperations = 1000
+
+	MaxSimulationRange = 366 * 24 * time.Hour
+
+	IncludeSchedule = "schedule"
+
+	BadgeStatus      = "status"
+	BadgeLastSuccess = "last_success"
+
+	ChangeCreate ChangeOperation = "create"
+	ChangeUpdate ChangeOperation = "update"
+	ChangeDelete ChangeOperation = "delete"
+
+	FileFormatJSON FileFormat = "json"
+	FileFormatYAML FileFormat = "yaml"
+	FileFormatCSV  FileFormat = "csv"
+
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+
+	HistoryInsert HistoryOperation = "I"
+	HistoryUpdate HistoryOperation = "U"
+	HistoryDelete HistoryOperation = "D"
+
+	HealthUp   HealthStatus = "up"
+	HealthDown HealthStatus = "down"
 )
@@ -1,52 +1,218 @@
 package monitor
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
 	"github.com/joaosoft/errors"
 	"github.com/joaosoft/logger"
-	"github.com/joaosoft/validator"
 	"github.com/joaosoft/web"
 )
 
 type Controller struct {
-	interactor *Interactor
-	logger     logger.ILogger
+	interactor     *Interactor
+	idempotency    *Idempotency
+	metrics        *metrics
+	health         *Health
+	statusPage     *StatusPage
+	logger         logger.ILogger
+	requireIfMatch bool
 }
 
 func (monitor *Monitor) NewController(interactor *Interactor) *Controller {
-	return &Controller{
-		interactor: interactor,
-		logger:     monitor.logger,
+	controller := &Controller{
+		interactor:  interactor,
+		idempotency: monitor.idempotency,
+		metrics:     monitor.metrics,
+		health:      monitor.health,
+		statusPage:  monitor.statusPage,
+		logger:      monitor.logger,
+	}
+
+	if monitor.config != nil {
+		controller.requireIfMatch = monitor.config.RequireIfMatch
 	}
+
+	return controller
 }
 
 func (controller *Controller) DoNothing(ctx *web.Context) error {
 	return nil
 }
 
+func (controller *Controller) OpenAPIHandler(ctx *web.Context) error {
+	return ctx.Response.JSON(web.StatusOK, OpenAPI())
+}
+
+func (controller *Controller) DocsHandler(ctx *web.Context) error {
+	return ctx.Response.HTML(web.StatusOK, docsPage)
+}
+
+func (controller *Controller) DashboardHandler(ctx *web.Context) error {
+	return ctx.Response.HTML(web.StatusOK, dashboardPage)
+}
+
+func (controller *Controller) StatusPageHandler(ctx *web.Context) error {
+	return ctx.Response.HTML(web.StatusOK, publicStatusPage)
+}
+
+// GetStatusPageHandler returns the public status of the processes of the status page
+func (controller *Controller) GetStatusPageHandler(ctx *web.Context) error {
+	if response, err := controller.interactor.GetStatusPage(controller.statusPage); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, response)
+	}
+}
+
+// BadgeHandler returns the badge of the process, as a svg that isn't cached so it shows the current status.
+// A process that isn't found is shown on the badge too, so it doesn't break where it's embedded
+func (controller *Controller) BadgeHandler(ctx *web.Context) error {
+	request := GetBadgeRequest{
+		IdProcess: strings.TrimSuffix(ctx.Request.GetUrlParam("id.svg"), ".svg"),
+		Show:      ctx.Request.GetParam("show"),
+		Label:     ctx.Request.GetParam("label"),
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	var label, value, color string
+	httpStatus := web.StatusOK
+
+	status, err := controller.interactor.GetProcessStatus(request.IdProcess, controller.statusPage.uptime)
+	switch {
+	case err == ErrorProcessNotFound:
+		label, value, color = request.Label, "not found", badgeColorNone
+		if label == "" {
+			label = request.IdProcess
+		}
+		httpStatus = web.StatusNotFound
+	case err != nil:
+		return writeProblem(ctx, err)
+	default:
+		label, value, color = processBadge(status, request.Show, request.Label, controller.interactor.clock.Now())
+	}
+
+	ctx.Response.SetHeader(HeaderCacheControl, []string{"no-cache, max-age=0"})
+	return ctx.Response.Bytes(httpStatus, ContentTypeSVG, badge(label, value, color))
+}
+
+// LiveHandler returns the liveness
+func (controller *Controller) LiveHandler(ctx *web.Context) error {
+	return ctx.Response.JSON(web.StatusOK, controller.health.Live())
+}
+
+// ReadyHandler returns the readiness, with the status service unavailable when it isn't ready
+func (controller *Controller) ReadyHandler(ctx *web.Context) error {
+	response := controller.health.Ready()
+	if response.Status != HealthUp {
+		return ctx.Response.JSON(web.StatusServiceUnavailable, response)
+	}
+	return ctx.Response.JSON(web.StatusOK, response)
+}
+
+// MetricsHandler writes the metrics in the prometheus text format
+func (controller *Controller) MetricsHandler(ctx *web.Context) error {
+	processes, err := controller.interactor.GetProcesses(nil)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	var buffer bytes.Buffer
+	if err := controller.metrics.write(&buffer, processes); err != nil {
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInternal, err))
+	}
+
+	return ctx.Response.Bytes(web.StatusOK, ContentTypeMetrics, buffer.Bytes())
+}
+
 func (controller *Controller) GetProcessHandler(ctx *web.Context) error {
 	request := GetProcessRequest{
 		IdProcess: ctx.Request.GetUrlParam("id"),
+		Include:   ctx.Request.GetParam("include"),
 	}
 
-	if errs := validator.Validate(request); len(errs) > 0 {
-		return ctx.Response.JSON(web.StatusBadRequest, errs)
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
 	}
 
 	if process, err := controller.interactor.GetProcess(request.IdProcess); err != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, ErrorResponse{Code: web.StatusInternalServerError, Message: err.Error()})
+		return writeProblem(ctx, err)
 	} else if process == nil {
-		return ctx.Response.NoContent(web.StatusNotFound)
+		return writeProblem(ctx, ErrorProcessNotFound)
 	} else {
+		if request.Include == IncludeSchedule {
+			controller.interactor.ScheduleProcesses(process)
+		}
+
+		ctx.Response.SetHeader(HeaderETag, []string{etag(process.Version)})
 		return ctx.Response.JSON(web.StatusOK, process)
 	}
 }
 
+func (controller *Controller) GetProcessHistoryHandler(ctx *web.Context) error {
+	request := GetProcessRequest{
+		IdProcess: ctx.Request.GetUrlParam("id"),
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	if history, err := controller.interactor.GetProcessHistory(request.IdProcess); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, history)
+	}
+}
+
+func (controller *Controller) GetProcessRunsHandler(ctx *web.Context) error {
+	request := GetProcessRequest{
+		IdProcess: ctx.Request.GetUrlParam("id"),
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	if runs, err := controller.interactor.GetProcessRuns(request.IdProcess); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, runs)
+	}
+}
+
 func (controller *Controller) GetProcessesHandler(ctx *web.Context) error {
-	if processes, err := controller.interactor.GetProcesses(ctx.Request.Params); err != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, ErrorResponse{Code: web.StatusInternalServerError, Message: err.Error()})
+	request := GetProcessesRequest{
+		Filter:  make(map[string][]string),
+		Include: ctx.Request.GetParam("include"),
+	}
+
+	for key, value := range ctx.Request.Params {
+		if key != "include" {
+			request.Filter[key] = value
+		}
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	if processes, err := controller.interactor.GetProcesses(request.Filter); err != nil {
+		return writeProblem(ctx, err)
 	} else if processes == nil {
-		return ctx.Response.NoContent(web.StatusNotFound)
+		return ctx.Response.JSON(web.StatusOK, ListProcess{})
 	} else {
+		if request.Include == IncludeSchedule {
+			controller.interactor.ScheduleProcesses(processes...)
+		}
+
 		return ctx.Response.JSON(web.StatusOK, processes)
 	}
 }
@@ -54,16 +220,15 @@ func (controller *Controller) GetProcessesHandler(ctx *web.Context) error {
 func (controller *Controller) CreateProcessHandler(ctx *web.Context) error {
 	request := CreateProcessRequest{}
 	if err := ctx.Request.Bind(&request.Body); err != nil {
-		err = controller.logger.WithFields(map[string]interface{}{"error": err}).
+		controller.logger.WithFields(map[string]interface{}{"error": err}).
 			Error("error getting body").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
 	}
 
-	if errs := validator.Validate(request.Body); len(errs) > 0 {
-		err := errors.New(errors.LevelError, 0, errs)
+	if err := validate(request.Body); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error when validating body request").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	}
 
 	newProcess := Process{
@@ -76,13 +241,13 @@ func (controller *Controller) CreateProcessHandler(ctx *web.Context) error {
 		TimeFrom:    request.Body.TimeFrom,
 		TimeTo:      request.Body.TimeTo,
 		DaysOff:     request.Body.DaysOff,
+		Monitor:     request.Body.Monitor,
 		Status:      request.Body.Status,
 	}
 	if err := controller.interactor.CreateProcess(&newProcess); err != nil {
-		err := errors.New(errors.LevelError, 0, err)
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error creating process %s", request.Body.IdProcess).ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	} else {
 		return ctx.Response.NoContent(web.StatusCreated)
 	}
@@ -93,17 +258,20 @@ func (controller *Controller) UpdateProcessHandler(ctx *web.Context) error {
 		IdProcess: ctx.Request.GetUrlParam("id"),
 	}
 	if err := ctx.Request.Bind(&request.Body); err != nil {
-		err := errors.New(errors.LevelError, 0, err)
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error getting body").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
 	}
 
-	if errs := validator.Validate(request); len(errs) > 0 {
-		err := errors.New(errors.LevelError, 0, errs)
+	if err := validate(request); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error when validating body request").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
+	}
+
+	version, err := controller.ifMatchVersion(ctx, request.IdProcess)
+	if err != nil {
+		return writeProblem(ctx, err)
 	}
 
 	updProcess := Process{
@@ -116,30 +284,436 @@ func (controller *Controller) UpdateProcessHandler(ctx *web.Context) error {
 		TimeFrom:    request.Body.TimeFrom,
 		TimeTo:      request.Body.TimeTo,
 		DaysOff:     request.Body.DaysOff,
+		Monitor:     request.Body.Monitor,
 		Status:      request.Body.Status,
+		Version:     version,
+	}
+	if err := controller.interactor.UpdateProcess(&updProcess); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		ctx.Response.SetHeader(HeaderETag, []string{etag(updProcess.Version)})
+		return ctx.Response.NoContent(web.StatusOK)
+	}
+}
+
+func (controller *Controller) PatchProcessHandler(ctx *web.Context) error {
+	request := PatchProcessRequest{
+		IdProcess: ctx.Request.GetUrlParam("id"),
+		Patch:     ctx.Request.Body,
+	}
+	if contentType := ctx.Request.GetContentType(); contentType != nil {
+		request.ContentType = patchMediaType(string(*contentType))
+	}
+
+	if err := validate(request); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	version, err := controller.ifMatchVersion(ctx, request.IdProcess)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	process, err := controller.interactor.GetProcess(request.IdProcess)
+	if err != nil {
+		return writeProblem(ctx, err)
+	} else if process == nil {
+		return writeProblem(ctx, ErrorProcessNotFound)
+	} else if version > 0 && version != process.Version {
+		return writeProblem(ctx, ErrorVersionMismatch)
+	}
+
+	// the patch is applied to the current process, so the omitted fields are kept
+	update := UpdateProcessRequest{IdProcess: request.IdProcess}
+	update.Body.Type = process.Type
+	update.Body.Name = process.Name
+	update.Body.Description = process.Description
+	update.Body.DateFrom = process.DateFrom
+	update.Body.DateTo = process.DateTo
+	update.Body.TimeFrom = process.TimeFrom
+	update.Body.TimeTo = process.TimeTo
+	update.Body.DaysOff = process.DaysOff
+	update.Body.Monitor = process.Monitor
+	update.Body.Status = process.Status
+
+	document, err := json.Marshal(update.Body)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	patched, err := ApplyPatch(request.ContentType, document, request.Patch)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	update.Body.Type, update.Body.Name, update.Body.Description, update.Body.Monitor = "", "", "", ""
+	update.Body.DateFrom, update.Body.DateTo, update.Body.TimeFrom, update.Body.TimeTo = nil, nil, nil, nil
+	update.Body.DaysOff, update.Body.Status = nil, nil
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update.Body); err != nil {
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidPatch, err))
+	}
+
+	if err := validate(update); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating the patched process").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	updProcess := Process{
+		IdProcess:   update.IdProcess,
+		Type:        update.Body.Type,
+		Name:        update.Body.Name,
+		Description: update.Body.Description,
+		DateFrom:    update.Body.DateFrom,
+		DateTo:      update.Body.DateTo,
+		TimeFrom:    update.Body.TimeFrom,
+		TimeTo:      update.Body.TimeTo,
+		DaysOff:     update.Body.DaysOff,
+		Monitor:     update.Body.Monitor,
+		Status:      update.Body.Status,
+		Version:     process.Version,
 	}
 	if err := controller.interactor.UpdateProcess(&updProcess); err != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, ErrorResponse{Code: web.StatusInternalServerError, Message: err.Error()})
+		return writeProblem(ctx, err)
 	} else {
+		ctx.Response.SetHeader(HeaderETag, []string{etag(updProcess.Version)})
 		return ctx.Response.NoContent(web.StatusOK)
 	}
 }
 
+func (controller *Controller) BatchProcessesHandler(ctx *web.Context) error {
+	// the router takes :batch as a parameter, so the route also matches other paths like /api/v1/processesfoo
+	if !strings.HasSuffix(ctx.Request.Address.Url, "/processes:batch") {
+		return writeProblem(ctx, ErrorRouteNotFound)
+	}
+
+	request := BatchProcessesRequest{}
+	if err := ctx.Request.Bind(&request.Body); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err}).
+			Error("error getting body").ToError()
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
+	}
+
+	if request.Body.Mode == "" {
+		request.Body.Mode = BatchModeAtomic
+	}
+
+	if err := validate(request.Body); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if len(request.Body.Operations) > MaxBatchOperations {
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeBatchTooLarge, "a batch can't have more than %d operations", MaxBatchOperations))
+	}
+
+	operations := make([]*BatchOperation, 0, len(request.Body.Operations))
+	for _, item := range request.Body.Operations {
+		operations = append(operations, batchOperation(item))
+	}
+
+	controller.interactor.Batch(request.Body.Mode, operations)
+
+	response := BatchProcessesResponse{
+		Mode:    request.Body.Mode,
+		Results: make([]*BatchOperationResult, 0, len(operations)),
+	}
+
+	for i, operation := range operations {
+		result := &BatchOperationResult{
+			Index:     i,
+			Op:        operation.Op,
+			IdProcess: operation.Process.IdProcess,
+		}
+
+		switch {
+		case operation.Error != nil:
+			result.Error = NewProblem(operation.Error)
+			result.Status = result.Error.Status
+			response.Failed++
+		case operation.Op == BatchOperationCreate:
+			result.Status = web.StatusCreated
+			response.Succeeded++
+		default:
+			result.Status = web.StatusOK
+			result.Version = operation.Process.Version
+			response.Succeeded++
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	if response.Failed > 0 {
+		return ctx.Response.JSON(web.StatusMultiStatus, response)
+	}
+
+	return ctx.Response.JSON(web.StatusOK, response)
+}
+
+func (controller *Controller) ApplyProcessesHandler(ctx *web.Context) error {
+	request := ApplyProcessesRequest{}
+	if err := ctx.Request.Bind(&request.Body); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err}).
+			Error("error getting body").ToError()
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
+	}
+
+	if len(request.Body.Processes) > MaxBatchOperations {
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeBatchTooLarge, "can't apply more than %d processes", MaxBatchOperations))
+	}
+
+	if err := validateDefinitions(request.Body.Processes); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if response, err := controller.interactor.ApplyProcesses(request.Body.Processes, request.Body.Prune, request.Body.DryRun); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, response)
+	}
+}
+
+func (controller *Controller) SimulateProcessHandler(ctx *web.Context) error {
+	request := SimulateProcessRequest{}
+	if err := ctx.Request.Bind(&request.Body); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err}).
+			Error("error getting body").ToError()
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
+	}
+
+	if err := validateSimulation(&request); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if response, err := controller.interactor.SimulateProcess(request.Body.IdProcess, request.Body.Process, request.Body.From, request.Body.To); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, response)
+	}
+}
+
+func (controller *Controller) ExportProcessesHandler(ctx *web.Context) error {
+	request := ExportProcessesRequest{
+		Filter: make(map[string][]string),
+		Format: FileFormat(ctx.Request.GetParam("format")),
+	}
+
+	for key, value := range ctx.Request.Params {
+		if key != "format" {
+			request.Filter[key] = value
+		}
+	}
+
+	if request.Format == "" {
+		request.Format = FileFormatJSON
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	definitions, err := controller.interactor.ExportProcesses(request.Filter)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	data, err := EncodeDefinitions(request.Format, definitions)
+	if err != nil {
+		return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInternal, err))
+	}
+
+	ctx.Response.SetHeader("Content-Disposition", []string{fmt.Sprintf(`attachment; filename="processes.%s"`, request.Format)})
+	return ctx.Response.Bytes(web.StatusOK, web.ContentType(request.Format.ContentType()), data)
+}
+
+func (controller *Controller) ImportProcessesHandler(ctx *web.Context) error {
+	request := ImportProcessesRequest{
+		Format:     FileFormat(ctx.Request.GetParam("format")),
+		OnConflict: ConflictPolicy(ctx.Request.GetParam("on_conflict")),
+		DryRun:     ctx.Request.GetParam("dry_run") == "true",
+	}
+
+	format, data := importFile(ctx)
+	if request.Format == "" {
+		request.Format = format
+	}
+	if request.OnConflict == "" {
+		request.OnConflict = ConflictFail
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	var err error
+	if request.Processes, err = DecodeDefinitions(request.Format, data); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error getting body").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if err := validateDefinitions(request.Processes); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if response, err := controller.interactor.ImportProcesses(request.Processes, request.OnConflict, request.DryRun); err != nil {
+		return writeProblem(ctx, err)
+	} else {
+		return ctx.Response.JSON(web.StatusOK, response)
+	}
+}
+
+// importFile returns the uploaded file and its format, by the extension or the content type.
+// The server drops the new lines of the body, so the yaml and csv files have to be uploaded as multipart form data
+func importFile(ctx *web.Context) (FileFormat, []byte) {
+	attachment, ok := ctx.Request.Attachments["file"]
+	if !ok {
+		for _, attachment = range ctx.Request.Attachments {
+			ok = true
+			break
+		}
+	}
+
+	if ok {
+		if extension := path.Ext(attachment.FileName); extension != "" {
+			return fileFormat(extension), attachment.Body
+		}
+		return fileFormat(string(attachment.ContentType)), attachment.Body
+	}
+
+	if contentType := ctx.Request.GetContentType(); contentType != nil {
+		return fileFormat(string(*contentType)), ctx.Request.Body
+	}
+	return FileFormatJSON, ctx.Request.Body
+}
+
+// batchOperation validates an operation of a batch, setting the error when it's invalid, like a null operation
+func batchOperation(item *BatchOperationRequest) *BatchOperation {
+	if item == nil {
+		return &BatchOperation{
+			Process: &Process{},
+			Error:   &ValidationError{Fields: []*FieldError{{Field: "operation", Code: validationRequired, Message: "is required"}}},
+		}
+	}
+
+	operation := &BatchOperation{
+		Op:      item.Op,
+		Process: &Process{IdProcess: item.IdProcess},
+	}
+
+	var err error
+	switch item.Op {
+	case BatchOperationCreate:
+		request := CreateProcessRequest{}
+		if err := json.Unmarshal(item.Body, &request.Body); err != nil {
+			operation.Error = errors.New(errors.LevelError, ErrorCodeInvalidBody, err)
+			return operation
+		}
+
+		if request.Body.IdProcess == "" {
+			request.Body.IdProcess = item.IdProcess
+		}
+
+		err = validate(request.Body)
+		operation.Process = &Process{
+			IdProcess:   request.Body.IdProcess,
+			Type:        request.Body.Type,
+			Name:        request.Body.Name,
+			Description: request.Body.Description,
+			DateFrom:    request.Body.DateFrom,
+			DateTo:      request.Body.DateTo,
+			TimeFrom:    request.Body.TimeFrom,
+			TimeTo:      request.Body.TimeTo,
+			DaysOff:     request.Body.DaysOff,
+			Monitor:     request.Body.Monitor,
+			Status:      request.Body.Status,
+		}
+
+	case BatchOperationUpdate:
+		request := UpdateProcessRequest{IdProcess: item.IdProcess}
+		if err := json.Unmarshal(item.Body, &request.Body); err != nil {
+			operation.Error = errors.New(errors.LevelError, ErrorCodeInvalidBody, err)
+			return operation
+		}
+
+		err = validate(request)
+		operation.Process = &Process{
+			IdProcess:   request.IdProcess,
+			Type:        request.Body.Type,
+			Name:        request.Body.Name,
+			Description: request.Body.Description,
+			DateFrom:    request.Body.DateFrom,
+			DateTo:      request.Body.DateTo,
+			TimeFrom:    request.Body.TimeFrom,
+			TimeTo:      request.Body.TimeTo,
+			DaysOff:     request.Body.DaysOff,
+			Monitor:     request.Body.Monitor,
+			Status:      request.Body.Status,
+			Version:     item.Version,
+		}
+
+	case BatchOperationStatus:
+		request := UpdateProcessStatusRequest{
+			IdProcess: item.IdProcess,
+			Status:    item.Status,
+		}
+
+		err = validate(request)
+		operation.Process.Status = &request.Status
+		operation.Process.Message = item.Message
+
+	default:
+		err = validate(item)
+	}
+
+	if err != nil {
+		operation.Error = err
+	}
+
+	return operation
+}
+
 func (controller *Controller) UpdateProcessStatusHandler(ctx *web.Context) error {
 	request := UpdateProcessStatusRequest{
 		IdProcess: ctx.Request.GetUrlParam("id"),
 		Status:    Status(ctx.Request.GetUrlParam("status")),
 	}
 
-	if errs := validator.Validate(request); len(errs) > 0 {
-		err := errors.New(errors.LevelError, 0, errs)
+	// the body, with the message of the status, is optional
+	if len(ctx.Request.Body) > 0 {
+		if err := ctx.Request.Bind(&request.Body); err != nil {
+			controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+				Error("error getting body").ToError()
+			return writeProblem(ctx, errors.New(errors.LevelError, ErrorCodeInvalidBody, err))
+		}
+	}
+
+	if err := validate(request); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error when validating query request").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	}
 
-	if errs := controller.interactor.UpdateProcessStatus(request.IdProcess, request.Status); errs != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, errs)
+	// the status changes don't require If-Match, so the workers can start the processes without getting them first
+	version, err := controller.ifMatchVersion(ctx, request.IdProcess)
+	if err != nil && err != ErrorVersionRequired {
+		return writeProblem(ctx, err)
+	}
+
+	if errs := controller.interactor.UpdateProcessStatus(request.IdProcess, request.Status, request.Body.Message, version); errs != nil {
+		return writeProblem(ctx, errorListError(errs))
 	} else {
 		return ctx.Response.NoContent(web.StatusOK)
 	}
@@ -151,15 +725,30 @@ func (controller *Controller) UpdateProcessStatusCheckHandler(ctx *web.Context)
 		Status:    Status(ctx.Request.GetUrlParam("status")),
 	}
 
-	if errs := validator.Validate(request); len(errs) > 0 {
-		err := errors.New(errors.LevelError, 0, errs)
+	if err := validate(request); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error when validating query request").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	}
 
 	if errs := controller.interactor.UpdateProcessStatusCheck(request.IdProcess, request.Status); errs != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, errs)
+		return writeProblem(ctx, errorListError(errs))
+	} else {
+		return ctx.Response.NoContent(web.StatusOK)
+	}
+}
+
+func (controller *Controller) HeartbeatProcessHandler(ctx *web.Context) error {
+	request := HeartbeatProcessRequest{
+		IdProcess: ctx.Request.GetUrlParam("id"),
+	}
+
+	if err := validate(request); err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	if err := controller.interactor.HeartbeatProcess(request.IdProcess); err != nil {
+		return writeProblem(ctx, err)
 	} else {
 		return ctx.Response.NoContent(web.StatusOK)
 	}
@@ -170,27 +759,128 @@ func (controller *Controller) DeleteProcessHandler(ctx *web.Context) error {
 		IdProcess: ctx.Request.GetUrlParam("id"),
 	}
 
-	if errs := validator.Validate(request); len(errs) > 0 {
-		err := errors.New(errors.LevelError, 0, errs)
+	if err := validate(request); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Error("error when validating body request").ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	}
 
-	if err := controller.interactor.DeleteProcess(request.IdProcess); err != nil {
-		err := errors.New(errors.LevelError, 0, err)
+	version, err := controller.ifMatchVersion(ctx, request.IdProcess)
+	if err != nil {
+		return writeProblem(ctx, err)
+	}
+
+	if err := controller.interactor.DeleteProcess(request.IdProcess, version); err != nil {
 		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error deleting process by id %s", request.IdProcess).ToError()
-		return ctx.Response.JSON(web.StatusBadRequest, ErrorResponse{Code: web.StatusBadRequest, Message: err.Error()})
+		return writeProblem(ctx, err)
 	} else {
 		return ctx.Response.NoContent(web.StatusOK)
 	}
 }
 
 func (controller *Controller) DeleteProcessesHandler(ctx *web.Context) error {
-	if err := controller.interactor.DeleteProcesses(); err != nil {
-		return ctx.Response.JSON(web.StatusInternalServerError, ErrorResponse{Code: web.StatusInternalServerError, Message: err.Error()})
+	request := DeleteProcessesRequest{
+		Filter:       make(map[string][]string),
+		Confirmation: ctx.Request.GetParam("confirmation"),
+	}
+
+	for key, value := range ctx.Request.Params {
+		if key != "confirmation" {
+			request.Filter[key] = value
+		}
+	}
+
+	response, err := controller.interactor.DeleteProcesses(request.Filter, request.Confirmation)
+	switch err {
+	case nil:
+		return ctx.Response.JSON(web.StatusOK, response)
+	case ErrorConfirmationRequired:
+		return writeConfirmationProblem(ctx, response)
+	default:
+		return writeProblem(ctx, err)
+	}
+}
+
+func (controller *Controller) RestoreProcessHandler(ctx *web.Context) error {
+	request := RestoreProcessRequest{
+		IdProcess: ctx.Request.GetUrlParam("id"),
+	}
+
+	if err := validate(request); err != nil {
+		controller.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Error("error when validating body request").ToError()
+		return writeProblem(ctx, err)
+	}
+
+	if restored, err := controller.interactor.RestoreProcess(request.IdProcess); err != nil {
+		return writeProblem(ctx, err)
+	} else if !restored {
+		return writeProblem(ctx, ErrorProcessNotFound)
 	} else {
 		return ctx.Response.NoContent(web.StatusOK)
 	}
 }
+
+// ifMatchVersion returns the process version expected by the If-Match header, zero when any version is accepted.
+// Of a list of entity tags, the current version of the process is expected when it's on the list
+func (controller *Controller) ifMatchVersion(ctx *web.Context, idProcess string) (int64, error) {
+	value := strings.TrimSpace(ctx.Request.GetHeader(HeaderIfMatch))
+	if value == "" {
+		if controller.requireIfMatch {
+			return 0, ErrorVersionRequired
+		}
+		return 0, nil
+	}
+
+	versions, anyVersion := parseIfMatch(value)
+	switch {
+	case anyVersion:
+		return 0, nil
+	case len(versions) == 0:
+		return 0, ErrorVersionMismatch
+	case len(versions) == 1:
+		return versions[0], nil
+	}
+
+	process, err := controller.interactor.GetProcess(idProcess)
+	if err != nil {
+		return 0, err
+	} else if process == nil {
+		return 0, ErrorProcessNotFound
+	}
+
+	for _, version := range versions {
+		if version == process.Version {
+			return version, nil
+		}
+	}
+
+	return 0, ErrorVersionMismatch
+}
+
+// parseIfMatch returns the versions of the entity tags of an If-Match header, or if any version is accepted (*).
+// The weak entity tags are ignored, as If-Match only matches with the strong comparison
+func parseIfMatch(value string) ([]int64, bool) {
+	if strings.TrimSpace(value) == "*" {
+		return nil, true
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, false
+}
+
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/web"
+)
+
+func TestDeleteProcess(t *testing.T) {
+	m, url := newTestServer(t)
+
+	if response, body := testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "job", "type": "cron", "name": "job"}`); response.StatusCode != http.StatusCreated {
+		t.Fatalf("error creating process: %d %s", response.StatusCode, body)
+	}
+
+	if response, body := testRequest(t, http.MethodDelete, url+"/api/v1/processes/job", ""); response.StatusCode != http.StatusOK {
+		t.Fatalf("error deleting process: %d %s", response.StatusCode, body)
+	}
+	if response, _ := testRequest(t, http.MethodGet, url+"/api/v1/processes/job", ""); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the deleted process to be hidden, got %d", response.StatusCode)
+	}
+
+	if response, _ := testRequest(t, http.MethodPost, url+"/api/v1/processes/job/restore", ""); response.StatusCode != http.StatusOK {
+		t.Fatalf("expected the process to be restored, got %d", response.StatusCode)
+	}
+	if response, _ := testRequest(t, http.MethodPost, url+"/api/v1/processes/job/restore", ""); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a process that isn't deleted to not be restored, got %d", response.StatusCode)
+	}
+
+	// the process is deleted again and replaced by creating it with the same id
+	testRequest(t, http.MethodDelete, url+"/api/v1/processes/job", "")
+	if response, body := testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "job", "type": "daemon", "name": "new job"}`); response.StatusCode != http.StatusCreated {
+		t.Fatalf("error creating the deleted process: %d %s", response.StatusCode, body)
+	}
+
+	response, body := testRequest(t, http.MethodGet, url+"/api/v1/processes/job", "")
+	var process Process
+	if err := json.Unmarshal([]byte(body), &process); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("expected the process, got %d %s", response.StatusCode, body)
+	}
+	if process.Type != "daemon" || process.Name != "new job" {
+		t.Errorf("expected the process to be replaced, got %+v", process)
+	}
+
+	// the deleted processes are purged after the retention
+	testRequest(t, http.MethodDelete, url+"/api/v1/processes/job", "")
+	interactor := m.NewInteractor(m.NewStoragePostgres(m.pm.GetDB("db_postgres")))
+
+	if purged, err := interactor.PurgeProcesses(time.Hour); err != nil || purged != 0 {
+		t.Errorf("expected no process to be purged before the retention, got %d %v", purged, err)
+	}
+	if purged, err := interactor.PurgeProcesses(0); err != nil || purged != 1 {
+		t.Errorf("expected the process to be purged, got %d %v", purged, err)
+	}
+	if response, _ := testRequest(t, http.MethodPost, url+"/api/v1/processes/job/restore", ""); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a purged process to not be restored, got %d", response.StatusCode)
+	}
+}
+
+func TestDeleteProcesses(t *testing.T) {
+	_, url := newTestServer(t)
+
+	for _, id := range []string{"a", "b", "c"} {
+		kind := "batch"
+		if id == "c" {
+			kind = "cron"
+		}
+		testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "`+id+`", "type": "`+kind+`", "name": "`+id+`"}`)
+	}
+
+	if response, _ := testRequest(t, http.MethodDelete, url+"/api/v1/processes", ""); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a filter to be required, got %d", response.StatusCode)
+	}
+
+	response, body := testRequest(t, http.MethodDelete, url+"/api/v1/processes?type=batch", "")
+	var deleted DeleteProcessesResponse
+	if err := json.Unmarshal([]byte(body), &deleted); err != nil || response.StatusCode != http.StatusPreconditionRequired {
+		t.Fatalf("expected the deletion to require a confirmation, got %d %s", response.StatusCode, body)
+	}
+	if deleted.Count != 2 || deleted.Confirmation == "" {
+		t.Fatalf("expected 2 processes to confirm, got %+v", deleted)
+	}
+
+	// the confirmation is refused when the selection changes
+	if response, _ := testRequest(t, http.MethodDelete, url+"/api/v1/processes?type=cron&confirmation="+deleted.Confirmation, ""); response.StatusCode != http.StatusPreconditionRequired {
+		t.Errorf("expected the confirmation of other processes to be refused, got %d", response.StatusCode)
+	}
+
+	response, body = testRequest(t, http.MethodDelete, url+"/api/v1/processes?type=batch&confirmation="+deleted.Confirmation, "")
+	if err := json.Unmarshal([]byte(body), &deleted); err != nil || response.StatusCode != http.StatusOK || deleted.Count != 2 {
+		t.Fatalf("expected 2 processes to be deleted, got %d %s", response.StatusCode, body)
+	}
+
+	response, body = testRequest(t, http.MethodGet, url+"/api/v1/processes", "")
+	var processes ListProcess
+	if err := json.Unmarshal([]byte(body), &processes); err != nil || len(processes) != 1 || processes[0].IdProcess != "c" {
+		t.Errorf("expected only the process c to be listed, got %d %s", response.StatusCode, body)
+	}
+}
+
+func TestProcessVersion(t *testing.T) {
+	_, url := newTestServer(t)
+
+	testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "job", "type": "cron", "name": "job"}`)
+
+	response, _ := testRequest(t, http.MethodGet, url+"/api/v1/processes/job", "")
+	if etag := response.Header.Get(HeaderETag); etag != `"1"` {
+		t.Fatalf("expected the etag \"1\", got %s", etag)
+	}
+
+	update := `{"type": "cron", "name": "renamed"}`
+	if response, body := testRequest(t, http.MethodPut, url+"/api/v1/processes/job", update, HeaderIfMatch, `"1"`); response.StatusCode != http.StatusOK || response.Header.Get(HeaderETag) != `"2"` {
+		t.Fatalf("expected the process to be updated to the version 2, got %d %s %s", response.StatusCode, response.Header.Get(HeaderETag), body)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		ifMatch  string
+		expected int
+	}{
+		{name: "update with a stale version", method: http.MethodPut, path: "/api/v1/processes/job", ifMatch: `"1"`, expected: http.StatusPreconditionFailed},
+		{name: "update with a weak version", method: http.MethodPut, path: "/api/v1/processes/job", ifMatch: `W/"2"`, expected: http.StatusPreconditionFailed},
+		{name: "update with a list without the version", method: http.MethodPut, path: "/api/v1/processes/job", ifMatch: `"1", "3"`, expected: http.StatusPreconditionFailed},
+		{name: "update with a list with the version", method: http.MethodPut, path: "/api/v1/processes/job", ifMatch: `"1", "2"`, expected: http.StatusOK},
+		{name: "update with any version", method: http.MethodPut, path: "/api/v1/processes/job", ifMatch: `*`, expected: http.StatusOK},
+		{name: "status with a stale version", method: http.MethodPut, path: "/api/v1/processes/job/status/stopped", ifMatch: `"1"`, expected: http.StatusPreconditionFailed},
+		{name: "status with the version", method: http.MethodPut, path: "/api/v1/processes/job/status/stopped", ifMatch: `"4"`, expected: http.StatusOK},
+		{name: "status without a version", method: http.MethodPut, path: "/api/v1/processes/job/status/stopped", expected: http.StatusOK},
+		{name: "delete with a stale version", method: http.MethodDelete, path: "/api/v1/processes/job", ifMatch: `"1"`, expected: http.StatusPreconditionFailed},
+		{name: "delete an unknown process", method: http.MethodDelete, path: "/api/v1/processes/unknown", expected: http.StatusNotFound},
+		{name: "delete an unknown process with a version", method: http.MethodDelete, path: "/api/v1/processes/unknown", ifMatch: `"1"`, expected: http.StatusNotFound},
+		{name: "delete with the version", method: http.MethodDelete, path: "/api/v1/processes/job", ifMatch: `"6"`, expected: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		var headers []string
+		if test.ifMatch != "" {
+			headers = []string{HeaderIfMatch, test.ifMatch}
+		}
+
+		if response, body := testRequest(t, test.method, url+test.path, update, headers...); response.StatusCode != test.expected {
+			t.Errorf("%s: expected %d, got %d %s", test.name, test.expected, response.StatusCode, body)
+		}
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		value      string
+		versions   []int64
+		anyVersion bool
+	}{
+		{value: `*`, anyVersion: true},
+		{value: `"3"`, versions: []int64{3}},
+		{value: `"3", "4"`, versions: []int64{3, 4}},
+		{value: `"3",W/"4"`, versions: []int64{3}},
+		{value: `W/"3"`},
+		{value: `3`},
+		{value: `"abc", "0"`},
+	}
+
+	for _, test := range tests {
+		versions, anyVersion := parseIfMatch(test.value)
+		if anyVersion != test.anyVersion || fmt.Sprint(versions) != fmt.Sprint(test.versions) {
+			t.Errorf("%s: expected %v %v, got %v %v", test.value, test.versions, test.anyVersion, versions, anyVersion)
+		}
+	}
+}
+
+func TestBatchProcesses(t *testing.T) {
+	_, url := newTestServer(t)
+
+	testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "report", "type": "cron", "name": "report"}`)
+
+	response, body := testRequest(t, http.MethodPost, url+"/api/v1/processes:batch", `{
+		"mode": "best_effort",
+		"operations": [
+			{"op": "create", "body": {"id_process": "backup", "type": "cron", "name": "backup"}},
+			null,
+			{"op": "update", "id_process": "report", "version": 3, "body": {"type": "cron", "name": "report"}},
+			{"op": "status", "id_process": "report", "status": "running"}
+		]
+	}`)
+
+	var batch BatchProcessesResponse
+	if err := json.Unmarshal([]byte(body), &batch); err != nil || response.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected the batch to partially fail, got %d %s", response.StatusCode, body)
+	}
+	if batch.Succeeded != 2 || batch.Failed != 2 {
+		t.Fatalf("expected 2 operations to succeed and 2 to fail, got %s", body)
+	}
+	for i, status := range []web.Status{web.StatusCreated, web.StatusBadRequest, web.StatusPreconditionFailed, web.StatusOK} {
+		if batch.Results[i].Status != status {
+			t.Errorf("expected the operation %d to have the status %d, got %d", i, status, batch.Results[i].Status)
+		}
+	}
+
+	// on atomic mode the failure of an operation aborts the others
+	response, body = testRequest(t, http.MethodPost, url+"/api/v1/processes:batch", `{
+		"operations": [
+			{"op": "create", "body": {"id_process": "cleanup", "type": "cron", "name": "cleanup"}},
+			{"op": "create", "body": {"id_process": "backup", "type": "cron", "name": "backup"}}
+		]
+	}`)
+	if err := json.Unmarshal([]byte(body), &batch); err != nil || response.StatusCode != http.StatusMultiStatus || batch.Succeeded != 0 {
+		t.Fatalf("expected the batch to fail, got %d %s", response.StatusCode, body)
+	}
+	if response, _ := testRequest(t, http.MethodGet, url+"/api/v1/processes/cleanup", ""); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the process of an aborted operation to not be created, got %d", response.StatusCode)
+	}
+
+	// the router takes :batch as a parameter, but only the batch path runs a batch
+	if response, _ := testRequest(t, http.MethodPost, url+"/api/v1/processesfoo", `{"operations": [{"op": "create", "body": {"id_process": "foo", "type": "cron", "name": "foo"}}]}`); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected another path to not be found, got %d", response.StatusCode)
+	}
+	if response, _ := testRequest(t, http.MethodGet, url+"/api/v1/processes/foo", ""); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected another path to not run a batch, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/web"
+)
+
+// ErrorCode is the stable, machine readable code of an error, used as the code of the errors
+type ErrorCode string
+
+const (
+	ErrorCodeInternal              ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeInvalidBody           ErrorCode = "INVALID_BODY"
+	ErrorCodeValidationFailed      ErrorCode = "VALIDATION_FAILED"
+	ErrorCodeRouteNotFound         ErrorCode = "ROUTE_NOT_FOUND"
+	ErrorCodeProcessNotFound       ErrorCode = "PROCESS_NOT_FOUND"
+	ErrorCodeProcessAlreadyExists  ErrorCode = "PROCESS_ALREADY_EXISTS"
+	ErrorCodeAlreadyRunning        ErrorCode = "ALREADY_RUNNING"
+	ErrorCodeWindowClosed          ErrorCode = "WINDOW_CLOSED"
+	ErrorCodeLeaseLost             ErrorCode = "LEASE_LOST"
+	ErrorCodeVersionMismatch       ErrorCode = "VERSION_MISMATCH"
+	ErrorCodeVersionRequired       ErrorCode = "VERSION_REQUIRED"
+	ErrorCodeUnsupportedPatch      ErrorCode = "UNSUPPORTED_PATCH"
+	ErrorCodeInvalidPatch          ErrorCode = "INVALID_PATCH"
+	ErrorCodeBatchTooLarge         ErrorCode = "BATCH_TOO_LARGE"
+	ErrorCodeBatchAborted          ErrorCode = "BATCH_ABORTED"
+	ErrorCodeIdempotencyKeyInvalid ErrorCode = "IDEMPOTENCY_KEY_INVALID"
+	ErrorCodeIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	ErrorCodeIdempotencyMismatch   ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeInvalidFilter         ErrorCode = "INVALID_FILTER"
+	ErrorCodeFilterRequired        ErrorCode = "FILTER_REQUIRED"
+	ErrorCodeConfirmationRequired  ErrorCode = "CONFIRMATION_REQUIRED"
+)
+
+type errorDefinition struct {
+	status web.Status
+	title  string
+}
+
+// errorDefinitions maps each error code to its http status and title
+var errorDefinitions = map[ErrorCode]errorDefinition{
+	ErrorCodeInternal:              {web.StatusInternalServerError, "Internal error"},
+	ErrorCodeInvalidBody:           {web.StatusBadRequest, "Invalid request body"},
+	ErrorCodeValidationFailed:      {web.StatusBadRequest, "Validation failed"},
+	ErrorCodeRouteNotFound:         {web.StatusNotFound, "Route not found"},
+	ErrorCodeProcessNotFound:       {web.StatusNotFound, "Process not found"},
+	ErrorCodeProcessAlreadyExists:  {web.StatusConflict, "Process already exists"},
+	ErrorCodeAlreadyRunning:        {web.StatusConflict, "Process already running"},
+	ErrorCodeWindowClosed:          {web.StatusConflict, "Process window closed"},
+	ErrorCodeLeaseLost:             {web.StatusConflict, "Lease lost"},
+	ErrorCodeVersionMismatch:       {web.StatusPreconditionFailed, "Version mismatch"},
+	ErrorCodeVersionRequired:       {web.StatusPreconditionRequired, "Version required"},
+	ErrorCodeUnsupportedPatch:      {web.StatusUnsupportedMediaType, "Unsupported patch"},
+	ErrorCodeInvalidPatch:          {web.StatusUnprocessableEntity, "Invalid patch"},
+	ErrorCodeBatchTooLarge:         {web.StatusRequestEntityTooLarge, "Batch too large"},
+	ErrorCodeBatchAborted:          {web.StatusFailedDependency, "Batch aborted"},
+	ErrorCodeIdempotencyKeyInvalid: {web.StatusBadRequest, "Invalid idempotency key"},
+	ErrorCodeIdempotencyInProgress: {web.StatusConflict, "Idempotent request in progress"},
+	ErrorCodeIdempotencyMismatch:   {web.StatusUnprocessableEntity, "Idempotency key reused"},
+	ErrorCodeInvalidFilter:         {web.StatusBadRequest, "Invalid filter"},
+	ErrorCodeFilterRequired:        {web.StatusBadRequest, "Filter required"},
+	ErrorCodeConfirmationRequired:  {web.StatusPreconditionRequired, "Confirmation required"},
+}
+
+var (
+	ErrorRouteNotFound         = errors.New(errors.LevelError, ErrorCodeRouteNotFound, "route not found")
+	ErrorProcessNotFound       = errors.New(errors.LevelError, ErrorCodeProcessNotFound, "process not found")
+	ErrorProcessAlreadyExists  = errors.New(errors.LevelError, ErrorCodeProcessAlreadyExists, "a process with the same id already exists")
+	ErrorAlreadyRunning        = errors.New(errors.LevelError, ErrorCodeAlreadyRunning, "the process is already running")
+	ErrorLeaseLost             = errors.New(errors.LevelError, ErrorCodeLeaseLost, "the process isn't running anymore")
+	ErrorVersionMismatch       = errors.New(errors.LevelError, ErrorCodeVersionMismatch, "the process was changed in the meantime, get the latest version and try again")
+	ErrorVersionRequired       = errors.New(errors.LevelError, ErrorCodeVersionRequired, "the If-Match header with the process version is required")
+	ErrorUnsupportedPatch      = errors.New(errors.LevelError, ErrorCodeUnsupportedPatch, "unsupported patch, use the content type application/merge-patch+json or application/json-patch+json")
+	ErrorBatchAborted          = errors.New(errors.LevelError, ErrorCodeBatchAborted, "the operation wasn't applied because another operation of the batch failed")
+	ErrorIdempotencyKeyInvalid = errors.New(errors.LevelError, ErrorCodeIdempotencyKeyInvalid, "the Idempotency-Key header is too long")
+	ErrorIdempotencyInProgress = errors.New(errors.LevelError, ErrorCodeIdempotencyInProgress, "a request with the same Idempotency-Key is still in progress")
+	ErrorIdempotencyMismatch   = errors.New(errors.LevelError, ErrorCodeIdempotencyMismatch, "the Idempotency-Key was already used with a different request")
+	ErrorInvalidFilter         = errors.New(errors.LevelError, ErrorCodeInvalidFilter, "invalid filter, the allowed filters are id_process, type, name, monitor and status")
+	ErrorFilterRequired        = errors.New(errors.LevelError, ErrorCodeFilterRequired, "a filter or selector is required to delete processes")
+	ErrorConfirmationRequired  = errors.New(errors.LevelError, ErrorCodeConfirmationRequired, "the deletion must be confirmed with the returned confirmation token")
+)
+
+// errorCode returns the code of an error, internal error when it hasn't a known code
+func errorCode(err error) ErrorCode {
+	switch e := err.(type) {
+	case *ValidationError:
+		return ErrorCodeValidationFailed
+	case *errors.Error:
+		if code, ok := e.Code.(ErrorCode); ok {
+			if _, ok := errorDefinitions[code]; ok {
+				return code
+			}
+		}
+	}
+
+	return ErrorCodeInternal
+}
+
+// errorStatus returns the http status of an error
+func errorStatus(err error) web.Status {
+	return errorDefinitions[errorCode(err)].status
+}
@@ -0,0 +1,253 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ContentTypeYAML = "application/yaml"
+	ContentTypeCSV  = "text/csv"
+)
+
+// csvColumns are the columns of the csv files, the days off are separated by commas
+var csvColumns = []string{"id_process", "type", "name", "description", "date_from", "date_to", "time_from", "time_to", "days_off", "monitor", "status"}
+
+// fileFormat returns the format of a content type or extension, json by default
+func fileFormat(contentType string) FileFormat {
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return FileFormatCSV
+	case strings.Contains(contentType, "yaml"), strings.Contains(contentType, "yml"):
+		return FileFormatYAML
+	default:
+		return FileFormatJSON
+	}
+}
+
+// ContentType returns the content type of the format
+func (format FileFormat) ContentType() string {
+	switch format {
+	case FileFormatCSV:
+		return ContentTypeCSV
+	case FileFormatYAML:
+		return ContentTypeYAML
+	default:
+		return "application/json"
+	}
+}
+
+// definition returns the definition of the process, without the runtime fields
+func (process *Process) definition() *ProcessDefinition {
+	return &ProcessDefinition{
+		IdProcess:   process.IdProcess,
+		Type:        process.Type,
+		Name:        process.Name,
+		Description: process.Description,
+		DateFrom:    process.DateFrom,
+		DateTo:      process.DateTo,
+		TimeFrom:    process.TimeFrom,
+		TimeTo:      process.TimeTo,
+		DaysOff:     process.DaysOff,
+		Monitor:     process.Monitor,
+		Status:      process.Status,
+	}
+}
+
+// EncodeDefinitions writes the definitions on the format
+func EncodeDefinitions(format FileFormat, definitions []*ProcessDefinition) ([]byte, error) {
+	switch format {
+	case FileFormatCSV:
+		return encodeCSV(definitions)
+	case FileFormatYAML:
+		return encodeYAML(definitions)
+	default:
+		return json.MarshalIndent(definitions, "", "  ")
+	}
+}
+
+// DecodeDefinitions reads the definitions of the format, the json and yaml files can also have them
+// on a processes key, like the apply request
+func DecodeDefinitions(format FileFormat, data []byte) ([]*ProcessDefinition, error) {
+	if format == FileFormatCSV {
+		return decodeCSV(data)
+	}
+
+	// json is also yaml, so both are read as yaml and converted to json
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid %s: %s", format, err)
+	}
+	if document, ok := value.(map[string]interface{}); ok && document["processes"] != nil {
+		value = document["processes"]
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid %s: %s", format, err)
+	}
+
+	definitions := make([]*ProcessDefinition, 0)
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid %s, expected a list of processes: %s", format, err)
+	}
+
+	return definitions, nil
+}
+
+// encodeYAML writes the yaml with the json names and order of the fields, the strings with
+// colons are kept quoted because the yaml 1.1 parsers read times, like 08:00:00, as numbers
+func encodeYAML(definitions []*ProcessDefinition) ([]byte, error) {
+	data, err := json.Marshal(definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+
+	var style func(node *yaml.Node)
+	style = func(node *yaml.Node) {
+		if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, ":") {
+			node.Style = 0
+		}
+		for _, child := range node.Content {
+			style(child)
+		}
+	}
+	style(node)
+
+	return yaml.Marshal(node)
+}
+
+func encodeCSV(definitions []*ProcessDefinition) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, err
+	}
+
+	for _, definition := range definitions {
+		days := make([]string, 0)
+		if definition.DaysOff != nil {
+			for _, day := range *definition.DaysOff {
+				days = append(days, string(day))
+			}
+		}
+
+		record := []string{
+			definition.IdProcess,
+			definition.Type,
+			definition.Name,
+			definition.Description,
+			optionalString(definition.DateFrom),
+			optionalString(definition.DateTo),
+			optionalString(definition.TimeFrom),
+			optionalString(definition.TimeTo),
+			strings.Join(days, ","),
+			definition.Monitor,
+			optionalString(definition.Status),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// decodeCSV reads the csv by the names of the header, the empty cells aren't set
+func decodeCSV(data []byte) ([]*ProcessDefinition, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid csv: %s", err)
+	}
+	if len(records) == 0 {
+		return []*ProcessDefinition{}, nil
+	}
+
+	known := make(map[string]bool, len(csvColumns))
+	for _, column := range csvColumns {
+		known[column] = true
+	}
+
+	header := records[0]
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !known[header[i]] {
+			return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid csv, unknown column %q, the columns are %s", column, strings.Join(csvColumns, ", "))
+		}
+	}
+
+	definitions := make([]*ProcessDefinition, 0, len(records)-1)
+	for line, record := range records[1:] {
+		if len(record) != len(header) {
+			return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid csv, the line %d has %d columns instead of %d", line+2, len(record), len(header))
+		}
+
+		definition := &ProcessDefinition{}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			switch header[i] {
+			case "id_process":
+				definition.IdProcess = value
+			case "type":
+				definition.Type = value
+			case "name":
+				definition.Name = value
+			case "description":
+				definition.Description = value
+			case "date_from":
+				definition.DateFrom = pointer(types.Date(value))
+			case "date_to":
+				definition.DateTo = pointer(types.Date(value))
+			case "time_from":
+				definition.TimeFrom = pointer(types.Time(value))
+			case "time_to":
+				definition.TimeTo = pointer(types.Time(value))
+			case "days_off":
+				days := make(types.ListDay, 0)
+				for _, day := range strings.Split(value, ",") {
+					days = append(days, types.Day(strings.ToLower(strings.TrimSpace(day))))
+				}
+				definition.DaysOff = &days
+			case "monitor":
+				definition.Monitor = value
+			case "status":
+				definition.Status = pointer(Status(value))
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+// optionalString returns the value, or an empty string when it isn't set
+func optionalString[T ~string](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+func pointer[T any](value T) *T {
+	return &value
+}
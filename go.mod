@@ -3,6 +3,7 @@ module github.com/joaosoft/monitor
 go 1.20
 
 require (
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/joaosoft/errors v0.0.0-20230531141818-ebb38600b462
 	github.com/joaosoft/logger v0.0.0-20230531142923-753c0a3e836a
 	github.com/joaosoft/manager v0.0.0-20230531145924-a549066d2284
@@ -11,12 +12,13 @@ require (
 	github.com/joaosoft/validator v0.0.0-20230531142908-28a5b2f72266
 	github.com/joaosoft/web v0.0.0-20230531143830-cd31d8a8c35e
 	github.com/labstack/gommon v0.4.0
+	github.com/mattn/go-sqlite3 v1.14.17
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/alphazero/Go-Redis v0.0.0-20120924171622-a0637b154364 // indirect
 	github.com/fsnotify/fsnotify v1.6.0 // indirect
-	github.com/go-sql-driver/mysql v1.7.1 // indirect
 	github.com/golang/snappy v0.0.1 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/joaosoft/auth-types/basic v0.0.0-20230531143726-6905d84fa794 // indirect
@@ -47,5 +49,4 @@ require (
 	golang.org/x/text v0.9.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
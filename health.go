@@ -0,0 +1,209 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+)
+
+// Health tells if the monitor is alive and ready, with the status of each component.
+// It's a process of the manager, stopped before the web server, so the monitor is
+// reported as not ready while draining on shutdown
+type Health struct {
+	checks   []*healthCheck
+	drain    time.Duration
+	stopping bool
+	started  bool
+	logger   logger.ILogger
+	mux      sync.Mutex
+}
+
+type healthCheck struct {
+	name  string
+	check func() (string, error)
+}
+
+// NewHealth ...
+func (monitor *Monitor) NewHealth() (*Health, error) {
+	health := &Health{
+		drain:  DefaultShutdownDrain,
+		logger: monitor.logger,
+	}
+
+	if monitor.config != nil && monitor.config.Shutdown.Drain != "" {
+		drain, err := time.ParseDuration(monitor.config.Shutdown.Drain)
+		if err != nil {
+			return nil, errors.New(errors.LevelError, 0, "invalid shutdown drain %s: %s", monitor.config.Shutdown.Drain, err)
+		}
+		health.drain = drain
+	}
+
+	return health, nil
+}
+
+// AddCheck adds the check of a component to the readiness, it returns a detail or the error when it's down
+func (health *Health) AddCheck(name string, check func() (string, error)) {
+	health.mux.Lock()
+	defer health.mux.Unlock()
+
+	health.checks = append(health.checks, &healthCheck{name: name, check: check})
+}
+
+// Live returns the liveness, the monitor is alive while it answers
+func (health *Health) Live() *HealthResponse {
+	return &HealthResponse{Status: HealthUp}
+}
+
+// Ready returns the readiness with the status of each component, it's down when any component is down
+func (health *Health) Ready() *HealthResponse {
+	health.mux.Lock()
+	checks := health.checks
+	stopping := health.stopping
+	health.mux.Unlock()
+
+	response := &HealthResponse{Status: HealthUp, Components: make(map[string]*ComponentHealth)}
+
+	server := &ComponentHealth{Status: HealthUp}
+	if stopping {
+		server = &ComponentHealth{Status: HealthDown, Detail: "shutting down"}
+	}
+	response.Components["server"] = server
+
+	for _, check := range checks {
+		component := &ComponentHealth{Status: HealthUp}
+		detail, err := check.check()
+		if err != nil {
+			component.Status = HealthDown
+			detail = err.Error()
+		}
+		component.Detail = detail
+		response.Components[check.name] = component
+	}
+
+	for _, component := range response.Components {
+		if component.Status == HealthDown {
+			response.Status = HealthDown
+		}
+	}
+
+	return response
+}
+
+// Start ...
+func (health *Health) Start(waitGroup ...*sync.WaitGroup) error {
+	var wg *sync.WaitGroup
+
+	if len(waitGroup) == 0 {
+		wg = &sync.WaitGroup{}
+		wg.Add(1)
+	} else {
+		wg = waitGroup[0]
+	}
+
+	defer wg.Done()
+
+	health.mux.Lock()
+	defer health.mux.Unlock()
+
+	health.started = true
+	health.stopping = false
+
+	return nil
+}
+
+// Stop reports the monitor as not ready and waits the drain, before the web server is stopped
+func (health *Health) Stop(waitGroup ...*sync.WaitGroup) error {
+	var wg *sync.WaitGroup
+
+	if len(waitGroup) == 0 {
+		wg = &sync.WaitGroup{}
+		wg.Add(1)
+	} else {
+		wg = waitGroup[0]
+	}
+
+	defer wg.Done()
+
+	health.mux.Lock()
+	if !health.started {
+		health.mux.Unlock()
+		return nil
+	}
+	health.started = false
+	health.stopping = true
+	health.mux.Unlock()
+
+	if health.drain > 0 {
+		health.logger.Infof("not ready, draining for %s", health.drain)
+		time.Sleep(health.drain)
+	}
+
+	return nil
+}
+
+// Started ...
+func (health *Health) Started() bool {
+	health.mux.Lock()
+	defer health.mux.Unlock()
+
+	return health.started
+}
+
+// addDatabaseChecks checks the connection to the database and that the migrations of the table are on the expected version
+func (monitor *Monitor) addDatabaseChecks(health *Health, connection manager.IDB, migrationTable, expected string) {
+	health.AddCheck("database", func() (string, error) {
+		db := connection.Get()
+		if db == nil {
+			return "", fmt.Errorf("not connected")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+		defer cancel()
+
+		if err := db.PingContext(ctx); err != nil {
+			return "", err
+		}
+		return "connected", nil
+	})
+
+	health.AddCheck("migrations", func() (string, error) {
+		db := connection.Get()
+		if db == nil {
+			return "", fmt.Errorf("not connected")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+		defer cancel()
+
+		var current sql.NullString
+		if err := db.QueryRowContext(ctx, fmt.Sprintf(`SELECT MAX(id_migration) FROM %s WHERE mode = 'database'`, migrationTable)).Scan(&current); err != nil {
+			return "", err
+		}
+
+		if current.String != expected {
+			return "", fmt.Errorf("at version %q, expected %q", current.String, expected)
+		}
+		return fmt.Sprintf("at version %q", current.String), nil
+	})
+}
+
+// postgresMigrations returns the migration table of postgres and the last migration of the configuration
+func (monitor *Monitor) postgresMigrations() (string, string) {
+	table := "migration"
+	if monitor.config.Migration == nil {
+		return table, ""
+	}
+
+	if monitor.config.Migration.Db != nil && monitor.config.Migration.Db.Schema != "" {
+		table = fmt.Sprintf(`"%s".migration`, monitor.config.Migration.Db.Schema)
+	}
+
+	return table, lastMigration(os.DirFS(monitor.config.Migration.Path.Database))
+}
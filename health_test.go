@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/web"
+)
+
+func newTestHealth(drain time.Duration) *Health {
+	return &Health{drain: drain, logger: logger.NewLogDefault("monitor", logger.ErrorLevel)}
+}
+
+func TestHealthReady(t *testing.T) {
+	health := newTestHealth(0)
+	health.Start()
+
+	if live := health.Live(); live.Status != HealthUp {
+		t.Errorf("expected the monitor to be alive, got %+v", live)
+	}
+
+	health.AddCheck("database", func() (string, error) { return "connected", nil })
+	if ready := health.Ready(); ready.Status != HealthUp || ready.Components["database"].Detail != "connected" || ready.Components["server"].Status != HealthUp {
+		t.Errorf("expected the monitor to be ready, got %+v", ready)
+	}
+
+	health.AddCheck("migrations", func() (string, error) { return "", fmt.Errorf("at version %q, expected %q", "01", "02") })
+	ready := health.Ready()
+	if ready.Status != HealthDown || ready.Components["database"].Status != HealthUp {
+		t.Errorf("expected the monitor to not be ready with a component down, got %+v", ready)
+	}
+	if migrations := ready.Components["migrations"]; migrations.Status != HealthDown || migrations.Detail != `at version "01", expected "02"` {
+		t.Errorf("expected the error of the component as detail, got %+v", migrations)
+	}
+}
+
+func TestHealthDrain(t *testing.T) {
+	health := newTestHealth(200 * time.Millisecond)
+	health.Start()
+
+	stopped := make(chan error)
+	go func() { stopped <- health.Stop() }()
+
+	// while draining the monitor is alive but not ready
+	deadline := time.Now().Add(health.drain / 2)
+	for health.Ready().Status == HealthUp && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("expected the stop to wait the drain")
+	default:
+	}
+
+	ready := health.Ready()
+	if ready.Status != HealthDown || ready.Components["server"].Detail != "shutting down" {
+		t.Errorf("expected the monitor to not be ready while draining, got %+v", ready)
+	}
+	if live := health.Live(); live.Status != HealthUp {
+		t.Errorf("expected the monitor to be alive while draining, got %+v", live)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("error stopping: %s", err)
+	}
+	if health.Started() || health.Ready().Status != HealthDown {
+		t.Error("expected the monitor to not be ready after stopping")
+	}
+
+	// starting again makes it ready
+	health.Start()
+	if ready := health.Ready(); ready.Status != HealthUp {
+		t.Errorf("expected the monitor to be ready after starting, got %+v", ready)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	health := newTestHealth(0)
+	controller := &Controller{health: health}
+
+	tests := []struct {
+		name   string
+		check  func() (string, error)
+		status web.Status
+	}{
+		{name: "ready", check: func() (string, error) { return "connected", nil }, status: web.StatusOK},
+		{name: "not ready", check: func() (string, error) { return "", fmt.Errorf("not connected") }, status: web.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			health.checks = nil
+			health.AddCheck("database", test.check)
+
+			ctx := web.NewContext(time.Now(),
+				&web.Request{Base: web.Base{Address: web.NewAddress("http://localhost/health/ready"), Headers: web.Headers{}}},
+				&web.Response{Base: web.Base{Headers: web.Headers{}}})
+			if err := controller.ReadyHandler(ctx); err != nil {
+				t.Fatalf("error getting the readiness: %s", err)
+			}
+
+			response := &HealthResponse{}
+			if err := json.Unmarshal(ctx.Response.Body, response); err != nil {
+				t.Fatalf("error decoding the readiness: %s", err)
+			}
+			if ctx.Response.Status != test.status || response.Components["database"] == nil {
+				t.Errorf("expected the status %d with the database, got %d %s", test.status, ctx.Response.Status, ctx.Response.Body)
+			}
+		})
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	_, url := newTestServer(t)
+
+	for _, path := range []string{"/health/live", "/health/ready"} {
+		response, body := testRequest(t, http.MethodGet, url+path, "")
+
+		health := &HealthResponse{}
+		if err := json.Unmarshal([]byte(body), health); err != nil {
+			t.Fatalf("error decoding %s: %s", path, err)
+		}
+		if response.StatusCode != http.StatusOK || health.Status != HealthUp {
+			t.Errorf("expected %s to be up, got %d %s", path, response.StatusCode, body)
+		}
+	}
+}
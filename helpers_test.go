@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+	migration "github.com/joaosoft/migration/services"
+	"github.com/joaosoft/types"
+)
+
+// newTestServer starts a monitor on a free port with the postgres database of MONITOR_POSTGRES_DATASOURCE,
+// that is cleaned, returning the monitor and the url of its api. The tests are skipped without the database
+func newTestServer(t *testing.T) (*Monitor, string) {
+	dataSource := os.Getenv("MONITOR_POSTGRES_DATASOURCE")
+	if dataSource == "" {
+		t.Skip("MONITOR_POSTGRES_DATASOURCE isn't set")
+	}
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("error getting a free port: %s", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := &MonitorConfig{
+		Host:      address,
+		Db:        manager.DBConfig{Driver: "postgres", DataSource: dataSource},
+		Migration: &migration.MigrationConfig{Db: &migration.DBConfig{DBConfig: manager.DBConfig{Driver: "postgres", DataSource: dataSource}, Schema: "monitor"}},
+	}
+	config.Migration.Path.Database = "schema/db/postgres"
+
+	m, err := NewMonitor(
+		WithConfiguration(config),
+		WithManager(manager.NewManager(manager.WithRunInBackground(true))),
+		WithLogLevel(logger.ErrorLevel),
+	)
+	if err != nil {
+		t.Fatalf("error creating the monitor: %s", err)
+	}
+
+	if err := m.Start(); err != nil {
+		t.Fatalf("error starting the monitor: %s", err)
+	}
+	t.Cleanup(func() { m.Stop() })
+
+	for _, table := range []string{"monitor.process", "monitor.process_history"} {
+		if _, err := m.pm.GetDB("db_postgres").Get().Exec(`DELETE FROM ` + table); err != nil {
+			t.Fatalf("error cleaning %s: %s", table, err)
+		}
+	}
+
+	// the web server is started on background
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", address); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return m, fmt.Sprintf("http://%s", address)
+}
+
+// testRequest does a request with the headers, given as pairs of name and value,
+// returning the response and its body
+func testRequest(t *testing.T, method, url, body string, headers ...string) (*http.Response, string) {
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("error creating the request: %s", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("error doing the request %s %s: %s", method, url, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("error reading the response: %s", err)
+	}
+
+	return response, string(data)
+}
+
+// NewTestMonitor is newTestMonitor for the tests of monitor_test
+var NewTestMonitor = newTestMonitor
+
+// testDatabases are the names of the databases of the drivers on the manager
+var testDatabases = map[string]string{
+	DriverSQLite:   "db_sqlite",
+	DriverMySQL:    "db_mysql",
+	DriverPostgres: "db_postgres",
+}
+
+// newTestMonitor creates a monitor with the database of the configuration, starting only the database,
+// so the storage can be used without the web server
+func newTestMonitor(t *testing.T, config *MonitorConfig) *Monitor {
+	config.Host = "localhost:0"
+
+	m, err := NewMonitor(
+		WithConfiguration(config),
+		WithManager(manager.NewManager(manager.WithRunInBackground(true))),
+		WithLogLevel(logger.ErrorLevel),
+	)
+	if err != nil {
+		t.Fatalf("error creating the monitor: %s", err)
+	}
+
+	db := m.pm.GetDB(testDatabases[config.Db.Driver])
+	if err := db.Start(); err != nil {
+		t.Fatalf("error starting the database: %s", err)
+	}
+	t.Cleanup(func() { db.Stop() })
+
+	return m
+}
+
+func testDate(value string) *types.Date {
+	date := types.Date(value)
+	return &date
+}
+
+func testTime(value string) *types.Time {
+	clock := types.Time(value)
+	return &clock
+}
+
+func testDays(values ...types.Day) *types.ListDay {
+	days := types.ListDay(values)
+	return &days
+}
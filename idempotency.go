@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/web"
+)
+
+// IIdempotencyStore keeps the responses of the requests with an idempotency key
+type IIdempotencyStore interface {
+	// Begin reserves the key for a request, returning the stored response when the request was already done
+	Begin(key, fingerprint string, expiresAt time.Time) (*IdempotentResponse, error)
+	// Complete stores the response of the request that reserved the key
+	Complete(key string, response *IdempotentResponse) error
+	// Release frees the key so the request can be retried
+	Release(key string) error
+}
+
+type IdempotentResponse struct {
+	Status      web.Status      `json:"status"`
+	ContentType web.ContentType `json:"content_type"`
+	ETag        string          `json:"etag"`
+	Body        []byte          `json:"body"`
+}
+
+type Idempotency struct {
+	store  IIdempotencyStore
+	ttl    time.Duration
+	logger logger.ILogger
+}
+
+// NewIdempotency ...
+func (monitor *Monitor) NewIdempotency(store IIdempotencyStore) (*Idempotency, error) {
+	idempotency := &Idempotency{
+		store:  store,
+		ttl:    DefaultIdempotencyTTL,
+		logger: monitor.logger,
+	}
+
+	if monitor.config != nil && monitor.config.Idempotency.TTL != "" {
+		ttl, err := time.ParseDuration(monitor.config.Idempotency.TTL)
+		if err != nil {
+			return nil, errors.New(errors.LevelError, 0, "invalid idempotency ttl %s: %s", monitor.config.Idempotency.TTL, err)
+		}
+		idempotency.ttl = ttl
+	}
+
+	return idempotency, nil
+}
+
+// Middleware replays the stored response of a request retried with the same idempotency key and body
+func (idempotency *Idempotency) Middleware() web.MiddlewareFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx *web.Context) error {
+			key := ctx.Request.GetHeader(HeaderIdempotencyKey)
+			if key == "" {
+				return next(ctx)
+			}
+
+			if len(key) > MaxIdempotencyKeyLength {
+				return writeProblem(ctx, ErrorIdempotencyKeyInvalid)
+			}
+
+			key = scopedKey(ctx.Request, key)
+			stored, err := idempotency.store.Begin(key, fingerprint(ctx.Request), time.Now().Add(idempotency.ttl))
+			if err != nil {
+				return writeProblem(ctx, err)
+			}
+
+			if stored != nil {
+				if stored.ETag != "" {
+					ctx.Response.SetHeader(HeaderETag, []string{stored.ETag})
+				}
+				ctx.Response.SetHeader(HeaderIdempotentReplayed, []string{"true"})
+				return ctx.Response.Bytes(stored.Status, stored.ContentType, stored.Body)
+			}
+
+			err = next(ctx)
+
+			// server errors aren't stored, so the request can be retried
+			if err != nil || ctx.Response.Status >= web.StatusInternalServerError {
+				if errRelease := idempotency.store.Release(key); errRelease != nil {
+					idempotency.logger.Errorf("error releasing idempotency key %s: %s", key, errRelease)
+				}
+				return err
+			}
+
+			response := &IdempotentResponse{
+				Status:      ctx.Response.Status,
+				ContentType: ctx.Response.ContentType,
+				ETag:        ctx.Response.GetHeader(HeaderETag),
+				Body:        ctx.Response.Body,
+			}
+			if errComplete := idempotency.store.Complete(key, response); errComplete != nil {
+				idempotency.logger.Errorf("error storing idempotency key %s: %s", key, errComplete)
+			}
+
+			return nil
+		}
+	}
+}
+
+// scopedKey scopes the key by the caller, identified by its Authorization header, so the callers
+// sending the same key don't get the responses of each other
+func scopedKey(request *web.Request, key string) string {
+	authorization := request.GetHeader(HeaderAuthorization)
+	if authorization == "" {
+		return key
+	}
+
+	hash := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(hash[:]) + ":" + key
+}
+
+// fingerprint identifies a request by its method, url and body
+func fingerprint(request *web.Request) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.Method))
+	hash.Write([]byte(" "))
+	hash.Write([]byte(request.Address.Url))
+	hash.Write([]byte("\n"))
+	hash.Write(request.Body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	response    *IdempotentResponse
+	expiresAt   time.Time
+}
+
+// MemoryIdempotencyStore keeps the idempotency keys in memory, so they are lost on restart
+type MemoryIdempotencyStore struct {
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+	mux       sync.Mutex
+}
+
+// NewMemoryIdempotencyStore ...
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries:   make(map[string]*idempotencyEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Begin ...
+func (store *MemoryIdempotencyStore) Begin(key, fingerprint string, expiresAt time.Time) (*IdempotentResponse, error) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(store.lastSweep) > time.Minute {
+		for k, entry := range store.entries {
+			if now.After(entry.expiresAt) {
+				delete(store.entries, k)
+			}
+		}
+		store.lastSweep = now
+	}
+
+	if entry, ok := store.entries[key]; ok && now.Before(entry.expiresAt) {
+		if entry.fingerprint != fingerprint {
+			return nil, ErrorIdempotencyMismatch
+		}
+
+		if entry.response == nil {
+			return nil, ErrorIdempotencyInProgress
+		}
+
+		return entry.response, nil
+	}
+
+	store.entries[key] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expiresAt:   expiresAt,
+	}
+
+	return nil, nil
+}
+
+// Complete ...
+func (store *MemoryIdempotencyStore) Complete(key string, response *IdempotentResponse) error {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	if entry, ok := store.entries[key]; ok {
+		entry.response = response
+	}
+
+	return nil
+}
+
+// Release ...
+func (store *MemoryIdempotencyStore) Release(key string) error {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	delete(store.entries, key)
+
+	return nil
+}
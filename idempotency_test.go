@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	if stored, err := store.Begin("key", "request", expiresAt); err != nil || stored != nil {
+		t.Fatalf("expected the key to be reserved, got %v %v", stored, err)
+	}
+	if _, err := store.Begin("key", "request", expiresAt); err != ErrorIdempotencyInProgress {
+		t.Errorf("expected the request to be in progress, got %v", err)
+	}
+
+	store.Complete("key", &IdempotentResponse{Status: 201})
+	if stored, err := store.Begin("key", "request", expiresAt); err != nil || stored == nil || stored.Status != 201 {
+		t.Errorf("expected the stored response, got %v %v", stored, err)
+	}
+	if _, err := store.Begin("key", "other request", expiresAt); err != ErrorIdempotencyMismatch {
+		t.Errorf("expected the key to be refused for another request, got %v", err)
+	}
+
+	store.Release("key")
+	if stored, err := store.Begin("key", "other request", expiresAt); err != nil || stored != nil {
+		t.Errorf("expected a released key to be reserved again, got %v %v", stored, err)
+	}
+
+	// an expired key is reserved again
+	if _, err := store.Begin("expired", "request", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("error reserving the key: %s", err)
+	}
+	if stored, err := store.Begin("expired", "other request", expiresAt); err != nil || stored != nil {
+		t.Errorf("expected an expired key to be reserved again, got %v %v", stored, err)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	_, url := newTestServer(t)
+
+	create := func(body, authorization string) *http.Response {
+		response, _ := testRequest(t, http.MethodPost, url+"/api/v1/processes", body,
+			HeaderIdempotencyKey, "same-key", HeaderAuthorization, authorization)
+		return response
+	}
+
+	body := `{"id_process": "job", "type": "cron", "name": "job"}`
+	if response := create(body, "Bearer first"); response.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the process to be created, got %d", response.StatusCode)
+	}
+	if response := create(body, "Bearer first"); response.StatusCode != http.StatusCreated || response.Header.Get(HeaderIdempotentReplayed) != "true" {
+		t.Errorf("expected the response to be replayed to the same caller, got %d", response.StatusCode)
+	}
+	if response := create(`{"id_process": "other", "type": "cron", "name": "other"}`, "Bearer first"); response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected the key to be refused for another request, got %d", response.StatusCode)
+	}
+
+	// the keys are scoped by the caller, so the request of another caller is executed
+	if response := create(body, "Bearer second"); response.StatusCode == http.StatusCreated || response.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Errorf("expected the request of another caller to be executed, got %d", response.StatusCode)
+	}
+}
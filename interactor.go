@@ -1,7 +1,10 @@
 package monitor
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"github.com/joaosoft/logger"
+	"sort"
 	"time"
 
 	"strings"
@@ -15,20 +18,43 @@ type IStorageDB interface {
 	GetProcesses(values map[string][]string) (ListProcess, error)
 	CreateProcess(newProcess *Process) error
 	UpdateProcess(updProcess *Process) error
-	UpdateProcessStatus(idProcess string, status Status) error
-	DeleteProcess(idProcess string) error
-	DeleteProcesses() error
+	UpdateProcessStatus(idProcess string, status Status, message string, version int64) error
+	HeartbeatProcess(idProcess string) (bool, error)
+	ExpireProcess(idProcess string, heartbeatBefore time.Time) (bool, error)
+	DeleteProcess(idProcess string, version int64) error
+	DeleteProcesses(values map[string][]string) (int64, error)
+	RestoreProcess(idProcess string) (bool, error)
+	PurgeProcesses(deletedBefore time.Time) (int64, error)
+	GetProcessHistory(idProcess string) (ListProcessHistory, error)
+	Transaction(handler func(storage IStorageDB) error) error
 }
 
 type Interactor struct {
 	storageDB IStorageDB
+	metrics   *metrics
 	logger    logger.ILogger
+	lease     time.Duration
+	clock     Clock
 }
 
 func (monitor *Monitor) NewInteractor(storageDB IStorageDB) *Interactor {
 	return &Interactor{
 		storageDB: storageDB,
+		metrics:   monitor.metrics,
 		logger:    monitor.logger,
+		lease:     monitor.lease,
+		clock:     monitor.clock,
+	}
+}
+
+// withStorage returns an interactor using another storage, like one bound to a transaction
+func (interactor *Interactor) withStorage(storageDB IStorageDB) *Interactor {
+	return &Interactor{
+		storageDB: storageDB,
+		metrics:   interactor.metrics,
+		logger:    interactor.logger,
+		lease:     interactor.lease,
+		clock:     interactor.clock,
 	}
 }
 
@@ -36,7 +62,7 @@ func (interactor *Interactor) GetProcesses(values map[string][]string) (ListProc
 	interactor.logger.WithFields(map[string]interface{}{"method": "GetProcesses"})
 	interactor.logger.Info("getting processes")
 	if categories, err := interactor.storageDB.GetProcesses(values); err != nil {
-		err = interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error getting processes on storage database %s", err).ToError()
 		return nil, err
 	} else {
@@ -56,11 +82,25 @@ func (interactor *Interactor) GetProcess(idProcess string) (*Process, error) {
 	}
 }
 
+// CreateProcess creates the process, or replaces a deleted process with the same id that wasn't purged yet,
+// restoring it with the new fields and keeping its history
 func (interactor *Interactor) CreateProcess(newProcess *Process) error {
 	interactor.logger.WithFields(map[string]interface{}{"method": "CreateProcess"})
 
 	interactor.logger.Infof("creating process with id %s", newProcess.IdProcess)
-	if err := interactor.storageDB.CreateProcess(newProcess); err != nil {
+	err := interactor.storageDB.Transaction(func(storage IStorageDB) error {
+		if restored, err := storage.RestoreProcess(newProcess.IdProcess); err != nil {
+			return err
+		} else if !restored {
+			return storage.CreateProcess(newProcess)
+		}
+
+		replaced := *newProcess
+		replaced.Version = 0
+		return storage.UpdateProcess(&replaced)
+	})
+
+	if err != nil {
 		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error creating process %s on storage database %s", newProcess.IdProcess, err).ToError()
 		return err
@@ -73,6 +113,10 @@ func (interactor *Interactor) UpdateProcess(updProcess *Process) error {
 	interactor.logger.WithFields(map[string]interface{}{"method": "UpdateProcess"})
 	interactor.logger.Infof("updating process %s", updProcess.IdProcess)
 	if err := interactor.storageDB.UpdateProcess(updProcess); err != nil {
+		if err == ErrorVersionMismatch {
+			return interactor.versionMismatch(updProcess.IdProcess)
+		}
+
 		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error updating process %s on storage database %s", updProcess.IdProcess, err).ToError()
 		return err
@@ -81,39 +125,128 @@ func (interactor *Interactor) UpdateProcess(updProcess *Process) error {
 	}
 }
 
-func (interactor *Interactor) UpdateProcessStatus(idProcess string, status Status) errors.ErrorList {
+// UpdateProcessStatus changes the status of the process, with a message like the error of a failed process
+func (interactor *Interactor) UpdateProcessStatus(idProcess string, status Status, message string, version int64) errors.ErrorList {
 	interactor.logger.WithFields(map[string]interface{}{"method": "UpdateProcessStatus"})
 	interactor.logger.Infof("updating process %s to status %s", idProcess, status)
 
-	if canExecuite, errs := interactor.CanExecute(idProcess); canExecuite {
+	// the check and the update are on the same transaction, and the storages only start a process that
+	// isn't running, so of concurrent starts only one succeeds, the others fail as already running
+	var process *Process
+	var errs errors.ErrorList
+	err := interactor.storageDB.Transaction(func(storage IStorageDB) error {
+		transaction := interactor.withStorage(storage)
+		if status == StatusRunning {
+			if err := transaction.expireProcess(idProcess); err != nil {
+				return err
+			}
+		}
 
-		if err := interactor.storageDB.UpdateProcessStatus(idProcess, status); err != nil {
-			err = interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
-				Errorf("error updating process %s to status %s on storage database %s", idProcess, status, err).ToError()
-			return errors.ErrorList{errors.New(errors.LevelError, 0, err)}
-		} else {
+		if process, errs = transaction.canExecute(idProcess, status); !errs.IsEmpty() {
 			return nil
 		}
-	} else {
+
+		err := storage.UpdateProcessStatus(idProcess, status, message, version)
+		if err == ErrorVersionMismatch {
+			err = transaction.versionMismatch(idProcess)
+		}
+		if err == ErrorVersionMismatch || err == ErrorProcessNotFound || err == ErrorAlreadyRunning {
+			errs = errors.ErrorList{err.(*errors.Error)}
+			return nil
+		}
+		return err
+	})
+
+	if !errs.IsEmpty() {
+		if status == StatusRunning {
+			interactor.metrics.refusedStart(errs[0])
+		}
 		return errs
 	}
+
+	if err != nil {
+		err = interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error updating process %s to status %s on storage database %s", idProcess, status, err).ToError()
+		return errors.ErrorList{errors.New(errors.LevelError, ErrorCodeInternal, err)}
+	}
+
+	interactor.metrics.transition(process, status, interactor.clock.Now())
+	return nil
 }
 
 func (interactor *Interactor) UpdateProcessStatusCheck(idProcess string, status Status) errors.ErrorList {
 	interactor.logger.WithFields(map[string]interface{}{"method": "UpdateProcessStatusCheck"})
 	interactor.logger.Infof("check updating process %s to status %s", idProcess, status)
 
-	if canExecuite, errs := interactor.CanExecute(idProcess); canExecuite {
+	if canExecuite, errs := interactor.CanExecute(idProcess, status); canExecuite {
 		return nil
 	} else {
 		return errs
 	}
 }
 
-func (interactor *Interactor) DeleteProcess(idProcess string) error {
+// HeartbeatProcess records that a running process is alive,
+// returns ErrorLeaseLost when the process isn't running anymore
+func (interactor *Interactor) HeartbeatProcess(idProcess string) error {
+	interactor.logger.WithFields(map[string]interface{}{"method": "HeartbeatProcess"})
+	interactor.logger.Debugf("heartbeat of process %s", idProcess)
+
+	alive, err := interactor.storageDB.HeartbeatProcess(idProcess)
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error recording heartbeat of process %s on storage database %s", idProcess, err).ToError()
+		return err
+	}
+
+	if alive {
+		return nil
+	}
+
+	process, err := interactor.storageDB.GetProcess(idProcess)
+	if err != nil {
+		return err
+	}
+
+	if process == nil {
+		return ErrorProcessNotFound
+	}
+
+	return ErrorLeaseLost
+}
+
+// expireProcess sets a running process as failed when its lease expired, because no heartbeat was received
+// for longer than the lease, so it can be started again. The lease starts with the first heartbeat
+func (interactor *Interactor) expireProcess(idProcess string) error {
+	if interactor.lease <= 0 {
+		return nil
+	}
+
+	expired, err := interactor.storageDB.ExpireProcess(idProcess, interactor.clock.Now().Add(-interactor.lease))
+	if err != nil {
+		return interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error expiring the lease of process %s on storage database %s", idProcess, err).ToError()
+	}
+
+	if expired {
+		interactor.logger.Warnf("the lease of process %s expired, it was set as %s", idProcess, StatusFailed)
+	}
+
+	return nil
+}
+
+// leaseExpired checks if the process didn't send a heartbeat for longer than the lease
+func (interactor *Interactor) leaseExpired(process *Process) bool {
+	return interactor.lease > 0 && process.HeartbeatAt != nil && process.HeartbeatAt.Before(interactor.clock.Now().Add(-interactor.lease))
+}
+
+func (interactor *Interactor) DeleteProcess(idProcess string, version int64) error {
 	interactor.logger.WithFields(map[string]interface{}{"method": "DeleteProcess"})
 	interactor.logger.Infof("deleting process %s", idProcess)
-	if err := interactor.storageDB.DeleteProcess(idProcess); err != nil {
+	if err := interactor.storageDB.DeleteProcess(idProcess, version); err != nil {
+		if err == ErrorVersionMismatch {
+			return interactor.versionMismatch(idProcess)
+		}
+
 		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error deleting process %s on storage database %s", idProcess, err).ToError()
 		return err
@@ -121,45 +254,536 @@ func (interactor *Interactor) DeleteProcess(idProcess string) error {
 	return nil
 }
 
-func (interactor *Interactor) DeleteProcesses() error {
+func (interactor *Interactor) DeleteProcesses(values map[string][]string, confirmation string) (*DeleteProcessesResponse, error) {
 	interactor.logger.WithFields(map[string]interface{}{"method": "DeleteProcesses"})
 	interactor.logger.Info("deleting processes")
-	if err := interactor.storageDB.DeleteProcesses(); err != nil {
+
+	if len(values) == 0 {
+		return nil, ErrorFilterRequired
+	}
+
+	processes, err := interactor.storageDB.GetProcesses(values)
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error getting processes to delete on storage database %s", err).ToError()
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(processes))
+	for _, process := range processes {
+		ids = append(ids, process.IdProcess)
+	}
+
+	response := &DeleteProcessesResponse{Count: int64(len(ids))}
+	if len(ids) == 0 {
+		return response, nil
+	}
+
+	response.Confirmation = deleteConfirmation(ids)
+
+	if confirmation != response.Confirmation {
+		return response, ErrorConfirmationRequired
+	}
+
+	// only the processes that were confirmed are deleted
+	deleted, err := interactor.storageDB.DeleteProcesses(map[string][]string{"id_process": ids})
+	if err != nil {
 		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error deleting processes on storage database %s", err).ToError()
+		return nil, err
+	}
+
+	return &DeleteProcessesResponse{Count: deleted}, nil
+}
+
+func (interactor *Interactor) RestoreProcess(idProcess string) (bool, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "RestoreProcess"})
+	interactor.logger.Infof("restoring process %s", idProcess)
+	restored, err := interactor.storageDB.RestoreProcess(idProcess)
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error restoring process %s on storage database %s", idProcess, err).ToError()
+		return false, err
+	}
+	return restored, nil
+}
+
+func (interactor *Interactor) PurgeProcesses(retention time.Duration) (int64, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "PurgeProcesses"})
+	interactor.logger.Infof("purging processes deleted more than %s ago", retention)
+	purged, err := interactor.storageDB.PurgeProcesses(interactor.clock.Now().Add(-retention))
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error purging processes on storage database %s", err).ToError()
+		return 0, err
+	}
+	return purged, nil
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (interactor *Interactor) GetProcessHistory(idProcess string) (ListProcessHistory, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "GetProcessHistory"})
+	interactor.logger.Infof("getting history of process %s", idProcess)
+
+	if process, err := interactor.GetProcess(idProcess); err != nil {
+		return nil, err
+	} else if process == nil {
+		return nil, ErrorProcessNotFound
+	}
+
+	history, err := interactor.storageDB.GetProcessHistory(idProcess)
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error getting history of process %s on storage database %s", idProcess, err).ToError()
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetProcessRuns returns the runs of the process, found on its history, the last first
+func (interactor *Interactor) GetProcessRuns(idProcess string) (ListProcessRun, error) {
+	history, err := interactor.GetProcessHistory(idProcess)
+	if err != nil {
+		return nil, err
+	}
+
+	return processRuns(history), nil
+}
+
+// processRuns returns the runs of the history, started when the status changes to running and
+// ended when it changes to stopped or failed. The run still running hasn't an end
+func processRuns(history ListProcessHistory) ListProcessRun {
+	runs := make(ListProcessRun, 0)
+	var run *ProcessRun
+
+	for _, change := range history {
+		if change.Status == nil {
+			continue
+		}
+
+		switch {
+		case *change.Status == StatusRunning && run == nil:
+			run = &ProcessRun{StartedAt: change.OperationAt, Status: StatusRunning, Message: change.Message}
+			runs = append(ListProcessRun{run}, runs...)
+		case *change.Status != StatusRunning && run != nil:
+			endedAt := change.OperationAt
+			run.EndedAt, run.Status, run.Message = &endedAt, *change.Status, change.Message
+			run = nil
+		}
+	}
+
+	return runs
+}
+
+// GetStatusPage returns the public status of the processes of each group of the page, with their uptime on its period
+func (interactor *Interactor) GetStatusPage(page *StatusPage) (*StatusPageResponse, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "GetStatusPage"})
+	interactor.logger.Info("getting status page")
+
+	now := interactor.clock.Now()
+	response := &StatusPageResponse{
+		Title:       page.title,
+		Groups:      make([]*StatusPageGroupResponse, 0, len(page.groups)),
+		UptimeSince: now.Add(-page.uptime),
+		UpdatedAt:   now,
+	}
+
+	for _, group := range page.groups {
+		processes, err := interactor.GetProcesses(group.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		groupResponse := &StatusPageGroupResponse{Name: group.Name, Processes: make([]*ProcessStatus, 0, len(processes))}
+		for _, process := range processes {
+			status, err := interactor.processStatus(process, response.UptimeSince, now)
+			if err != nil {
+				return nil, err
+			}
+			groupResponse.Processes = append(groupResponse.Processes, status)
+		}
+		response.Groups = append(response.Groups, groupResponse)
+	}
+
+	return response, nil
+}
+
+// GetProcessStatus returns the public status of the process, with its uptime on the period
+func (interactor *Interactor) GetProcessStatus(idProcess string, uptime time.Duration) (*ProcessStatus, error) {
+	process, err := interactor.GetProcess(idProcess)
+	if err != nil {
+		return nil, err
+	} else if process == nil {
+		return nil, ErrorProcessNotFound
+	}
+
+	now := interactor.clock.Now()
+	return interactor.processStatus(process, now.Add(-uptime), now)
+}
+
+func (interactor *Interactor) processStatus(process *Process, since, now time.Time) (*ProcessStatus, error) {
+	history, err := interactor.storageDB.GetProcessHistory(process.IdProcess)
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error getting history of process %s on storage database %s", process.IdProcess, err).ToError()
+		return nil, err
+	}
+
+	return processStatus(process, history, since, now), nil
+}
+
+// ScheduleProcesses sets if the processes can be started now and their next window, open now or the next to open.
+// The window is searched on the next year, the end isn't set when the window doesn't close on it
+func (interactor *Interactor) ScheduleProcesses(processes ...*Process) {
+	now := interactor.clock.Now()
+
+	for _, process := range processes {
+		running := process.Status != nil && *process.Status == StatusRunning && !interactor.leaseExpired(process)
+		canExecute := !running && len(windowErrors(process, now)) == 0
+		process.CanExecuteNow = &canExecute
+		process.NextWindowStart, process.NextWindowEnd = nextWindow(process.window(), now, MaxSimulationRange)
+	}
+}
+
+// SimulateProcess returns the intervals of the range where the process could be started, and the ones where it couldn't
+// with the reasons. The window is the one of the process, with the fields set on the overrides replaced, or only the
+// overrides without a process. The range is evaluated on the location of the clock, like when starting the process
+func (interactor *Interactor) SimulateProcess(idProcess string, overrides *ProcessWindow, from, to time.Time) (*SimulateProcessResponse, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "SimulateProcess"})
+	interactor.logger.Infof("simulating process %s from %s to %s", idProcess, from, to)
+
+	window := &ProcessWindow{}
+	if idProcess != "" {
+		process, err := interactor.GetProcess(idProcess)
+		if err != nil {
+			return nil, err
+		}
+
+		if process == nil {
+			return nil, ErrorProcessNotFound
+		}
+
+		window = process.window()
+	}
+	window = window.override(overrides)
+
+	location := interactor.clock.Now().Location()
+	response := &SimulateProcessResponse{
+		IdProcess: idProcess,
+		Process:   window,
+		From:      from.In(location),
+		To:        to.In(location),
+	}
+	response.Allowed, response.Blocked = windowIntervals(window, response.From, response.To)
+
+	return response, nil
+}
+
+// Batch executes the operations without an error, setting the error of each operation that fails.
+// On atomic mode the operations are executed on a single transaction and
+// when one fails the others are set with ErrorBatchAborted
+func (interactor *Interactor) Batch(mode BatchMode, operations []*BatchOperation) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "Batch"})
+	interactor.logger.Infof("executing batch of %d operations on %s mode", len(operations), mode)
+
+	if mode != BatchModeAtomic {
+		for _, operation := range operations {
+			if operation.Error == nil {
+				operation.Error = interactor.executeOperation(operation)
+			}
+		}
+		return
+	}
+
+	for _, operation := range operations {
+		if operation.Error != nil {
+			abortOperations(operations)
+			return
+		}
+	}
+
+	err := interactor.storageDB.Transaction(func(storage IStorageDB) error {
+		tx := interactor.withStorage(storage)
+		for _, operation := range operations {
+			if operation.Error = tx.executeOperation(operation); operation.Error != nil {
+				return operation.Error
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error executing batch, rolled back %s", err).ToError()
+
+		failed := false
+		for _, operation := range operations {
+			failed = failed || operation.Error != nil
+		}
+
+		if !failed {
+			// the transaction itself failed
+			for _, operation := range operations {
+				operation.Error = err
+			}
+			return
+		}
+
+		abortOperations(operations)
+	}
+}
+
+// ApplyProcesses creates, updates and, when pruning, deletes the processes to match the definitions,
+// on a single transaction. On dry run the changes are only planned
+func (interactor *Interactor) ApplyProcesses(definitions []*ProcessDefinition, prune bool, dryRun bool) (*ApplyProcessesResponse, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "ApplyProcesses"})
+	interactor.logger.Infof("applying %d process definitions, prune %t, dry run %t", len(definitions), prune, dryRun)
+
+	response := &ApplyProcessesResponse{DryRun: dryRun}
+	err := interactor.storageDB.Transaction(func(storage IStorageDB) error {
+		processes, err := storage.GetProcesses(nil)
+		if err != nil {
+			return err
+		}
+
+		response.Changes = planProcesses(processes, definitions, prune)
+		if dryRun {
+			return nil
+		}
+
+		tx := interactor.withStorage(storage)
+		for _, change := range response.Changes {
+			if err := tx.applyChange(change); err != nil {
+				return changeError(change, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error applying process definitions, rolled back %s", err).ToError()
+		return nil, err
+	}
+
+	for _, change := range response.Changes {
+		switch change.Op {
+		case ChangeCreate:
+			response.Created++
+		case ChangeUpdate:
+			response.Updated++
+		case ChangeDelete:
+			response.Deleted++
+		}
+	}
+
+	return response, nil
+}
+
+// ExportProcesses returns the definitions of the filtered processes
+func (interactor *Interactor) ExportProcesses(values map[string][]string) ([]*ProcessDefinition, error) {
+	processes, err := interactor.GetProcesses(values)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make([]*ProcessDefinition, 0, len(processes))
+	for _, process := range processes {
+		definitions = append(definitions, process.definition())
+	}
+
+	return definitions, nil
+}
+
+// ImportProcesses creates the processes of the definitions on a single transaction, the existing ones are
+// skipped, overwritten (keeping their status) or fail the import, by the policy. On dry run the changes are only planned
+func (interactor *Interactor) ImportProcesses(definitions []*ProcessDefinition, onConflict ConflictPolicy, dryRun bool) (*ImportProcessesResponse, error) {
+	interactor.logger.WithFields(map[string]interface{}{"method": "ImportProcesses"})
+	interactor.logger.Infof("importing %d processes, on conflict %s, dry run %t", len(definitions), onConflict, dryRun)
+
+	response := &ImportProcessesResponse{OnConflict: onConflict, DryRun: dryRun, Skipped: make([]string, 0)}
+	err := interactor.storageDB.Transaction(func(storage IStorageDB) error {
+		processes, err := storage.GetProcesses(nil)
+		if err != nil {
+			return err
+		}
+
+		existing := make(map[string]bool, len(processes))
+		for _, process := range processes {
+			existing[process.IdProcess] = true
+		}
+
+		imported := make([]*ProcessDefinition, 0, len(definitions))
+		conflicts := make([]string, 0)
+		for _, definition := range definitions {
+			if existing[definition.IdProcess] && onConflict != ConflictOverwrite {
+				conflicts = append(conflicts, definition.IdProcess)
+				continue
+			}
+			imported = append(imported, definition)
+		}
+
+		if len(conflicts) > 0 && onConflict == ConflictFail {
+			return errors.New(errors.LevelError, ErrorCodeProcessAlreadyExists, "the processes %s already exist, import them with the policy skip or overwrite", strings.Join(conflicts, ", "))
+		}
+		response.Skipped = conflicts
+
+		response.Changes = planProcesses(processes, imported, false)
+		if dryRun {
+			return nil
+		}
+
+		tx := interactor.withStorage(storage)
+		for _, change := range response.Changes {
+			if err := tx.applyChange(change); err != nil {
+				return changeError(change, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
+			Errorf("error importing processes, rolled back %s", err).ToError()
+		return nil, err
+	}
+
+	for _, change := range response.Changes {
+		if change.Op == ChangeCreate {
+			response.Created++
+		} else {
+			response.Updated++
+		}
+	}
+
+	return response, nil
+}
+
+func (interactor *Interactor) executeOperation(operation *BatchOperation) error {
+	switch operation.Op {
+	case BatchOperationCreate:
+		return interactor.CreateProcess(operation.Process)
+	case BatchOperationUpdate:
+		return interactor.UpdateProcess(operation.Process)
+	case BatchOperationStatus:
+		if errs := interactor.UpdateProcessStatus(operation.Process.IdProcess, *operation.Process.Status, operation.Process.Message, operation.Process.Version); errs != nil {
+			return errorListError(errs)
+		}
+		return nil
+	default:
+		return errors.New(errors.LevelError, ErrorCodeValidationFailed, "invalid operation %s", operation.Op)
+	}
+}
+
+// abortOperations sets the operations without an error as aborted
+func abortOperations(operations []*BatchOperation) {
+	for _, operation := range operations {
+		if operation.Error == nil {
+			operation.Error = ErrorBatchAborted
+		}
+	}
+}
+
+// errorListError joins the errors on a single error with the code of the first error
+func errorListError(errs errors.ErrorList) error {
+	if errs.IsEmpty() {
+		return nil
+	}
+
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+
+	return errors.New(errors.LevelError, errs[0].Code, strings.Join(messages, "; "))
+}
+
+// versionMismatch tells apart a stale version from a process that doesn't exist
+func (interactor *Interactor) versionMismatch(idProcess string) error {
+	process, err := interactor.storageDB.GetProcess(idProcess)
+	if err != nil {
 		return err
 	}
-	return nil
+
+	if process == nil {
+		return ErrorProcessNotFound
+	}
+
+	return ErrorVersionMismatch
 }
 
-func (interactor *Interactor) CanExecute(idProcess string) (bool, errors.ErrorList) {
+// CanExecute checks if the process can change to the status,
+// a process can only be started once and inside of its window
+func (interactor *Interactor) CanExecute(idProcess string, status Status) (bool, errors.ErrorList) {
+	_, errs := interactor.canExecute(idProcess, status)
+	return errs.IsEmpty(), errs
+}
+
+// canExecute returns the process with the errors when it can't change to the status
+func (interactor *Interactor) canExecute(idProcess string, status Status) (*Process, errors.ErrorList) {
 	var errs errors.ErrorList
 	process, err := interactor.GetProcess(idProcess)
 	if err != nil {
 		err = interactor.logger.WithFields(map[string]interface{}{"error": err.Error()}).
 			Errorf("error getting process %s on storage database %s", idProcess, err).ToError()
-		return false, errors.ErrorList{errors.New(errors.LevelError, 0, err)}
+		return nil, errors.ErrorList{errors.New(errors.LevelError, ErrorCodeInternal, err)}
 	}
 
-	now := time.Now()
-	if process.Status != nil && *process.Status == StatusRunning {
-		errors.New(errors.LevelError, 0, "the process is already running!")
+	if process == nil {
+		return nil, errors.ErrorList{ErrorProcessNotFound}
 	}
+
+	if status != StatusRunning {
+		return process, nil
+	}
+
+	// a running process whose lease expired is lost, so it can be started again
+	if process.Status != nil && *process.Status == StatusRunning && !interactor.leaseExpired(process) {
+		errs.Add(ErrorAlreadyRunning)
+	}
+	errs = append(errs, windowErrors(process, interactor.clock.Now())...)
+
+	return process, errs
+}
+
+// windowErrors returns the errors of the rules of the window of the process that don't allow it to start at the time
+func windowErrors(process *Process, now time.Time) errors.ErrorList {
+	var errs errors.ErrorList
 	if process.DaysOff != nil && process.DaysOff.Contains(types.Day(strings.ToLower(now.Weekday().String()))) {
-		errors.New(errors.LevelError, 0, "the process cannot the executed on %+v!", process.DaysOff)
+		errs.Add(errors.New(errors.LevelError, ErrorCodeWindowClosed, "the process cannot the executed on %+v!", *process.DaysOff))
 	}
-	if process.DateFrom != nil && now.Format("02-01-2006") < string(*process.DateFrom) {
-		errors.New(errors.LevelError, 0, "the process can just be started after %s", *process.DateFrom)
+	if process.DateFrom != nil && now.Format(sortableDateLayout) < sortableDate(*process.DateFrom) {
+		errs.Add(errors.New(errors.LevelError, ErrorCodeWindowClosed, "the process can just be started after %s", *process.DateFrom))
 	}
-	if process.DateTo != nil && now.Format("02-01-2006") > string(*process.DateTo) {
-		errors.New(errors.LevelError, 0, "the process could just be started before %s", *process.DateTo)
+	if process.DateTo != nil && now.Format(sortableDateLayout) > sortableDate(*process.DateTo) {
+		errs.Add(errors.New(errors.LevelError, ErrorCodeWindowClosed, "the process could just be started before %s", *process.DateTo))
 	}
-	if process.TimeFrom != nil && now.Format("15:04:05") < string(*process.TimeFrom) {
-		errors.New(errors.LevelError, 0, "the process can just be started after %s", *process.TimeFrom)
+	if process.TimeFrom != nil && now.Format(timeLayout) < string(*process.TimeFrom) {
+		errs.Add(errors.New(errors.LevelError, ErrorCodeWindowClosed, "the process can just be started after %s", *process.TimeFrom))
 	}
-	if process.TimeTo != nil && now.Format("15:04:05") > string(*process.TimeTo) {
-		errors.New(errors.LevelError, 0, "the process could just be started before %s", *process.TimeTo)
+	if process.TimeTo != nil && now.Format(timeLayout) > string(*process.TimeTo) {
+		errs.Add(errors.New(errors.LevelError, ErrorCodeWindowClosed, "the process could just be started before %s", *process.TimeTo))
 	}
 
-	return errs.IsEmpty(), errs
+	return errs
+}
+
+// sortableDate converts a date to a format that can be compared as a string
+func sortableDate(date types.Date) string {
+	parsed, err := time.Parse(dateLayout, string(date))
+	if err != nil {
+		return string(date)
+	}
+
+	return parsed.Format(sortableDateLayout)
+}
+
+// deleteConfirmation returns a token bound to the exact set of processes that will be deleted,
+// so a confirmation becomes invalid as soon as the selection changes
+func deleteConfirmation(ids []string) string {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+
+	hash := sha1.Sum([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(hash[:])
 }
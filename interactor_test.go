@@ -0,0 +1,249 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+)
+
+func TestCanExecute(t *testing.T) {
+	// friday, 15 of march of 2024
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	running, stopped := StatusRunning, StatusStopped
+
+	tests := []struct {
+		name    string
+		process Process
+		status  Status
+		now     time.Time
+		errors  int
+	}{
+		{name: "without window", process: Process{}, status: StatusRunning, now: now},
+		{name: "stopping is always allowed", process: Process{Status: &running, DaysOff: testDays("friday"), DateTo: testDate("01-01-2024")}, status: StatusStopped, now: now},
+		{name: "already running", process: Process{Status: &running}, status: StatusRunning, now: now, errors: 1},
+		{name: "stopped", process: Process{Status: &stopped}, status: StatusRunning, now: now},
+		{name: "day off", process: Process{DaysOff: testDays("friday")}, status: StatusRunning, now: now, errors: 1},
+		{name: "other days off", process: Process{DaysOff: testDays("saturday", "sunday")}, status: StatusRunning, now: now},
+		{name: "day off on the next day", process: Process{DaysOff: testDays("saturday")}, status: StatusRunning, now: now.Add(14 * time.Hour), errors: 1},
+		{name: "before the date from", process: Process{DateFrom: testDate("16-03-2024")}, status: StatusRunning, now: now, errors: 1},
+		{name: "on the date from", process: Process{DateFrom: testDate("15-03-2024")}, status: StatusRunning, now: now},
+		{name: "date from on another year", process: Process{DateFrom: testDate("16-03-2023")}, status: StatusRunning, now: now},
+		{name: "after the date to", process: Process{DateTo: testDate("14-03-2024")}, status: StatusRunning, now: now, errors: 1},
+		{name: "on the date to", process: Process{DateTo: testDate("15-03-2024")}, status: StatusRunning, now: now},
+		{name: "date to on another month", process: Process{DateTo: testDate("01-04-2024")}, status: StatusRunning, now: now},
+		{name: "inside of the dates", process: Process{DateFrom: testDate("01-03-2024"), DateTo: testDate("31-03-2024")}, status: StatusRunning, now: now},
+		{name: "before the time from", process: Process{TimeFrom: testTime("10:30:01")}, status: StatusRunning, now: now, errors: 1},
+		{name: "on the time from", process: Process{TimeFrom: testTime("10:30:00")}, status: StatusRunning, now: now},
+		{name: "after the time to", process: Process{TimeTo: testTime("10:29:59")}, status: StatusRunning, now: now, errors: 1},
+		{name: "on the time to", process: Process{TimeTo: testTime("10:30:00")}, status: StatusRunning, now: now},
+		{name: "inside of the times", process: Process{TimeFrom: testTime("08:00:00"), TimeTo: testTime("18:00:00")}, status: StatusRunning, now: now},
+		{name: "at midnight", process: Process{TimeFrom: testTime("00:00:00"), TimeTo: testTime("00:00:00")}, status: StatusRunning, now: time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{name: "the time of the clock location", process: Process{TimeTo: testTime("10:00:00")}, status: StatusRunning, now: now.In(time.FixedZone("WET", -3600))},
+		{name: "every rule closed", process: Process{Status: &running, DaysOff: testDays("friday"), DateFrom: testDate("16-03-2024"), TimeTo: testTime("09:00:00")}, status: StatusRunning, now: now, errors: 4},
+	}
+
+	fake := NewFakeClock(now)
+	storage := NewStorageMemory(fake)
+	interactor := newTestInteractor(t, storage, fake)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			process := test.process
+			process.IdProcess, process.Type, process.Name = "job", "cron", "job"
+			storage.DeleteProcesses(nil)
+			storage.PurgeProcesses(time.Now().Add(time.Hour))
+			if err := storage.CreateProcess(&process); err != nil {
+				t.Fatalf("error creating process: %s", err)
+			}
+			if process.Status != nil {
+				storage.UpdateProcessStatus("job", *process.Status, "", 0)
+			}
+
+			fake.Set(test.now)
+			canExecute, errs := interactor.CanExecute("job", test.status)
+			if canExecute != (test.errors == 0) || len(errs) != test.errors {
+				t.Errorf("expected %d errors, got %t %s", test.errors, canExecute, errs.String())
+			}
+		})
+	}
+
+	if canExecute, errs := interactor.CanExecute("missing", StatusRunning); canExecute || len(errs) != 1 || errs[0] != ErrorProcessNotFound {
+		t.Errorf("expected the process to not be found, got %t %s", canExecute, errs.String())
+	}
+}
+
+func TestPurgeProcessesClock(t *testing.T) {
+	fake := NewFakeClock(time.Now())
+	storage := NewStorageMemory(fake)
+	interactor := newTestInteractor(t, storage, fake)
+
+	storage.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"})
+	storage.DeleteProcess("job", 0)
+
+	if purged, _ := interactor.PurgeProcesses(24 * time.Hour); purged != 0 {
+		t.Fatalf("expected the process to be kept, got %d purged", purged)
+	}
+
+	fake.Advance(25 * time.Hour)
+	if purged, _ := interactor.PurgeProcesses(24 * time.Hour); purged != 1 {
+		t.Fatalf("expected the process to be purged, got %d purged", purged)
+	}
+}
+
+func TestPurgerClock(t *testing.T) {
+	fake := NewFakeClock(time.Now())
+	storage := NewStorageMemory(fake)
+	interactor := newTestInteractor(t, storage, fake)
+	purger, err := (&Monitor{logger: interactor.logger, clock: fake}).NewPurger(interactor)
+	if err != nil {
+		t.Fatalf("error creating the purger: %s", err)
+	}
+
+	storage.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"})
+	storage.DeleteProcess("job", 0)
+
+	purger.Start()
+	defer purger.Stop()
+
+	// the purges are done when the clock reaches the interval, then the purger waits for the next one
+	waitTimers(t, fake)
+	fake.Advance(DefaultPurgeRetention)
+	waitTimers(t, fake)
+	if history, _ := storage.GetProcessHistory("job"); len(history) != 2 {
+		t.Fatalf("expected the process to be kept before the retention, got %d changes", len(history))
+	}
+
+	fake.Advance(DefaultPurgeInterval)
+	waitTimers(t, fake)
+	if restored, err := storage.RestoreProcess("job"); err != nil || restored {
+		t.Errorf("expected the process to be purged, got %v %v", restored, err)
+	}
+}
+
+// waitTimers waits for a timer of the clock, like the one of a background task that is waiting for the next run
+func waitTimers(t *testing.T, clock *FakeClock) {
+	for deadline := time.Now().Add(time.Second); clock.Timers() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a timer of the clock")
+		}
+	}
+}
+
+func TestCreateProcessReplacesDeleted(t *testing.T) {
+	storage := NewStorageMemory(nil)
+	interactor := newTestInteractor(t, storage, systemClock{})
+
+	if err := interactor.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if err := interactor.DeleteProcess("job", 0); err != nil {
+		t.Fatalf("error deleting process: %s", err)
+	}
+
+	if err := interactor.CreateProcess(&Process{IdProcess: "job", Type: "daemon", Name: "new job"}); err != nil {
+		t.Fatalf("error creating the deleted process: %s", err)
+	}
+	if err := interactor.CreateProcess(&Process{IdProcess: "job", Type: "daemon", Name: "new job"}); err != ErrorProcessAlreadyExists {
+		t.Errorf("expected the process to already exist, got %v", err)
+	}
+
+	process, err := interactor.GetProcess("job")
+	if err != nil || process == nil {
+		t.Fatalf("expected the process, got %v %v", process, err)
+	}
+	if process.Type != "daemon" || process.Name != "new job" || process.Version != 4 {
+		t.Errorf("expected the process to be replaced on the version 4, got %+v", process)
+	}
+}
+
+func TestHeartbeatLease(t *testing.T) {
+	fake := NewFakeClock(time.Now())
+	interactor := newTestInteractor(t, NewStorageMemory(fake), fake)
+
+	if err := interactor.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if errs := interactor.UpdateProcessStatus("job", StatusRunning, "", 0); errs != nil {
+		t.Fatalf("error starting process: %s", errs)
+	}
+
+	// the lease starts with the first heartbeat
+	fake.Advance(2 * interactor.lease)
+	if errs := interactor.UpdateProcessStatus("job", StatusRunning, "", 0); len(errs) != 1 || errs[0].Code != ErrorCodeAlreadyRunning {
+		t.Errorf("expected a process without heartbeats to keep running, got %s", errs)
+	}
+
+	if err := interactor.HeartbeatProcess("job"); err != nil {
+		t.Fatalf("error sending heartbeat: %s", err)
+	}
+	fake.Advance(interactor.lease / 2)
+	if errs := interactor.UpdateProcessStatusCheck("job", StatusRunning); len(errs) != 1 || errs[0].Code != ErrorCodeAlreadyRunning {
+		t.Errorf("expected the process to be running inside of its lease, got %s", errs)
+	}
+
+	fake.Advance(interactor.lease)
+	if errs := interactor.UpdateProcessStatusCheck("job", StatusRunning); errs != nil {
+		t.Errorf("expected the process with an expired lease to be able to start, got %s", errs)
+	}
+	if errs := interactor.UpdateProcessStatus("job", StatusRunning, "", 0); errs != nil {
+		t.Fatalf("expected the process with an expired lease to start, got %s", errs)
+	}
+
+	process, err := interactor.GetProcess("job")
+	if err != nil {
+		t.Fatalf("error getting process: %s", err)
+	}
+	if *process.Status != StatusRunning || process.HeartbeatAt != nil {
+		t.Errorf("expected the process running without heartbeats, got %s %v", *process.Status, process.HeartbeatAt)
+	}
+
+	// the expired lease is on the history, as the process failed before starting again
+	history, _ := interactor.GetProcessHistory("job")
+	if expired := history[len(history)-2]; *expired.Status != StatusFailed || expired.Message != MessageLeaseExpired {
+		t.Errorf("expected the process to fail when the lease expired, got %+v", expired.Process)
+	}
+}
+
+func TestScheduleProcessesLease(t *testing.T) {
+	fake := NewFakeClock(time.Now())
+	interactor := newTestInteractor(t, NewStorageMemory(fake), fake)
+
+	if err := interactor.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if errs := interactor.UpdateProcessStatus("job", StatusRunning, "", 0); errs != nil {
+		t.Fatalf("error starting process: %s", errs)
+	}
+	if err := interactor.HeartbeatProcess("job"); err != nil {
+		t.Fatalf("error sending heartbeat: %s", err)
+	}
+
+	process, _ := interactor.GetProcess("job")
+	if interactor.ScheduleProcesses(process); *process.CanExecuteNow {
+		t.Errorf("expected the running process to not be startable")
+	}
+
+	// with the lease expired it can be started again
+	fake.Advance(2 * interactor.lease)
+	if interactor.ScheduleProcesses(process); !*process.CanExecuteNow {
+		t.Errorf("expected the process with an expired lease to be startable")
+	}
+}
+
+// newTestInteractor creates the interactor of a monitor with the storage and the clock
+func newTestInteractor(t *testing.T, storage IStorageDB, clock Clock) *Interactor {
+	m, err := NewMonitor(
+		WithConfiguration(&MonitorConfig{Host: "localhost:0"}),
+		WithManager(manager.NewManager(manager.WithRunInBackground(true))),
+		WithLogLevel(logger.ErrorLevel),
+		WithStorage(storage),
+		WithClock(clock),
+	)
+	if err != nil {
+		t.Fatalf("error creating the monitor: %s", err)
+	}
+
+	return m.NewInteractor(m.storage)
+}
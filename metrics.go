@@ -0,0 +1,229 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaosoft/web"
+)
+
+const ContentTypeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// latencyBuckets are the buckets, in seconds, of the http requests and database queries
+	latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// runBuckets are the buckets, in seconds, of the runs of the processes, from a second to a day
+	runBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400}
+)
+
+// metrics keeps the metrics of the monitor, written in the prometheus text format.
+// The methods can be called on a nil metrics, that doesn't record anything
+type metrics struct {
+	transitions   *metric
+	refusedStarts *metric
+	runDuration   *metric
+	httpDuration  *metric
+	queryDuration *metric
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		transitions:   newMetric("monitor_process_status_transitions_total", "Status changes of the processes.", "counter", nil, "from", "to"),
+		refusedStarts: newMetric("monitor_process_refused_starts_total", "Starts of processes refused, by the code of the error.", "counter", nil, "reason"),
+		runDuration:   newMetric("monitor_process_run_duration_seconds", "Duration of the runs of the processes, from running to stopped or failed.", "histogram", runBuckets, "type", "status"),
+		httpDuration:  newMetric("monitor_http_request_duration_seconds", "Latency of the http requests, by route.", "histogram", latencyBuckets, "method", "route", "status"),
+		queryDuration: newMetric("monitor_db_query_duration_seconds", "Latency of the database queries, by operation.", "histogram", latencyBuckets, "operation"),
+	}
+}
+
+// transition records the change of status of the process, and the duration of the run when it finishes
+func (metrics *metrics) transition(process *Process, status Status, now time.Time) {
+	if metrics == nil {
+		return
+	}
+
+	from := "none"
+	if process.Status != nil {
+		from = string(*process.Status)
+	}
+	metrics.transitions.add(1, from, string(status))
+
+	if from == string(StatusRunning) && status != StatusRunning {
+		metrics.runDuration.observe(now.Sub(process.UpdatedAt).Seconds(), process.Type, string(status))
+	}
+}
+
+func (metrics *metrics) refusedStart(err error) {
+	if metrics == nil {
+		return
+	}
+	metrics.refusedStarts.add(1, strings.ToLower(string(errorCode(err))))
+}
+
+func (metrics *metrics) query(operation string, start time.Time) {
+	if metrics == nil {
+		return
+	}
+	metrics.queryDuration.observe(time.Since(start).Seconds(), operation)
+}
+
+// middleware records the latency of the requests of a route
+func (metrics *metrics) middleware(method, route string) web.MiddlewareFunc {
+	return func(next web.HandlerFunc) web.HandlerFunc {
+		return func(ctx *web.Context) error {
+			start := time.Now()
+			err := next(ctx)
+
+			status := ctx.Response.Status
+			if err != nil && status < web.StatusBadRequest {
+				status = web.StatusInternalServerError
+			}
+			metrics.httpDuration.observe(time.Since(start).Seconds(), method, route, strconv.Itoa(int(status)))
+
+			return err
+		}
+	}
+}
+
+// write writes the metrics, with the processes counted by status, type and monitor
+func (metrics *metrics) write(w io.Writer, processes ListProcess) error {
+	gauge := newMetric("monitor_processes", "Processes by status, type and monitor.", "gauge", nil, "status", "type", "monitor")
+	for _, process := range processes {
+		status := "none"
+		if process.Status != nil {
+			status = string(*process.Status)
+		}
+		gauge.add(1, status, process.Type, process.Monitor)
+	}
+
+	for _, metric := range []*metric{gauge, metrics.transitions, metrics.refusedStarts, metrics.runDuration, metrics.httpDuration, metrics.queryDuration} {
+		if _, err := io.WriteString(w, metric.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// metric is a counter, gauge or histogram with labels
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+	mux     sync.Mutex
+}
+
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func newMetric(name, help, kind string, buckets []float64, labels ...string) *metric {
+	return &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// get returns the series of the label values, creating it when it doesn't exist
+func (metric *metric) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := metric.series[key]
+	if !ok {
+		s = &series{labels: values, counts: make([]uint64, len(metric.buckets))}
+		metric.series[key] = s
+	}
+	return s
+}
+
+func (metric *metric) add(value float64, labels ...string) {
+	metric.mux.Lock()
+	defer metric.mux.Unlock()
+
+	metric.get(labels).value += value
+}
+
+// observe adds the value to the buckets of the histogram, the value of the series is the sum
+func (metric *metric) observe(value float64, labels ...string) {
+	metric.mux.Lock()
+	defer metric.mux.Unlock()
+
+	s := metric.get(labels)
+	s.value += value
+	s.count++
+	for i, bucket := range metric.buckets {
+		if value <= bucket {
+			s.counts[i]++
+		}
+	}
+}
+
+// String returns the metric in the prometheus text format, with the series sorted by labels
+func (metric *metric) String() string {
+	metric.mux.Lock()
+	defer metric.mux.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+
+	keys := make([]string, 0, len(metric.series))
+	for key := range metric.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := metric.series[key]
+		if metric.kind != "histogram" {
+			fmt.Fprintf(&b, "%s%s %s\n", metric.name, metric.labelPairs(s.labels, ""), formatFloat(s.value))
+			continue
+		}
+
+		for i, bucket := range metric.buckets {
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", metric.name, metric.labelPairs(s.labels, formatFloat(bucket)), s.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", metric.name, metric.labelPairs(s.labels, "+Inf"), s.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", metric.name, metric.labelPairs(s.labels, ""), formatFloat(s.value))
+		fmt.Fprintf(&b, "%s_count%s %d\n", metric.name, metric.labelPairs(s.labels, ""), s.count)
+	}
+
+	return b.String()
+}
+
+// labelPairs returns the labels, with the le label of the histogram buckets when given
+func (metric *metric) labelPairs(values []string, le string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, metric.labels[i], escapeLabel(value)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/web"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := newMetrics()
+	now := time.Now()
+
+	stopped, running := StatusStopped, StatusRunning
+	metrics.transition(&Process{Type: "job", Status: &stopped}, StatusRunning, now)
+	metrics.transition(&Process{Type: "job", Status: &running, UpdatedAt: now.Add(-90 * time.Second)}, StatusFailed, now)
+	metrics.transition(&Process{Type: "job"}, StatusRunning, now)
+	metrics.refusedStart(ErrorAlreadyRunning)
+	metrics.refusedStart(ErrorAlreadyRunning)
+	metrics.query("get_process", now)
+
+	status := StatusStopped
+	processes := ListProcess{
+		{Type: "job", Monitor: "team", Status: &status},
+		{Type: "job", Monitor: "team", Status: &status},
+		{Type: "cron", Monitor: `a "quoted" team`},
+	}
+
+	var output strings.Builder
+	if err := metrics.write(&output, processes); err != nil {
+		t.Fatalf("error writing metrics: %s", err)
+	}
+
+	for _, expected := range []string{
+		"# TYPE monitor_processes gauge\n",
+		`monitor_processes{status="stopped",type="job",monitor="team"} 2` + "\n",
+		`monitor_processes{status="none",type="cron",monitor="a \"quoted\" team"} 1` + "\n",
+		`monitor_process_status_transitions_total{from="stopped",to="running"} 1` + "\n",
+		`monitor_process_status_transitions_total{from="running",to="failed"} 1` + "\n",
+		`monitor_process_status_transitions_total{from="none",to="running"} 1` + "\n",
+		`monitor_process_refused_starts_total{reason="already_running"} 2` + "\n",
+		`monitor_process_run_duration_seconds_bucket{type="job",status="failed",le="60"} 0` + "\n",
+		`monitor_process_run_duration_seconds_bucket{type="job",status="failed",le="300"} 1` + "\n",
+		`monitor_process_run_duration_seconds_bucket{type="job",status="failed",le="+Inf"} 1` + "\n",
+		`monitor_process_run_duration_seconds_sum{type="job",status="failed"} 90` + "\n",
+		`monitor_process_run_duration_seconds_count{type="job",status="failed"} 1` + "\n",
+		`monitor_db_query_duration_seconds_count{operation="get_process"} 1` + "\n",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected the metrics to have %q, got\n%s", expected, output.String())
+		}
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics := newMetrics()
+	handler := metrics.middleware(string(web.MethodGet), "/api/v1/processes/:id")(func(ctx *web.Context) error {
+		return ctx.Response.NoContent(web.StatusNotFound)
+	})
+
+	ctx := web.NewContext(time.Now(),
+		&web.Request{Base: web.Base{Address: web.NewAddress("http://localhost/api/v1/processes/a"), Headers: web.Headers{}}},
+		&web.Response{Base: web.Base{Headers: web.Headers{}}})
+	if err := handler(ctx); err != nil {
+		t.Fatalf("error handling the request: %s", err)
+	}
+
+	expected := `monitor_http_request_duration_seconds_count{method="GET",route="/api/v1/processes/:id",status="404"} 1`
+	if output := metrics.httpDuration.String(); !strings.Contains(output, expected) {
+		t.Errorf("expected the request to be recorded by route, got\n%s", output)
+	}
+}
+
+func TestMetricsNil(t *testing.T) {
+	var metrics *metrics
+
+	// a nil metrics doesn't record anything
+	metrics.transition(&Process{}, StatusRunning, time.Now())
+	metrics.refusedStart(ErrorAlreadyRunning)
+	metrics.query("get_process", time.Now())
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	_, url := newTestServer(t)
+
+	if response, body := testRequest(t, http.MethodPost, url+"/api/v1/processes", `{"id_process": "job", "type": "cron", "name": "job", "monitor": "ops"}`); response.StatusCode != http.StatusCreated {
+		t.Fatalf("error creating process: %d %s", response.StatusCode, body)
+	}
+	for _, status := range []Status{StatusRunning, StatusRunning, StatusStopped} {
+		testRequest(t, http.MethodPut, url+"/api/v1/processes/job/status/"+string(status), "")
+	}
+
+	response, body := testRequest(t, http.MethodGet, url+"/metrics", "")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("error getting the metrics: %d %s", response.StatusCode, body)
+	}
+
+	for _, line := range []string{
+		`monitor_processes{status="stopped",type="cron",monitor="ops"} 1`,
+		`monitor_process_status_transitions_total{from="none",to="running"} 1`,
+		`monitor_process_status_transitions_total{from="running",to="stopped"} 1`,
+		`monitor_process_refused_starts_total{reason="already_running"} 1`,
+		`monitor_process_run_duration_seconds_count{type="cron",status="stopped"} 1`,
+		`monitor_http_request_duration_seconds_count{method="POST",route="/api/v1/processes",status="201"} 1`,
+		`monitor_http_request_duration_seconds_count{method="PUT",route="/api/v1/processes/:id/status/:status",status="409"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected the metric %s on\n%s", line, body)
+		}
+	}
+}
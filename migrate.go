@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/joaosoft/errors"
+)
+
+const (
+	migrationUp   = "-- migrate up"
+	migrationDown = "-- migrate down"
+)
+
+// executeMigrations executes the migrations of the files not executed yet, by the order of the names,
+// registering them on the migration table like the migrations of postgres. Each migration is executed
+// on a transaction, with the statements after "-- migrate up" and before "-- migrate down"
+func executeMigrations(db *sql.DB, files fs.FS, placeholder func(position int) string) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS migration (
+			id_migration VARCHAR(255) NOT NULL,
+			mode         VARCHAR(50) NOT NULL,
+			executed_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT migration_id_migration_pkey PRIMARY KEY (id_migration)
+		)
+	`); err != nil {
+		return errors.New(errors.LevelError, 0, "error creating the migration table: %s", err)
+	}
+
+	names, err := migrationNames(files)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		var executed int
+		if err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM migration WHERE id_migration = %s`, placeholder(1)), name).Scan(&executed); err != nil {
+			return errors.New(errors.LevelError, 0, "error checking the migration %s: %s", name, err)
+		}
+
+		if executed > 0 {
+			continue
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return errors.New(errors.LevelError, 0, "error reading the migration %s: %s", name, err)
+		}
+
+		if err := executeMigration(db, name, migrationStatements(string(content)), placeholder); err != nil {
+			return errors.New(errors.LevelError, 0, "error executing the migration %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+func executeMigration(db *sql.DB, name, statements string, placeholder func(position int) string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(statements); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`INSERT INTO migration(id_migration, mode) VALUES(%s, 'database')`, placeholder(1)), name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrationNames returns the names of the sql files, sorted
+func migrationNames(files fs.FS) ([]string, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// migrationStatements returns the statements to migrate up
+func migrationStatements(content string) string {
+	if index := strings.Index(content, migrationUp); index >= 0 {
+		content = content[index+len(migrationUp):]
+	}
+	if index := strings.Index(content, migrationDown); index >= 0 {
+		content = content[:index]
+	}
+
+	return strings.TrimSpace(content)
+}
+
+// lastMigration returns the name of the last migration of the files
+func lastMigration(files fs.FS) string {
+	names, _ := migrationNames(files)
+	if len(names) == 0 {
+		return ""
+	}
+
+	return names[len(names)-1]
+}
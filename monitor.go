@@ -1,30 +1,43 @@
 package monitor
 
 import (
+	"database/sql"
 	"sync"
+	"time"
 
 	"github.com/labstack/gommon/log"
 
+	"github.com/joaosoft/errors"
 	"github.com/joaosoft/logger"
 	"github.com/joaosoft/manager"
 	migration "github.com/joaosoft/migration/services"
 )
 
 type Monitor struct {
-	logger        logger.ILogger
-	config        *MonitorConfig
-	isLogExternal bool
-	pm            *manager.Manager
-	mux           sync.Mutex
+	logger           logger.ILogger
+	config           *MonitorConfig
+	isLogExternal    bool
+	pm               *manager.Manager
+	storage          IStorageDB
+	idempotencyStore IIdempotencyStore
+	idempotency      *Idempotency
+	lease            time.Duration
+	metrics          *metrics
+	health           *Health
+	statusPage       *StatusPage
+	clock            Clock
+	mux              sync.Mutex
 }
 
 // NewMonitor ...
 func NewMonitor(options ...MonitorOption) (*Monitor, error) {
 	config, simpleConfig, err := NewConfig()
 	service := &Monitor{
-		pm:     manager.NewManager(manager.WithRunInBackground(false)),
-		logger: logger.NewLogDefault("monitor", logger.WarnLevel),
-		config: config.Monitor,
+		pm:      manager.NewManager(manager.WithRunInBackground(false)),
+		logger:  logger.NewLogDefault("monitor", logger.WarnLevel),
+		config:  config.Monitor,
+		metrics: newMetrics(),
+		clock:   systemClock{},
 	}
 
 	if service.isLogExternal {
@@ -42,35 +55,137 @@ func NewMonitor(options ...MonitorOption) (*Monitor, error) {
 		config.Monitor = &MonitorConfig{
 			Host: DefaultURL,
 		}
+		service.config = config.Monitor
 	}
 
 	service.Reconfigure(options...)
 
-	// execute migrations
-	migrationService, err := migration.NewCmdService(migration.WithCmdConfiguration(service.config.Migration))
-	if err != nil {
+	if service.health, err = service.NewHealth(); err != nil {
 		return nil, err
 	}
 
-	if _, err := migrationService.Execute(migration.OptionUp, 0, migration.ExecutorModeDatabase); err != nil {
+	if service.statusPage, err = service.NewStatusPage(); err != nil {
 		return nil, err
 	}
 
-	simpleDB := service.pm.NewSimpleDB(&config.Monitor.Db)
-	if err := service.pm.AddDB("db_postgres", simpleDB); err != nil {
-		log.Error(err.Error())
+	if service.storage == nil {
+		if service.storage, err = service.newStorage(); err != nil {
+			return nil, err
+		}
+	}
+
+	if service.idempotencyStore == nil {
+		service.idempotencyStore = NewMemoryIdempotencyStore()
+	}
+
+	if service.idempotency, err = service.NewIdempotency(service.idempotencyStore); err != nil {
 		return nil, err
 	}
 
+	service.lease = DefaultHeartbeatLease
+	if service.config.Heartbeat.Lease != "" {
+		if service.lease, err = time.ParseDuration(service.config.Heartbeat.Lease); err != nil {
+			return nil, errors.New(errors.LevelError, 0, "invalid heartbeat lease %s: %s", service.config.Heartbeat.Lease, err)
+		}
+	}
+
 	web := service.pm.NewSimpleWebServer(service.config.Host)
-	controller := service.NewController(service.NewInteractor(service.NewStoragePostgres(simpleDB)))
+	interactor := service.NewInteractor(service.storage)
+	controller := service.NewController(interactor)
 	controller.RegisterRoutes(web)
 
 	service.pm.AddWeb("api_web", web)
 
+	purger, err := service.NewPurger(interactor)
+	if err != nil {
+		return nil, err
+	}
+	service.pm.AddProcess("process_purge", purger)
+	service.pm.AddProcess("process_health", service.health)
+
 	return service, nil
 }
 
+// newStorage connects to the database of the configuration, by the driver, executing the migrations
+func (monitor *Monitor) newStorage() (IStorageDB, error) {
+	switch monitor.config.Db.Driver {
+	case DriverSQLite:
+		simpleDB := monitor.pm.NewSimpleDB(&monitor.config.Db)
+		if err := simpleDB.Start(); err != nil {
+			return nil, err
+		}
+
+		// sqlite allows a single writer, so the connections are serialized
+		simpleDB.Get().SetMaxOpenConns(1)
+
+		if err := executeMigrations(simpleDB.Get(), sqliteMigrations, questionPlaceholder); err != nil {
+			return nil, err
+		}
+
+		if err := monitor.pm.AddDB("db_sqlite", simpleDB); err != nil {
+			return nil, err
+		}
+
+		monitor.addDatabaseChecks(monitor.health, simpleDB, "migration", lastMigration(sqliteMigrations))
+		return monitor.NewStorageSQLite(simpleDB), nil
+
+	case DriverMySQL:
+		migrationsDataSource, err := mysqlDataSource(monitor.config.Db.DataSource, true)
+		if err != nil {
+			return nil, err
+		}
+
+		db, err := sql.Open(DriverMySQL, migrationsDataSource)
+		if err != nil {
+			return nil, err
+		}
+		err = executeMigrations(db, mysqlMigrations, questionPlaceholder)
+		db.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		config := monitor.config.Db
+		if config.DataSource, err = mysqlDataSource(config.DataSource, false); err != nil {
+			return nil, err
+		}
+
+		simpleDB := monitor.pm.NewSimpleDB(&config)
+		if err := monitor.pm.AddDB("db_mysql", simpleDB); err != nil {
+			return nil, err
+		}
+
+		monitor.addDatabaseChecks(monitor.health, simpleDB, "migration", lastMigration(mysqlMigrations))
+		return monitor.NewStorageMySQL(simpleDB), nil
+
+	default:
+		// execute migrations
+		migrationService, err := migration.NewCmdService(migration.WithCmdConfiguration(monitor.config.Migration))
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := migrationService.Execute(migration.OptionUp, 0, migration.ExecutorModeDatabase); err != nil {
+			return nil, err
+		}
+
+		simpleDB := monitor.pm.NewSimpleDB(&monitor.config.Db)
+		if err := monitor.pm.AddDB("db_postgres", simpleDB); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+
+		table, expected := monitor.postgresMigrations()
+		monitor.addDatabaseChecks(monitor.health, simpleDB, table, expected)
+		return monitor.NewStoragePostgres(simpleDB), nil
+	}
+}
+
+// Storage returns the storage of the processes
+func (m *Monitor) Storage() IStorageDB {
+	return m.storage
+}
+
 // Start ...
 func (m *Monitor) Start() error {
 	return m.pm.Start()
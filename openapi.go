@@ -0,0 +1,615 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joaosoft/types"
+	"github.com/joaosoft/web"
+)
+
+// openAPIOperation documents a route registered on RegisterRoutes
+type openAPIOperation struct {
+	Id          string // the id derived from the method and the path when it's empty
+	Method      web.Method
+	Path        string
+	Summary     string
+	Query       []string
+	Headers     []string
+	Request     interface{}
+	Optional    bool
+	Patch       bool
+	Files       bool // the request, or the response when there isn't a request, is a json, yaml or csv file
+	Responses   map[web.Status]interface{}
+	RespHeaders []string
+}
+
+// openAPIParameters describes the query and header parameters used by the operations
+var openAPIParameters = map[string]map[string]interface{}{
+	"id_process":         {"name": "id_process", "in": "query", "description": "filter by process, can be repeated", "schema": map[string]interface{}{"type": "string"}},
+	"type":               {"name": "type", "in": "query", "description": "filter by type", "schema": map[string]interface{}{"type": "string"}},
+	"name":               {"name": "name", "in": "query", "description": "filter by name", "schema": map[string]interface{}{"type": "string"}},
+	"monitor":            {"name": "monitor", "in": "query", "description": "filter by monitor", "schema": map[string]interface{}{"type": "string"}},
+	"status":             {"name": "status", "in": "query", "description": "filter by status", "schema": map[string]interface{}{"$ref": "#/components/schemas/Status"}},
+	"format":             {"name": "format", "in": "query", "description": "format of the file, json, yaml or csv", "schema": map[string]interface{}{"type": "string", "enum": []FileFormat{FileFormatJSON, FileFormatYAML, FileFormatCSV}}},
+	"on_conflict":        {"name": "on_conflict", "in": "query", "description": "what to do with the processes that already exist, fail by default", "schema": map[string]interface{}{"type": "string", "enum": []ConflictPolicy{ConflictSkip, ConflictOverwrite, ConflictFail}}},
+	"dry_run":            {"name": "dry_run", "in": "query", "description": "only plan the changes", "schema": map[string]interface{}{"type": "boolean"}},
+	"confirmation":       {"name": "confirmation", "in": "query", "description": "confirmation token returned by the first request", "schema": map[string]interface{}{"type": "string"}},
+	"include":            {"name": "include", "in": "query", "description": "include when the processes can be started", "schema": map[string]interface{}{"type": "string", "enum": []string{IncludeSchedule}}},
+	"show":               {"name": "show", "in": "query", "description": "what the badge shows, the status by default", "schema": map[string]interface{}{"type": "string", "enum": []string{BadgeStatus, BadgeLastSuccess}}},
+	"label":              {"name": "label", "in": "query", "description": "label of the badge, the name of the process by default", "schema": map[string]interface{}{"type": "string"}},
+	HeaderIfMatch:        {"name": HeaderIfMatch, "in": "header", "description": "versions of the process returned on the ETag header, or * for any version", "schema": map[string]interface{}{"type": "string"}},
+	HeaderIdempotencyKey: {"name": HeaderIdempotencyKey, "in": "header", "description": "key to safely retry the request", "schema": map[string]interface{}{"type": "string", "maxLength": MaxIdempotencyKeyLength}},
+}
+
+var (
+	openAPIFilters   = []string{"id_process", "type", "name", "monitor", "status"}
+	openAPIMutations = []string{HeaderIdempotencyKey}
+	openAPIVersioned = []string{HeaderIfMatch, HeaderIdempotencyKey}
+)
+
+// openAPIOperations has to be kept in sync with RegisterRoutes, which is checked by the tests
+var openAPIOperations = []*openAPIOperation{
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/processes/export",
+		Summary:   "Export the filtered processes as a json, yaml or csv file",
+		Query:     append(append([]string{}, openAPIFilters...), "format"),
+		Files:     true,
+		Responses: map[web.Status]interface{}{web.StatusOK: []*ProcessDefinition{}, web.StatusBadRequest: Problem{}},
+	},
+	{
+		Method:      web.MethodGet,
+		Path:        "/api/v1/processes/:id",
+		Summary:     "Get a process, with when it can be started on include=schedule",
+		Query:       []string{"include"},
+		Responses:   map[web.Status]interface{}{web.StatusOK: Process{}, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}},
+		RespHeaders: []string{HeaderETag},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/processes/:id/history",
+		Summary:   "Get the changes of a process, by the order they were made",
+		Responses: map[web.Status]interface{}{web.StatusOK: ListProcessHistory{}, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/processes/:id/runs",
+		Summary:   "Get the runs of a process, from running to stopped or failed, the last first",
+		Responses: map[web.Status]interface{}{web.StatusOK: ListProcessRun{}, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/processes",
+		Summary:   "List the processes, with when they can be started on include=schedule",
+		Query:     append(append([]string{}, openAPIFilters...), "include"),
+		Responses: map[web.Status]interface{}{web.StatusOK: ListProcess{}, web.StatusBadRequest: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes",
+		Summary:   "Create a process",
+		Headers:   openAPIMutations,
+		Request:   CreateProcessRequest{}.Body,
+		Responses: map[web.Status]interface{}{web.StatusCreated: nil, web.StatusBadRequest: Problem{}, web.StatusConflict: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes:batch",
+		Summary:   "Create, update and change the status of many processes",
+		Headers:   openAPIMutations,
+		Request:   BatchProcessesRequest{}.Body,
+		Responses: map[web.Status]interface{}{web.StatusOK: BatchProcessesResponse{}, web.StatusMultiStatus: BatchProcessesResponse{}, web.StatusBadRequest: Problem{}, web.StatusRequestEntityTooLarge: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes/apply",
+		Summary:   "Create, update and optionally prune the processes to match their definitions, or only plan it on dry run",
+		Headers:   openAPIMutations,
+		Request:   ApplyProcessesRequest{}.Body,
+		Responses: map[web.Status]interface{}{web.StatusOK: ApplyProcessesResponse{}, web.StatusBadRequest: Problem{}, web.StatusConflict: Problem{}, web.StatusPreconditionFailed: Problem{}, web.StatusRequestEntityTooLarge: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes/import",
+		Summary:   "Import the processes of a json, yaml or csv file, the format is also given by the content type",
+		Query:     []string{"format", "on_conflict", "dry_run"},
+		Headers:   openAPIMutations,
+		Request:   []*ProcessDefinition{},
+		Files:     true,
+		Responses: map[web.Status]interface{}{web.StatusOK: ImportProcessesResponse{}, web.StatusBadRequest: Problem{}, web.StatusConflict: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes/simulate",
+		Summary:   "Simulate when a process, or a process with another window, could be started on a range of time",
+		Request:   SimulateProcessRequest{}.Body,
+		Responses: map[web.Status]interface{}{web.StatusOK: SimulateProcessResponse{}, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}},
+	},
+	{
+		Method:      web.MethodPut,
+		Path:        "/api/v1/processes/:id",
+		Summary:     "Update a process",
+		Headers:     openAPIVersioned,
+		Request:     UpdateProcessRequest{}.Body,
+		Responses:   map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusPreconditionFailed: Problem{}, web.StatusPreconditionRequired: Problem{}},
+		RespHeaders: []string{HeaderETag},
+	},
+	{
+		Method:      web.MethodPatch,
+		Path:        "/api/v1/processes/:id",
+		Summary:     "Patch a process with a JSON Merge Patch or a JSON Patch",
+		Headers:     openAPIVersioned,
+		Patch:       true,
+		Responses:   map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusPreconditionFailed: Problem{}, web.StatusPreconditionRequired: Problem{}, web.StatusUnsupportedMediaType: Problem{}, web.StatusUnprocessableEntity: Problem{}},
+		RespHeaders: []string{HeaderETag},
+	},
+	{
+		Method:    web.MethodPut,
+		Path:      "/api/v1/processes/:id/status/:status",
+		Summary:   "Change the status of a process, with an optional message like the error of a failed process",
+		Headers:   openAPIVersioned,
+		Request:   UpdateProcessStatusRequest{}.Body,
+		Optional:  true,
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusConflict: Problem{}, web.StatusPreconditionFailed: Problem{}},
+	},
+	{
+		Method:    web.MethodPut,
+		Path:      "/api/v1/processes/:id/status/:status/check",
+		Summary:   "Check if the status of a process can be changed",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusConflict: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes/:id/heartbeat",
+		Summary:   "Record that a running process is alive, fails with LEASE_LOST when it isn't running anymore",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusConflict: Problem{}},
+	},
+	{
+		Method:    web.MethodPost,
+		Path:      "/api/v1/processes/:id/restore",
+		Summary:   "Restore a deleted process",
+		Headers:   openAPIMutations,
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}},
+	},
+	{
+		Method:    web.MethodDelete,
+		Path:      "/api/v1/processes/:id",
+		Summary:   "Delete a process",
+		Headers:   openAPIVersioned,
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: Problem{}, web.StatusPreconditionFailed: Problem{}, web.StatusPreconditionRequired: Problem{}},
+	},
+	{
+		Method:    web.MethodDelete,
+		Path:      "/api/v1/processes",
+		Summary:   "Delete the filtered processes, after confirming",
+		Query:     append(append([]string{}, openAPIFilters...), "confirmation"),
+		Headers:   openAPIMutations,
+		Responses: map[web.Status]interface{}{web.StatusOK: DeleteProcessesResponse{}, web.StatusPreconditionRequired: ConfirmationProblem{}, web.StatusBadRequest: Problem{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/openapi.json",
+		Summary:   "Get this OpenAPI specification",
+		Responses: map[web.Status]interface{}{web.StatusOK: map[string]interface{}{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/docs",
+		Summary:   "Browse this OpenAPI specification",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/dashboard",
+		Summary:   "Browse the processes, their history and runs, and start or stop them",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/api/v1/status",
+		Summary:   "Get the public status of the processes of the status page, with their last run and uptime",
+		Responses: map[web.Status]interface{}{web.StatusOK: StatusPageResponse{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Id:        "getStatusPage",
+		Path:      "/status",
+		Summary:   "Show the public read-only status page",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/badge/:id.svg",
+		Summary:   "Get a svg badge with the status or the last success of a process",
+		Query:     []string{"show", "label"},
+		Responses: map[web.Status]interface{}{web.StatusOK: nil, web.StatusBadRequest: Problem{}, web.StatusNotFound: nil},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/metrics",
+		Summary:   "Get the metrics in the prometheus text format",
+		Responses: map[web.Status]interface{}{web.StatusOK: nil},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/health/live",
+		Summary:   "Check if the monitor is alive",
+		Responses: map[web.Status]interface{}{web.StatusOK: HealthResponse{}},
+	},
+	{
+		Method:    web.MethodGet,
+		Path:      "/health/ready",
+		Summary:   "Check if the monitor is ready, with the status of each component, it isn't ready while shutting down",
+		Responses: map[web.Status]interface{}{web.StatusOK: HealthResponse{}, web.StatusServiceUnavailable: HealthResponse{}},
+	},
+}
+
+// OpenAPI returns the OpenAPI 3 specification of the api
+func OpenAPI() map[string]interface{} {
+	schemas := newOpenAPISchemas()
+	paths := make(map[string]map[string]interface{})
+
+	for _, operation := range openAPIOperations {
+		path, parameters := openAPIPath(operation.Path)
+		for _, name := range operation.Query {
+			parameters = append(parameters, openAPIParameters[name])
+		}
+		for _, name := range operation.Headers {
+			parameters = append(parameters, openAPIParameters[name])
+		}
+
+		item := map[string]interface{}{
+			"summary":     operation.Summary,
+			"operationId": openAPIOperationId(operation),
+			"responses":   openAPIResponses(schemas, operation),
+		}
+
+		if len(parameters) > 0 {
+			item["parameters"] = parameters
+		}
+
+		switch {
+		case operation.Patch:
+			item["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					ContentTypeMergePatch: map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(UpdateProcessRequest{}.Body), "")},
+					ContentTypeJSONPatch:  map[string]interface{}{"schema": schemas.schema(reflect.TypeOf([]PatchOperation{}), "")},
+				},
+			}
+		case operation.Files && operation.Request != nil:
+			item["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  openAPIFiles(schemas, operation.Request),
+			}
+		case operation.Request != nil:
+			item["requestBody"] = map[string]interface{}{
+				"required": !operation.Optional,
+				"content": map[string]interface{}{
+					string(web.ContentTypeApplicationJSON): map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(operation.Request), "")},
+				},
+			}
+		}
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(string(operation.Method))] = item
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "monitor",
+			"description": "A simple and centralized process monitor",
+			"version":     "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+		},
+	}
+}
+
+// openAPIPath converts the route parameters to the OpenAPI format
+func openAPIPath(path string) (string, []interface{}) {
+	parameters := make([]interface{}, 0)
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			// the extension of the parameter, like on :id.svg, is kept on the path
+			name, extension := segment[1:], ""
+			if index := strings.Index(name, "."); index >= 0 {
+				name, extension = name[:index], name[index:]
+			}
+			segments[i] = fmt.Sprintf("{%s}%s", name, extension)
+
+			schema := map[string]interface{}{"type": "string"}
+			if name == "status" {
+				schema = map[string]interface{}{"$ref": "#/components/schemas/Status"}
+			}
+
+			parameters = append(parameters, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   schema,
+			})
+		}
+	}
+
+	return strings.Join(segments, "/"), parameters
+}
+
+func openAPIOperationId(operation *openAPIOperation) string {
+	if operation.Id != "" {
+		return operation.Id
+	}
+
+	id := strings.ToLower(string(operation.Method))
+	for _, segment := range strings.FieldsFunc(operation.Path, func(r rune) bool { return r == '/' || r == ':' || r == '.' }) {
+		if segment == "api" || segment == "v1" {
+			continue
+		}
+		id += strings.Title(segment)
+	}
+	return id
+}
+
+func openAPIResponses(schemas *openAPISchemas, operation *openAPIOperation) map[string]interface{} {
+	responses := make(map[string]interface{})
+
+	for status, body := range operation.Responses {
+		response := map[string]interface{}{
+			"description": web.StatusText(status),
+		}
+
+		switch {
+		case operation.Files && operation.Request == nil && status == web.StatusOK:
+			response["content"] = openAPIFiles(schemas, body)
+		case body != nil:
+			response["content"] = openAPIContent(schemas, body)
+		}
+
+		if status < web.StatusMultipleChoices && len(operation.RespHeaders) > 0 {
+			headers := make(map[string]interface{})
+			for _, header := range operation.RespHeaders {
+				headers[header] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+			}
+			response["headers"] = headers
+		}
+
+		responses[fmt.Sprintf("%d", status)] = response
+	}
+
+	responses["default"] = map[string]interface{}{
+		"description": "Error",
+		"content":     openAPIContent(schemas, Problem{}),
+	}
+
+	return responses
+}
+
+func openAPIContent(schemas *openAPISchemas, body interface{}) map[string]interface{} {
+	contentType := string(web.ContentTypeApplicationJSON)
+	switch body.(type) {
+	case Problem, ConfirmationProblem:
+		contentType = ContentTypeProblem
+	}
+
+	return map[string]interface{}{
+		contentType: map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(body), "")},
+	}
+}
+
+// openAPIFiles returns the content of the files with the processes, as json, yaml or csv
+func openAPIFiles(schemas *openAPISchemas, body interface{}) map[string]interface{} {
+	schema := schemas.schema(reflect.TypeOf(body), "")
+
+	return map[string]interface{}{
+		string(web.ContentTypeApplicationJSON): map[string]interface{}{"schema": schema},
+		ContentTypeYAML:                        map[string]interface{}{"schema": schema},
+		ContentTypeCSV:                         map[string]interface{}{"schema": map[string]interface{}{"type": "string", "description": "with the header " + strings.Join(csvColumns, ",")}},
+	}
+}
+
+type openAPISchemas struct {
+	components map[string]interface{}
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		components: map[string]interface{}{
+			"Status":    map[string]interface{}{"type": "string", "enum": []Status{StatusStopped, StatusRunning, StatusFailed}},
+			"ErrorCode": map[string]interface{}{"type": "string", "enum": openAPIErrorCodes()},
+		},
+	}
+}
+
+// openAPIErrorCodes returns the sorted error codes
+func openAPIErrorCodes() []string {
+	codes := make([]string, 0, len(errorDefinitions))
+	for code := range errorDefinitions {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+var (
+	typeStatus    = reflect.TypeOf(Status(""))
+	typeDate      = reflect.TypeOf(types.Date(""))
+	typeTime      = reflect.TypeOf(types.Time(""))
+	typeListDay   = reflect.TypeOf(types.ListDay{})
+	typeDateTime  = reflect.TypeOf(time.Time{})
+	typeRaw       = reflect.TypeOf(json.RawMessage{})
+	typeStatusWeb = reflect.TypeOf(web.Status(0))
+	typeErrorCode = reflect.TypeOf(ErrorCode(""))
+)
+
+// schema returns the schema of a type, the named structs are added to the components
+func (schemas *openAPISchemas) schema(t reflect.Type, tag string) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch t {
+	case typeStatus:
+		schema = map[string]interface{}{"$ref": "#/components/schemas/Status"}
+	case typeDate:
+		schema = map[string]interface{}{"type": "string", "example": "31-12-2006"}
+	case typeTime:
+		schema = map[string]interface{}{"type": "string", "format": "time", "example": "15:04:05"}
+	case typeListDay:
+		schema = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	case typeDateTime:
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case typeRaw:
+		schema = map[string]interface{}{}
+	case typeErrorCode:
+		schema = map[string]interface{}{"$ref": "#/components/schemas/ErrorCode"}
+	case typeStatusWeb:
+		schema = map[string]interface{}{"type": "integer"}
+	default:
+		schema = schemas.kind(t)
+	}
+
+	openAPIConstraints(schema, tag)
+
+	if nullable && schema["$ref"] == nil {
+		schema["nullable"] = true
+	}
+
+	return schema
+}
+
+func (schemas *openAPISchemas) kind(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemas.schema(t.Elem(), "")}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemas.schema(t.Elem(), "")}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return schemas.object(t)
+		}
+
+		if _, ok := schemas.components[t.Name()]; !ok {
+			// reserved before building, for the recursive types
+			schemas.components[t.Name()] = nil
+			schemas.components[t.Name()] = schemas.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (schemas *openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		// the fields of the embedded structs are promoted, like on json
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := schemas.object(field.Type)
+			for key, value := range embedded["properties"].(map[string]interface{}) {
+				properties[key] = value
+			}
+			if fields, ok := embedded["required"].([]string); ok {
+				required = append(required, fields...)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		tag := field.Tag.Get("validate")
+		properties[name] = schemas.schema(field.Type, tag)
+
+		if openAPIRequired(tag) {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// openAPIConstraints maps the validation tags to the schema constraints
+func openAPIConstraints(schema map[string]interface{}, tag string) {
+	for _, validation := range strings.Split(tag, ",") {
+		validation = strings.TrimSpace(validation)
+
+		switch {
+		case validation == "not-empty":
+			if schema["type"] == "string" {
+				schema["minLength"] = 1
+			}
+			if schema["type"] == "array" {
+				schema["minItems"] = 1
+			}
+		case strings.HasPrefix(validation, "options="):
+			options := strings.Split(strings.TrimPrefix(validation, "options="), ";")
+			if items, ok := schema["items"].(map[string]interface{}); ok && schema["type"] == "array" {
+				items["enum"] = options
+			} else if schema["$ref"] == nil {
+				schema["enum"] = options
+			}
+		case strings.HasPrefix(validation, "regex="):
+			schema["pattern"] = strings.TrimPrefix(validation, "regex=")
+		}
+	}
+}
+
+func openAPIRequired(tag string) bool {
+	for _, validation := range strings.Split(tag, ",") {
+		if strings.TrimSpace(validation) == "not-empty" {
+			return true
+		}
+	}
+	return false
+}
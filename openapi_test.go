@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/joaosoft/manager"
+	"github.com/joaosoft/web"
+)
+
+// routesRecorder is a manager.IWeb that only keeps the registered routes
+type routesRecorder struct {
+	routes []*manager.Route
+}
+
+func (recorder *routesRecorder) AddRoute(method, path string, handler manager.HandlerFunc, middleware ...manager.MiddlewareFunc) error {
+	recorder.routes = append(recorder.routes, manager.NewRoute(method, path, handler, middleware...))
+	return nil
+}
+
+func (recorder *routesRecorder) AddRoutes(routes ...*manager.Route) error {
+	recorder.routes = append(recorder.routes, routes...)
+	return nil
+}
+
+func (recorder *routesRecorder) AddNamespace(path string, middleware []manager.MiddlewareFunc, routes ...*manager.Route) error {
+	for _, route := range routes {
+		recorder.routes = append(recorder.routes, manager.NewRoute(route.Method, path+route.Path, route.Handler))
+	}
+	return nil
+}
+
+func (recorder *routesRecorder) AddFilter(pattern string, position string, middleware manager.MiddlewareFunc, method string, methods ...string) {
+}
+
+func (recorder *routesRecorder) Start(waitGroup ...*sync.WaitGroup) error { return nil }
+func (recorder *routesRecorder) Stop(waitGroup ...*sync.WaitGroup) error  { return nil }
+func (recorder *routesRecorder) Started() bool                            { return false }
+func (recorder *routesRecorder) GetClient() interface{}                   { return nil }
+
+func TestOpenAPIOperationsMatchRoutes(t *testing.T) {
+	recorder := &routesRecorder{}
+	if err := (&Controller{}).RegisterRoutes(recorder); err != nil {
+		t.Fatalf("error registering routes: %s", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range recorder.routes {
+		if route.Method == string(web.MethodOptions) {
+			continue
+		}
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	documented := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, operation := range openAPIOperations {
+		key := string(operation.Method) + " " + operation.Path
+		if documented[key] {
+			t.Errorf("operation %s is documented more than once", key)
+		}
+		documented[key] = true
+
+		if id := openAPIOperationId(operation); ids[id] {
+			t.Errorf("operation id %s of %s is repeated", id, key)
+		} else {
+			ids[id] = true
+		}
+
+		if !registered[key] {
+			t.Errorf("operation %s is documented but not registered", key)
+		}
+
+		for _, name := range append(append([]string{}, operation.Query...), operation.Headers...) {
+			if openAPIParameters[name] == nil {
+				t.Errorf("parameter %s of operation %s isn't described", name, key)
+			}
+		}
+	}
+
+	for key := range registered {
+		if !documented[key] {
+			t.Errorf("route %s is registered but not documented", key)
+		}
+	}
+}
+
+func TestOpenAPISpecification(t *testing.T) {
+	bytes, err := json.Marshal(OpenAPI())
+	if err != nil {
+		t.Fatalf("error marshaling the specification: %s", err)
+	}
+
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage        `json:"paths"`
+		Components struct{ Schemas map[string]json.RawMessage } `json:"components"`
+	}
+	if err = json.Unmarshal(bytes, &spec); err != nil {
+		t.Fatalf("error unmarshaling the specification: %s", err)
+	}
+
+	if spec.OpenAPI == "" {
+		t.Error("missing openapi version")
+	}
+
+	for _, path := range []string{"/api/v1/processes/{id}", "/api/v1/processes:batch", "/badge/{id}.svg"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("missing path %s", path)
+		}
+	}
+
+	for _, schema := range []string{"Process", "Status"} {
+		if _, ok := spec.Components.Schemas[schema]; !ok {
+			t.Errorf("missing schema %s", schema)
+		}
+	}
+}
@@ -43,3 +43,25 @@ func WithManager(mgr *manager.Manager) MonitorOption {
 		dropbox.pm = mgr
 	}
 }
+
+// WithIdempotencyStore ...
+func WithIdempotencyStore(store IIdempotencyStore) MonitorOption {
+	return func(monitor *Monitor) {
+		monitor.idempotencyStore = store
+	}
+}
+
+// WithStorage uses the storage instead of the postgres database, without executing the migrations
+func WithStorage(storage IStorageDB) MonitorOption {
+	return func(monitor *Monitor) {
+		monitor.storage = storage
+	}
+}
+
+// WithClock uses the clock to evaluate the execution windows, the retentions and the interval of the purges,
+// instead of the system clock
+func WithClock(clock Clock) MonitorOption {
+	return func(monitor *Monitor) {
+		monitor.clock = clock
+	}
+}
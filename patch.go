@@ -0,0 +1,344 @@
+package monitor
+
+import (
+	"encoding/json"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joaosoft/errors"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// PatchOperation is an operation of a json patch, the value is kept raw so a null value
+// is told apart from a missing one
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// patchMediaType returns the media type of the content type, without its parameters like the charset
+func patchMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+// ApplyPatch applies a json merge patch (RFC 7386) or a json patch (RFC 6902) to a json document
+func ApplyPatch(contentType string, document, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, errors.New(errors.LevelError, ErrorCodeInternal, err)
+	}
+
+	switch contentType {
+	case ContentTypeMergePatch:
+		var merge interface{}
+		if err := json.Unmarshal(patch, &merge); err != nil {
+			return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid merge patch: %s", err)
+		}
+		target = mergePatch(target, merge)
+
+	case ContentTypeJSONPatch:
+		var operations []PatchOperation
+		if err := json.Unmarshal(patch, &operations); err != nil {
+			return nil, errors.New(errors.LevelError, ErrorCodeInvalidBody, "invalid json patch: %s", err)
+		}
+
+		var err error
+		for i, operation := range operations {
+			if target, err = operation.apply(target); err != nil {
+				return nil, errors.New(errors.LevelError, ErrorCodeInvalidPatch, "invalid json patch operation %d (%s %s): %s", i, operation.Op, operation.Path, err)
+			}
+		}
+
+	default:
+		return nil, ErrorUnsupportedPatch
+	}
+
+	return json.Marshal(target)
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+func (operation PatchOperation) apply(document interface{}) (interface{}, error) {
+	var value interface{}
+	if len(operation.Value) > 0 {
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		if len(operation.Value) == 0 {
+			return nil, errors.New(errors.LevelError, 0, "missing value")
+		}
+		return pointerAdd(document, operation.Path, value)
+
+	case "remove":
+		document, _, err := pointerRemove(document, operation.Path)
+		return document, err
+
+	case "replace":
+		if len(operation.Value) == 0 {
+			return nil, errors.New(errors.LevelError, 0, "missing value")
+		}
+		document, _, err := pointerRemove(document, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, operation.Path, value)
+
+	case "move":
+		if operation.Path == operation.From {
+			return document, nil
+		}
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, errors.New(errors.LevelError, 0, "cannot move a value into one of its children")
+		}
+		document, moved, err := pointerRemove(document, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, operation.Path, moved)
+
+	case "copy":
+		copied, err := pointerGet(document, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, operation.Path, deepCopy(copied))
+
+	case "test":
+		current, err := pointerGet(document, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, errors.New(errors.LevelError, 0, "test failed")
+		}
+		return document, nil
+
+	default:
+		return nil, errors.New(errors.LevelError, 0, "unknown operation")
+	}
+}
+
+// pointerTokens splits a json pointer (RFC 6901) into its unescaped reference tokens
+func pointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New(errors.LevelError, 0, "invalid path")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, errors.New(errors.LevelError, 0, "invalid array index %s", token)
+	}
+
+	if index > length || (!allowEnd && index == length) {
+		return 0, errors.New(errors.LevelError, 0, "array index %s out of bounds", token)
+	}
+
+	return index, nil
+}
+
+func pointerGet(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, errors.New(errors.LevelError, 0, "path not found")
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, errors.New(errors.LevelError, 0, "path not found")
+		}
+	}
+
+	return current, nil
+}
+
+func pointerAdd(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(document, parentPointer(pointer))
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return document, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return replaceAt(document, parentPointer(pointer), node)
+	default:
+		return nil, errors.New(errors.LevelError, 0, "path not found")
+	}
+}
+
+func pointerRemove(document interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+
+	parent, err := pointerGet(document, parentPointer(pointer))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, errors.New(errors.LevelError, 0, "path not found")
+		}
+		delete(node, last)
+		return document, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		document, err = replaceAt(document, parentPointer(pointer), node)
+		return document, value, err
+	default:
+		return nil, nil, errors.New(errors.LevelError, 0, "path not found")
+	}
+}
+
+// replaceAt sets the value of an existing path, used when a changed array has to be stored back
+func replaceAt(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(document, parentPointer(pointer))
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+
+	return document, nil
+}
+
+func parentPointer(pointer string) string {
+	return pointer[:strings.LastIndex(pointer, "/")]
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for key, item := range node {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, item := range node {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		invalid  bool
+	}{
+		{name: "add", patch: `[{"op":"add","path":"/monitor","value":"ops"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"},"monitor":"ops"}`},
+		{name: "add to the end of an array", patch: `[{"op":"add","path":"/days_off/-","value":"monday"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday","monday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "add on an array index", patch: `[{"op":"add","path":"/days_off/0","value":"friday"}]`,
+			expected: `{"name":"job","days_off":["friday","saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "add out of range", patch: `[{"op":"add","path":"/days_off/3","value":"monday"}]`, invalid: true},
+		{name: "add without value", patch: `[{"op":"add","path":"/monitor"}]`, invalid: true},
+		{name: "remove", patch: `[{"op":"remove","path":"/nested"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2}`},
+		{name: "remove of an array", patch: `[{"op":"remove","path":"/days_off/1"}]`,
+			expected: `{"name":"job","days_off":["saturday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "remove the end of an array", patch: `[{"op":"remove","path":"/days_off/-"}]`, invalid: true},
+		{name: "remove out of range", patch: `[{"op":"remove","path":"/days_off/2"}]`, invalid: true},
+		{name: "remove missing", patch: `[{"op":"remove","path":"/missing"}]`, invalid: true},
+		{name: "replace", patch: `[{"op":"replace","path":"/name","value":"new"}]`,
+			expected: `{"name":"new","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "replace with null", patch: `[{"op":"replace","path":"/name","value":null}]`,
+			expected: `{"name":null,"days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "add null", patch: `[{"op":"add","path":"/monitor","value":null}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"},"monitor":null}`},
+		{name: "test null", patch: `[{"op":"add","path":"/monitor","value":null},{"op":"test","path":"/monitor","value":null}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{"key":"value"},"monitor":null}`},
+		{name: "replace missing", patch: `[{"op":"replace","path":"/missing","value":"new"}]`, invalid: true},
+		{name: "move", patch: `[{"op":"move","from":"/nested/key","path":"/key"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"m~n":2,"nested":{},"key":"value"}`},
+		{name: "move into a child", patch: `[{"op":"move","from":"/nested","path":"/nested/child"}]`, invalid: true},
+		{name: "copy", patch: `[{"op":"copy","from":"/days_off/0","path":"/days_off/-"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday","saturday"],"a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "test", patch: `[{"op":"test","path":"/days_off","value":["saturday","sunday"]},{"op":"remove","path":"/days_off"}]`,
+			expected: `{"name":"job","a/b":1,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "test failed", patch: `[{"op":"test","path":"/name","value":"other"},{"op":"remove","path":"/name"}]`, invalid: true},
+		{name: "escaped slash", patch: `[{"op":"replace","path":"/a~1b","value":10}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":10,"m~n":2,"nested":{"key":"value"}}`},
+		{name: "escaped tilde", patch: `[{"op":"remove","path":"/m~0n"}]`,
+			expected: `{"name":"job","days_off":["saturday","sunday"],"a/b":1,"nested":{"key":"value"}}`},
+		{name: "leading zero index", patch: `[{"op":"remove","path":"/days_off/01"}]`, invalid: true},
+		{name: "invalid path", patch: `[{"op":"remove","path":"name"}]`, invalid: true},
+		{name: "unknown operation", patch: `[{"op":"rename","path":"/name"}]`, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := ApplyPatch(ContentTypeJSONPatch, []byte(document), []byte(test.patch))
+			if test.invalid {
+				if err == nil {
+					t.Errorf("expected the patch to be invalid, got %s", patched)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error applying the patch: %s", err)
+			}
+			assertJSON(t, test.expected, patched)
+		})
+	}
+}
+
+func TestApplyJSONPatchNullValue(t *testing.T) {
+	patched, err := ApplyPatch(ContentTypeJSONPatch, []byte(`{"date_from":"01-01-2024"}`), []byte(`[{"op":"replace","path":"/date_from","value":null}]`))
+	if err != nil {
+		t.Fatalf("error replacing with null: %s", err)
+	}
+	assertJSON(t, `{"date_from":null}`, patched)
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	document := `{"name":"job","description":"old","nested":{"a":1,"b":{"c":2,"d":3}},"days_off":["saturday"]}`
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{name: "replace", patch: `{"name":"new"}`,
+			expected: `{"name":"new","description":"old","nested":{"a":1,"b":{"c":2,"d":3}},"days_off":["saturday"]}`},
+		{name: "null deletes the key", patch: `{"description":null}`,
+			expected: `{"name":"job","nested":{"a":1,"b":{"c":2,"d":3}},"days_off":["saturday"]}`},
+		{name: "nested merge", patch: `{"nested":{"b":{"c":null,"e":4}}}`,
+			expected: `{"name":"job","description":"old","nested":{"a":1,"b":{"d":3,"e":4}},"days_off":["saturday"]}`},
+		{name: "arrays are replaced", patch: `{"days_off":["sunday"]}`,
+			expected: `{"name":"job","description":"old","nested":{"a":1,"b":{"c":2,"d":3}},"days_off":["sunday"]}`},
+		{name: "object replaces a value", patch: `{"name":{"first":"job"}}`,
+			expected: `{"name":{"first":"job"},"description":"old","nested":{"a":1,"b":{"c":2,"d":3}},"days_off":["saturday"]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := ApplyPatch(ContentTypeMergePatch, []byte(document), []byte(test.patch))
+			if err != nil {
+				t.Fatalf("error applying the patch: %s", err)
+			}
+			assertJSON(t, test.expected, patched)
+		})
+	}
+
+	if _, err := ApplyPatch("application/json", []byte(document), []byte(`{}`)); err != ErrorUnsupportedPatch {
+		t.Errorf("expected the patch to be unsupported, got %v", err)
+	}
+}
+
+func TestPatchMediaType(t *testing.T) {
+	for contentType, expected := range map[string]string{
+		ContentTypeMergePatch:                     ContentTypeMergePatch,
+		ContentTypeMergePatch + "; charset=utf-8": ContentTypeMergePatch,
+		"Application/JSON-Patch+JSON":             ContentTypeJSONPatch,
+	} {
+		if mediaType := patchMediaType(contentType); mediaType != expected {
+			t.Errorf("expected the media type of %s to be %s, got %s", contentType, expected, mediaType)
+		}
+	}
+}
+
+func assertJSON(t *testing.T, expected string, actual []byte) {
+	t.Helper()
+
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		t.Fatalf("invalid expected json: %s", err)
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		t.Fatalf("invalid json %s: %s", actual, err)
+	}
+	if !reflect.DeepEqual(expectedValue, actualValue) {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
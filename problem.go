@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/joaosoft/web"
+)
+
+const (
+	ContentTypeProblem = "application/problem+json"
+
+	problemTypePrefix = "urn:monitor:error:"
+)
+
+// Problem is the error response of the api, following the RFC 7807 (problem details for http apis)
+type Problem struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Status   web.Status    `json:"status"`
+	Code     ErrorCode     `json:"code"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Errors   []*FieldError `json:"errors,omitempty"`
+}
+
+// ConfirmationProblem is the problem of a bulk delete without a valid confirmation, with the count of the
+// selected processes and the confirmation to send as extension members
+type ConfirmationProblem struct {
+	Problem
+	DeleteProcessesResponse
+}
+
+// NewProblem returns the problem details of an error
+func NewProblem(err error) *Problem {
+	code := errorCode(err)
+	definition := errorDefinitions[code]
+
+	problem := &Problem{
+		Type:   problemTypePrefix + strings.ToLower(strings.Replace(string(code), "_", "-", -1)),
+		Title:  definition.title,
+		Status: definition.status,
+		Code:   code,
+		Detail: err.Error(),
+	}
+
+	// the internal errors are logged, their details aren't exposed
+	if code == ErrorCodeInternal {
+		problem.Detail = "an internal error occurred"
+	}
+
+	if validation, ok := err.(*ValidationError); ok {
+		problem.Errors = validation.Fields
+	}
+
+	return problem
+}
+
+// writeProblem writes the error as a problem+json response
+func writeProblem(ctx *web.Context, err error) error {
+	problem := NewProblem(err)
+	problem.Instance = ctx.Request.Address.Url
+
+	return writeProblemBody(ctx, problem.Status, problem)
+}
+
+// writeConfirmationProblem writes the confirmation required error as a problem+json response,
+// with the count and the confirmation of the bulk delete
+func writeConfirmationProblem(ctx *web.Context, response *DeleteProcessesResponse) error {
+	problem := NewProblem(ErrorConfirmationRequired)
+	problem.Instance = ctx.Request.Address.Url
+
+	return writeProblemBody(ctx, problem.Status, &ConfirmationProblem{Problem: *problem, DeleteProcessesResponse: *response})
+}
+
+func writeProblemBody(ctx *web.Context, status web.Status, problem interface{}) error {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Response.Bytes(status, web.ContentType(ContentTypeProblem), body)
+}
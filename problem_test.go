@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/web"
+)
+
+func TestErrorCodes(t *testing.T) {
+	for code, definition := range errorDefinitions {
+		if definition.status == 0 || definition.title == "" {
+			t.Errorf("expected the code %s to have a status and a title, got %+v", code, definition)
+		}
+	}
+
+	tests := []struct {
+		err    error
+		code   ErrorCode
+		status web.Status
+	}{
+		{err: ErrorRouteNotFound, code: ErrorCodeRouteNotFound, status: web.StatusNotFound},
+		{err: ErrorProcessNotFound, code: ErrorCodeProcessNotFound, status: web.StatusNotFound},
+		{err: ErrorProcessAlreadyExists, code: ErrorCodeProcessAlreadyExists, status: web.StatusConflict},
+		{err: ErrorAlreadyRunning, code: ErrorCodeAlreadyRunning, status: web.StatusConflict},
+		{err: ErrorVersionMismatch, code: ErrorCodeVersionMismatch, status: web.StatusPreconditionFailed},
+		{err: ErrorVersionRequired, code: ErrorCodeVersionRequired, status: web.StatusPreconditionRequired},
+		{err: ErrorUnsupportedPatch, code: ErrorCodeUnsupportedPatch, status: web.StatusUnsupportedMediaType},
+		{err: ErrorBatchAborted, code: ErrorCodeBatchAborted, status: web.StatusFailedDependency},
+		{err: ErrorIdempotencyKeyInvalid, code: ErrorCodeIdempotencyKeyInvalid, status: web.StatusBadRequest},
+		{err: ErrorIdempotencyInProgress, code: ErrorCodeIdempotencyInProgress, status: web.StatusConflict},
+		{err: ErrorIdempotencyMismatch, code: ErrorCodeIdempotencyMismatch, status: web.StatusUnprocessableEntity},
+		{err: ErrorInvalidFilter, code: ErrorCodeInvalidFilter, status: web.StatusBadRequest},
+		{err: ErrorFilterRequired, code: ErrorCodeFilterRequired, status: web.StatusBadRequest},
+		{err: ErrorConfirmationRequired, code: ErrorCodeConfirmationRequired, status: web.StatusPreconditionRequired},
+		{err: errors.New(errors.LevelError, ErrorCodeWindowClosed, "closed"), code: ErrorCodeWindowClosed, status: web.StatusConflict},
+		{err: &ValidationError{}, code: ErrorCodeValidationFailed, status: web.StatusBadRequest},
+		{err: errors.New(errors.LevelError, ErrorCode("UNKNOWN"), "unknown"), code: ErrorCodeInternal, status: web.StatusInternalServerError},
+		{err: errors.New(errors.LevelError, 0, "database down"), code: ErrorCodeInternal, status: web.StatusInternalServerError},
+		{err: fmt.Errorf("database down"), code: ErrorCodeInternal, status: web.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		if code := errorCode(test.err); code != test.code {
+			t.Errorf("%s: expected the code %s, got %s", test.err, test.code, code)
+		}
+		if status := errorStatus(test.err); status != test.status {
+			t.Errorf("%s: expected the status %d, got %d", test.err, test.status, status)
+		}
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	validation := &ValidationError{Fields: []*FieldError{{Field: "name", Code: validationRequired, Message: "is required"}}}
+
+	tests := []struct {
+		name        string
+		write       func(ctx *web.Context) error
+		status      web.Status
+		code        ErrorCode
+		problemType string
+		detail      string
+		expected    func(body map[string]interface{}) bool
+	}{
+		{name: "error", write: func(ctx *web.Context) error { return writeProblem(ctx, ErrorVersionMismatch) },
+			status: web.StatusPreconditionFailed, code: ErrorCodeVersionMismatch, problemType: "version-mismatch", detail: ErrorVersionMismatch.Error()},
+		{name: "validation", write: func(ctx *web.Context) error { return writeProblem(ctx, validation) },
+			status: web.StatusBadRequest, code: ErrorCodeValidationFailed, problemType: "validation-failed", detail: "name is required",
+			expected: func(body map[string]interface{}) bool {
+				fields, ok := body["errors"].([]interface{})
+				return ok && len(fields) == 1 && fields[0].(map[string]interface{})["field"] == "name"
+			}},
+		{name: "internal", write: func(ctx *web.Context) error { return writeProblem(ctx, fmt.Errorf("password leaked")) },
+			status: web.StatusInternalServerError, code: ErrorCodeInternal, problemType: "internal-error", detail: "an internal error occurred"},
+		{name: "confirmation", write: func(ctx *web.Context) error {
+			return writeConfirmationProblem(ctx, &DeleteProcessesResponse{Count: 3, Confirmation: "token"})
+		},
+			status: web.StatusPreconditionRequired, code: ErrorCodeConfirmationRequired, problemType: "confirmation-required", detail: ErrorConfirmationRequired.Error(),
+			expected: func(body map[string]interface{}) bool {
+				return body["count"] == float64(3) && body["confirmation"] == "token"
+			}},
+	}
+
+	for _, test := range tests {
+		ctx := web.NewContext(time.Now(),
+			&web.Request{Base: web.Base{Address: web.NewAddress("http://localhost/api/v1/processes"), Headers: web.Headers{}}},
+			&web.Response{Base: web.Base{Headers: web.Headers{}}})
+
+		if err := test.write(ctx); err != nil {
+			t.Fatalf("%s: error writing the problem: %s", test.name, err)
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(ctx.Response.Body, &body); err != nil {
+			t.Fatalf("%s: invalid problem %s: %s", test.name, ctx.Response.Body, err)
+		}
+
+		if ctx.Response.Status != test.status || ctx.Response.ContentType != ContentTypeProblem {
+			t.Errorf("%s: expected the status %d as a problem, got %d %s", test.name, test.status, ctx.Response.Status, ctx.Response.ContentType)
+		}
+		if body["status"] != float64(test.status) || body["code"] != string(test.code) || body["detail"] != test.detail ||
+			body["type"] != problemTypePrefix+test.problemType || body["instance"] != "/api/v1/processes" {
+			t.Errorf("%s: unexpected problem %s", test.name, ctx.Response.Body)
+		}
+		if test.expected != nil && !test.expected(body) {
+			t.Errorf("%s: unexpected problem %s", test.name, ctx.Response.Body)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/logger"
+)
+
+// Purger permanently removes the processes that were soft deleted longer than the retention
+type Purger struct {
+	interactor *Interactor
+	retention  time.Duration
+	interval   time.Duration
+	logger     logger.ILogger
+	clock      Clock
+	quit       chan bool
+	started    bool
+	mux        sync.Mutex
+}
+
+// NewPurger ...
+func (monitor *Monitor) NewPurger(interactor *Interactor) (*Purger, error) {
+	purger := &Purger{
+		interactor: interactor,
+		retention:  DefaultPurgeRetention,
+		interval:   DefaultPurgeInterval,
+		logger:     monitor.logger,
+		clock:      monitor.clock,
+	}
+
+	if monitor.config != nil {
+		if monitor.config.Purge.Retention != "" {
+			retention, err := time.ParseDuration(monitor.config.Purge.Retention)
+			if err != nil {
+				return nil, errors.New(errors.LevelError, 0, "invalid purge retention %s: %s", monitor.config.Purge.Retention, err)
+			}
+			purger.retention = retention
+		}
+
+		if monitor.config.Purge.Interval != "" {
+			interval, err := time.ParseDuration(monitor.config.Purge.Interval)
+			if err != nil {
+				return nil, errors.New(errors.LevelError, 0, "invalid purge interval %s: %s", monitor.config.Purge.Interval, err)
+			}
+			purger.interval = interval
+		}
+	}
+
+	return purger, nil
+}
+
+// Start ...
+func (purger *Purger) Start(waitGroup ...*sync.WaitGroup) error {
+	var wg *sync.WaitGroup
+
+	if len(waitGroup) == 0 {
+		wg = &sync.WaitGroup{}
+		wg.Add(1)
+	} else {
+		wg = waitGroup[0]
+	}
+
+	defer wg.Done()
+
+	purger.mux.Lock()
+	defer purger.mux.Unlock()
+
+	if purger.started {
+		return nil
+	}
+
+	purger.quit = make(chan bool)
+	go purger.run(purger.quit)
+	purger.started = true
+
+	return nil
+}
+
+// Stop ...
+func (purger *Purger) Stop(waitGroup ...*sync.WaitGroup) error {
+	var wg *sync.WaitGroup
+
+	if len(waitGroup) == 0 {
+		wg = &sync.WaitGroup{}
+		wg.Add(1)
+	} else {
+		wg = waitGroup[0]
+	}
+
+	defer wg.Done()
+
+	purger.mux.Lock()
+	defer purger.mux.Unlock()
+
+	if !purger.started {
+		return nil
+	}
+
+	close(purger.quit)
+	purger.started = false
+
+	return nil
+}
+
+// Started ...
+func (purger *Purger) Started() bool {
+	purger.mux.Lock()
+	defer purger.mux.Unlock()
+
+	return purger.started
+}
+
+func (purger *Purger) run(quit chan bool) {
+	for {
+		select {
+		case <-purger.clock.After(purger.interval):
+			if purged, err := purger.interactor.PurgeProcesses(purger.retention); err == nil && purged > 0 {
+				purger.logger.Infof("purged %d deleted processes", purged)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
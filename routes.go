@@ -1,20 +1,72 @@
 package monitor
 
 import (
+	_ "embed"
+
 	"github.com/joaosoft/manager"
 	"github.com/joaosoft/web"
 )
 
+//go:embed assets/docs.html
+var docsPage string
+
+//go:embed assets/dashboard.html
+var dashboardPage string
+
+//go:embed assets/status.html
+var publicStatusPage string
+
 func (controller *Controller) RegisterRoutes(w manager.IWeb) error {
-	return w.AddRoutes(
+	idempotent := controller.idempotentMiddlewares()
+
+	routes := []*manager.Route{
 		manager.NewRoute(string(web.MethodOptions), "*", controller.DoNothing, web.MiddlewareOptions()),
+		// the export has to be registered before the process, so it isn't matched as an id
+		manager.NewRoute(string(web.MethodGet), "/api/v1/processes/export", controller.ExportProcessesHandler),
 		manager.NewRoute(string(web.MethodGet), "/api/v1/processes/:id", controller.GetProcessHandler),
+		manager.NewRoute(string(web.MethodGet), "/api/v1/processes/:id/history", controller.GetProcessHistoryHandler),
+		manager.NewRoute(string(web.MethodGet), "/api/v1/processes/:id/runs", controller.GetProcessRunsHandler),
 		manager.NewRoute(string(web.MethodGet), "/api/v1/processes", controller.GetProcessesHandler),
-		manager.NewRoute(string(web.MethodPost), "/api/v1/processes", controller.CreateProcessHandler),
-		manager.NewRoute(string(web.MethodPut), "/api/v1/processes/:id", controller.UpdateProcessHandler),
-		manager.NewRoute(string(web.MethodPut), "/api/v1/processes/:id/status/:status", controller.UpdateProcessStatusHandler),
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes", controller.CreateProcessHandler, idempotent...),
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes:batch", controller.BatchProcessesHandler, idempotent...),
+		// the router matches :batch as a parameter, so any other custom method would be taken by it
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes/apply", controller.ApplyProcessesHandler, idempotent...),
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes/import", controller.ImportProcessesHandler, idempotent...),
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes/simulate", controller.SimulateProcessHandler),
+		manager.NewRoute(string(web.MethodPut), "/api/v1/processes/:id", controller.UpdateProcessHandler, idempotent...),
+		manager.NewRoute(string(web.MethodPatch), "/api/v1/processes/:id", controller.PatchProcessHandler, idempotent...),
+		manager.NewRoute(string(web.MethodPut), "/api/v1/processes/:id/status/:status", controller.UpdateProcessStatusHandler, idempotent...),
 		manager.NewRoute(string(web.MethodPut), "/api/v1/processes/:id/status/:status/check", controller.UpdateProcessStatusCheckHandler),
-		manager.NewRoute(string(web.MethodDelete), "/api/v1/processes/:id", controller.DeleteProcessHandler),
-		manager.NewRoute(string(web.MethodDelete), "/api/v1/processes", controller.DeleteProcessesHandler),
-	)
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes/:id/heartbeat", controller.HeartbeatProcessHandler),
+		manager.NewRoute(string(web.MethodPost), "/api/v1/processes/:id/restore", controller.RestoreProcessHandler, idempotent...),
+		manager.NewRoute(string(web.MethodDelete), "/api/v1/processes/:id", controller.DeleteProcessHandler, idempotent...),
+		manager.NewRoute(string(web.MethodDelete), "/api/v1/processes", controller.DeleteProcessesHandler, idempotent...),
+		manager.NewRoute(string(web.MethodGet), "/api/v1/openapi.json", controller.OpenAPIHandler),
+		manager.NewRoute(string(web.MethodGet), "/api/v1/docs", controller.DocsHandler),
+		manager.NewRoute(string(web.MethodGet), "/dashboard", controller.DashboardHandler),
+		manager.NewRoute(string(web.MethodGet), "/api/v1/status", controller.GetStatusPageHandler),
+		manager.NewRoute(string(web.MethodGet), "/status", controller.StatusPageHandler),
+		// the router takes the extension as part of the parameter name
+		manager.NewRoute(string(web.MethodGet), "/badge/:id.svg", controller.BadgeHandler),
+		manager.NewRoute(string(web.MethodGet), "/metrics", controller.MetricsHandler),
+		manager.NewRoute(string(web.MethodGet), "/health/live", controller.LiveHandler),
+		manager.NewRoute(string(web.MethodGet), "/health/ready", controller.ReadyHandler),
+	}
+
+	// the metrics middleware is the first, so the latency includes the other middlewares
+	if controller.metrics != nil {
+		for _, route := range routes {
+			route.Middlewares = append([]manager.MiddlewareFunc{controller.metrics.middleware(route.Method, route.Path)}, route.Middlewares...)
+		}
+	}
+
+	return w.AddRoutes(routes...)
+}
+
+func (controller *Controller) idempotentMiddlewares() []manager.MiddlewareFunc {
+	if controller.idempotency == nil {
+		return nil
+	}
+
+	return []manager.MiddlewareFunc{controller.idempotency.Middleware()}
 }
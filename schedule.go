@@ -0,0 +1,205 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/joaosoft/types"
+)
+
+// validateSimulation validates the simulation, that needs a process or a window and a range up to the maximum
+func validateSimulation(request *SimulateProcessRequest) error {
+	validation := &ValidationError{}
+
+	if request.Body.IdProcess == "" && request.Body.Process == nil {
+		validation.Fields = append(validation.Fields, &FieldError{Field: "process", Code: validationRequired, Message: "is required without id_process"})
+	}
+
+	if request.Body.Process != nil {
+		if err := validate(request.Body.Process); err != nil {
+			for _, field := range err.(*ValidationError).Fields {
+				validation.Fields = append(validation.Fields, &FieldError{Field: "process." + field.Field, Code: field.Code, Message: field.Message})
+			}
+		}
+	}
+
+	switch {
+	case request.Body.From.IsZero():
+		validation.Fields = append(validation.Fields, &FieldError{Field: "from", Code: validationRequired, Message: "is required"})
+	case request.Body.To.IsZero():
+		validation.Fields = append(validation.Fields, &FieldError{Field: "to", Code: validationRequired, Message: "is required"})
+	case !request.Body.To.After(request.Body.From):
+		validation.Fields = append(validation.Fields, &FieldError{Field: "to", Code: validationRange, Message: "has to be after from"})
+	case request.Body.To.Sub(request.Body.From) > MaxSimulationRange:
+		validation.Fields = append(validation.Fields, &FieldError{Field: "to", Code: validationRange, Message: fmt.Sprintf("can't be more than %s after from", MaxSimulationRange)})
+	}
+
+	if len(validation.Fields) > 0 {
+		return validation
+	}
+
+	return nil
+}
+
+// window returns the window of the process
+func (process *Process) window() *ProcessWindow {
+	return &ProcessWindow{
+		DateFrom: process.DateFrom,
+		DateTo:   process.DateTo,
+		TimeFrom: process.TimeFrom,
+		TimeTo:   process.TimeTo,
+		DaysOff:  process.DaysOff,
+	}
+}
+
+// process returns a process with the window, to check its rules
+func (window *ProcessWindow) process() *Process {
+	return &Process{
+		DateFrom: window.DateFrom,
+		DateTo:   window.DateTo,
+		TimeFrom: window.TimeFrom,
+		TimeTo:   window.TimeTo,
+		DaysOff:  window.DaysOff,
+	}
+}
+
+// override returns the window with the fields set on the overrides replaced
+func (window *ProcessWindow) override(overrides *ProcessWindow) *ProcessWindow {
+	overridden := *window
+	if overrides == nil {
+		return &overridden
+	}
+
+	if overrides.DateFrom != nil {
+		overridden.DateFrom = overrides.DateFrom
+	}
+	if overrides.DateTo != nil {
+		overridden.DateTo = overrides.DateTo
+	}
+	if overrides.TimeFrom != nil {
+		overridden.TimeFrom = overrides.TimeFrom
+	}
+	if overrides.TimeTo != nil {
+		overridden.TimeTo = overrides.TimeTo
+	}
+	if overrides.DaysOff != nil {
+		overridden.DaysOff = overrides.DaysOff
+	}
+
+	return &overridden
+}
+
+// windowIntervals splits the range on the intervals where the window allows a start and the ones where it's blocked,
+// with the reasons. The intervals include the start and exclude the end, the times are on the location of the range
+func windowIntervals(window *ProcessWindow, from, to time.Time) (allowed, blocked []*ScheduleInterval) {
+	allowed, blocked = make([]*ScheduleInterval, 0), make([]*ScheduleInterval, 0)
+	process := window.process()
+
+	var last *ScheduleInterval
+	var lastAllowed bool
+	boundaries := windowBoundaries(window, from, to)
+
+	for i := 0; i < len(boundaries)-1; i++ {
+		var reasons []string
+		for _, err := range windowErrors(process, boundaries[i]) {
+			reasons = append(reasons, err.Error())
+		}
+
+		// the intervals with the same result are joined
+		isAllowed := len(reasons) == 0
+		if last != nil && lastAllowed == isAllowed && sameReasons(last.Reasons, reasons) {
+			last.To = boundaries[i+1]
+			continue
+		}
+
+		last, lastAllowed = &ScheduleInterval{From: boundaries[i], To: boundaries[i+1], Reasons: reasons}, isAllowed
+		if isAllowed {
+			allowed = append(allowed, last)
+		} else {
+			blocked = append(blocked, last)
+		}
+	}
+
+	return allowed, blocked
+}
+
+// nextWindow returns the interval of the window open at the time, or of the next one to open until the horizon.
+// The end is nil when the window doesn't close until the horizon, and both when it doesn't open
+func nextWindow(window *ProcessWindow, now time.Time, horizon time.Duration) (start, end *time.Time) {
+	process := window.process()
+	boundaries := windowBoundaries(window, now, now.Add(horizon))
+
+	for i := 0; i < len(boundaries)-1; i++ {
+		open := len(windowErrors(process, boundaries[i])) == 0
+
+		switch {
+		case start == nil && open:
+			start = &boundaries[i]
+		case start != nil && !open:
+			return start, &boundaries[i]
+		}
+	}
+
+	return start, nil
+}
+
+// windowBoundaries returns the sorted times of the range where the result of the window can change,
+// the start of each day and the times of the window, including the start and the end of the range
+func windowBoundaries(window *ProcessWindow, from, to time.Time) []time.Time {
+	boundaries := []time.Time{from, to}
+	add := func(boundary time.Time) {
+		if boundary.After(from) && boundary.Before(to) {
+			boundaries = append(boundaries, boundary)
+		}
+	}
+
+	timeFrom, hasTimeFrom := clockTime(window.TimeFrom)
+	timeTo, hasTimeTo := clockTime(window.TimeTo)
+
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()); day.Before(to); day = day.AddDate(0, 0, 1) {
+		add(day)
+		if hasTimeFrom {
+			add(time.Date(day.Year(), day.Month(), day.Day(), timeFrom.Hour(), timeFrom.Minute(), timeFrom.Second(), 0, day.Location()))
+		}
+		// the time to is included, so the window closes on the next second
+		if hasTimeTo {
+			add(time.Date(day.Year(), day.Month(), day.Day(), timeTo.Hour(), timeTo.Minute(), timeTo.Second()+1, 0, day.Location()))
+		}
+	}
+
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	unique := boundaries[:1]
+	for _, boundary := range boundaries[1:] {
+		if !boundary.Equal(unique[len(unique)-1]) {
+			unique = append(unique, boundary)
+		}
+	}
+
+	return unique
+}
+
+// clockTime parses the time of a window
+func clockTime(value *types.Time) (time.Time, bool) {
+	if value == nil {
+		return time.Time{}, false
+	}
+
+	parsed, err := time.Parse(timeLayout, string(*value))
+	return parsed, err == nil
+}
+
+func sameReasons(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowIntervals(t *testing.T) {
+	// friday, 15 of march of 2024
+	friday := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		window  ProcessWindow
+		from    time.Time
+		to      time.Time
+		allowed []string
+		blocked int
+	}{
+		{name: "without window", from: friday, to: friday.AddDate(0, 0, 3), allowed: []string{"15 00:00:00-18 00:00:00"}},
+		{name: "times", window: ProcessWindow{TimeFrom: testTime("08:00:00"), TimeTo: testTime("09:59:59")}, from: friday, to: friday.AddDate(0, 0, 2),
+			allowed: []string{"15 08:00:00-15 10:00:00", "16 08:00:00-16 10:00:00"}, blocked: 4},
+		{name: "range inside of the window", window: ProcessWindow{TimeFrom: testTime("08:00:00"), TimeTo: testTime("09:59:59")}, from: friday.Add(8*time.Hour + 30*time.Minute), to: friday.Add(9 * time.Hour),
+			allowed: []string{"15 08:30:00-15 09:00:00"}},
+		{name: "days off", window: ProcessWindow{DaysOff: testDays("saturday", "sunday")}, from: friday, to: friday.AddDate(0, 0, 4),
+			allowed: []string{"15 00:00:00-16 00:00:00", "18 00:00:00-19 00:00:00"}, blocked: 1},
+		{name: "dates", window: ProcessWindow{DateFrom: testDate("16-03-2024"), DateTo: testDate("17-03-2024")}, from: friday, to: friday.AddDate(0, 0, 4),
+			allowed: []string{"16 00:00:00-18 00:00:00"}, blocked: 2},
+		{name: "closed", window: ProcessWindow{DateTo: testDate("01-01-2024")}, from: friday, to: friday.AddDate(0, 0, 4), blocked: 1},
+		{name: "times after the days off", window: ProcessWindow{TimeFrom: testTime("12:00:00"), DaysOff: testDays("saturday")}, from: friday, to: friday.AddDate(0, 0, 2),
+			allowed: []string{"15 12:00:00-16 00:00:00"}, blocked: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allowed, blocked := windowIntervals(&test.window, test.from, test.to)
+
+			if len(allowed) != len(test.allowed) || len(blocked) != test.blocked {
+				t.Fatalf("expected %d allowed and %d blocked intervals, got %d and %d", len(test.allowed), test.blocked, len(allowed), len(blocked))
+			}
+			for i, interval := range allowed {
+				if got := interval.From.Format("02 15:04:05") + "-" + interval.To.Format("02 15:04:05"); got != test.allowed[i] {
+					t.Errorf("expected the interval %s, got %s", test.allowed[i], got)
+				}
+			}
+			for _, interval := range blocked {
+				if len(interval.Reasons) == 0 {
+					t.Errorf("expected the reasons of the blocked interval %s-%s", interval.From, interval.To)
+				}
+			}
+		})
+	}
+}
+
+func TestNextWindow(t *testing.T) {
+	// friday, 15 of march of 2024 at 10:30
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		window ProcessWindow
+		start  string
+		end    string
+	}{
+		{name: "always open", start: "15 10:30:00"},
+		{name: "open now", window: ProcessWindow{TimeTo: testTime("17:59:59")}, start: "15 10:30:00", end: "15 18:00:00"},
+		{name: "later today", window: ProcessWindow{TimeFrom: testTime("20:00:00"), TimeTo: testTime("21:59:59")}, start: "15 20:00:00", end: "15 22:00:00"},
+		{name: "tomorrow", window: ProcessWindow{TimeFrom: testTime("08:00:00"), TimeTo: testTime("09:59:59")}, start: "16 08:00:00", end: "16 10:00:00"},
+		{name: "after the days off", window: ProcessWindow{TimeFrom: testTime("08:00:00"), DaysOff: testDays("friday", "saturday", "sunday")}, start: "18 08:00:00", end: "19 00:00:00"},
+		{name: "after the date from", window: ProcessWindow{DateFrom: testDate("01-04-2024")}, start: "01 00:00:00"},
+		{name: "closed", window: ProcessWindow{DateTo: testDate("14-03-2024")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end := nextWindow(&test.window, now, MaxSimulationRange)
+
+			format := func(value *time.Time) string {
+				if value == nil {
+					return ""
+				}
+				return value.Format("02 15:04:05")
+			}
+			if format(start) != test.start || format(end) != test.end {
+				t.Errorf("expected the window %q to %q, got %q to %q", test.start, test.end, format(start), format(end))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+-- migrate up
+CREATE TABLE process (
+  id_process              VARCHAR(255) NOT NULL,
+  `type`                  VARCHAR(255) NOT NULL,
+  name                    VARCHAR(255) NOT NULL,
+  description             TEXT,
+  time_from               TIME,
+  time_to                 TIME,
+  date_from               DATE,
+  date_to                 DATE,
+  days_off                JSON,
+  monitor                 VARCHAR(255),
+  status                  VARCHAR(50),
+  created_at              DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  updated_at              DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+  deleted_at              DATETIME(6),
+  version                 BIGINT NOT NULL DEFAULT 1,
+  message                 TEXT,
+  CONSTRAINT monitor_id_process_pkey PRIMARY KEY (id_process)
+);
+
+CREATE INDEX index_process_deleted_at ON process (deleted_at);
+
+CREATE TRIGGER trigger_process_version BEFORE UPDATE ON process
+  FOR EACH ROW SET NEW.version = OLD.version + 1;
+
+
+-- HEARTBEAT
+-- the heartbeats are kept apart, so they don't change the version nor the history of the process
+CREATE TABLE process_heartbeat (
+  id_process              VARCHAR(255) NOT NULL,
+  heartbeat_at            DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  CONSTRAINT process_heartbeat_id_process_pkey PRIMARY KEY (id_process),
+  CONSTRAINT process_heartbeat_id_process_fkey FOREIGN KEY (id_process) REFERENCES process (id_process) ON DELETE CASCADE
+);
+
+
+-- HISTORY
+CREATE TABLE process_history (
+  id_process              VARCHAR(255) NOT NULL,
+  `type`                  VARCHAR(255) NOT NULL,
+  name                    VARCHAR(255) NOT NULL,
+  description             TEXT,
+  time_from               TIME,
+  time_to                 TIME,
+  date_from               DATE,
+  date_to                 DATE,
+  days_off                JSON,
+  monitor                 VARCHAR(255),
+  status                  VARCHAR(50),
+  created_at              DATETIME(6),
+  updated_at              DATETIME(6),
+  deleted_at              DATETIME(6),
+  version                 BIGINT,
+  message                 TEXT,
+  _operation              CHAR(1) NOT NULL,
+  _user                   VARCHAR(255) NOT NULL,
+  _operation_at           DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+CREATE TRIGGER trigger_process_history_insert AFTER INSERT ON process
+  FOR EACH ROW INSERT INTO process_history(id_process, `type`, name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, _user)
+  VALUES(NEW.id_process, NEW.`type`, NEW.name, NEW.description, NEW.time_from, NEW.time_to, NEW.date_from, NEW.date_to, NEW.days_off, NEW.monitor, NEW.status, NEW.created_at, NEW.updated_at, NEW.deleted_at, NEW.version, NEW.message, 'I', CURRENT_USER());
+
+CREATE TRIGGER trigger_process_history_update AFTER UPDATE ON process
+  FOR EACH ROW INSERT INTO process_history(id_process, `type`, name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, _user)
+  VALUES(NEW.id_process, NEW.`type`, NEW.name, NEW.description, NEW.time_from, NEW.time_to, NEW.date_from, NEW.date_to, NEW.days_off, NEW.monitor, NEW.status, NEW.created_at, NEW.updated_at, NEW.deleted_at, NEW.version, NEW.message, 'U', CURRENT_USER());
+
+CREATE TRIGGER trigger_process_history_delete AFTER DELETE ON process
+  FOR EACH ROW INSERT INTO process_history(id_process, `type`, name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, _user)
+  VALUES(OLD.id_process, OLD.`type`, OLD.name, OLD.description, OLD.time_from, OLD.time_to, OLD.date_from, OLD.date_to, OLD.days_off, OLD.monitor, OLD.status, OLD.created_at, OLD.updated_at, OLD.deleted_at, OLD.version, OLD.message, 'D', CURRENT_USER());
+
+
+-- migrate down
+DROP TRIGGER trigger_process_history_delete;
+DROP TRIGGER trigger_process_history_update;
+DROP TRIGGER trigger_process_history_insert;
+DROP TRIGGER trigger_process_version;
+
+DROP TABLE process_history;
+DROP TABLE process_heartbeat;
+DROP TABLE process;
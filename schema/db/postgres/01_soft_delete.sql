@@ -0,0 +1,46 @@
+-- migrate up
+ALTER TABLE monitor.process ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE monitor.process_history ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX index_process_deleted_at ON monitor.process (deleted_at);
+
+-- the history table no longer matches the process table column order, so the columns are explicit
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- migrate down
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX monitor.index_process_deleted_at;
+
+ALTER TABLE monitor.process_history DROP COLUMN deleted_at;
+ALTER TABLE monitor.process DROP COLUMN deleted_at;
@@ -0,0 +1,58 @@
+-- migrate up
+ALTER TABLE monitor.process ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE monitor.process_history ADD COLUMN version BIGINT;
+
+CREATE OR REPLACE FUNCTION monitor.function_version()
+  RETURNS TRIGGER AS $$
+  BEGIN
+   NEW.version = OLD.version + 1;
+   RETURN NEW;
+  END;
+  $$ LANGUAGE 'plpgsql';
+
+CREATE TRIGGER trigger_process_version BEFORE UPDATE
+  ON monitor.process FOR EACH ROW EXECUTE PROCEDURE monitor.function_version();
+
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- migrate down
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER trigger_process_version ON monitor.process;
+DROP FUNCTION monitor.function_version();
+
+ALTER TABLE monitor.process_history DROP COLUMN version;
+ALTER TABLE monitor.process DROP COLUMN version;
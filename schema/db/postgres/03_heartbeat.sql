@@ -0,0 +1,54 @@
+-- migrate up
+ALTER TABLE monitor.process ADD COLUMN message TEXT;
+ALTER TABLE monitor.process_history ADD COLUMN message TEXT;
+
+-- the heartbeats are kept apart, so they don't change the version nor the history of the process
+CREATE TABLE monitor.process_heartbeat (
+  id_process              TEXT NOT NULL,
+  heartbeat_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT process_heartbeat_id_process_pkey PRIMARY KEY (id_process),
+  CONSTRAINT process_heartbeat_id_process_fkey FOREIGN KEY (id_process) REFERENCES monitor.process (id_process) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, "_user", _operation_at)
+        VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- migrate down
+CREATE OR REPLACE FUNCTION function_process_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'DELETE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(OLD.*, 'D', user, now());
+        RETURN OLD;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'U', user, now());
+        RETURN NEW;
+    ELSIF (TG_OP = 'INSERT') THEN
+        INSERT INTO monitor.process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, _operation, "_user", _operation_at)
+        VALUES(NEW.*, 'I', user, now());
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE monitor.process_heartbeat;
+
+ALTER TABLE monitor.process_history DROP COLUMN message;
+ALTER TABLE monitor.process DROP COLUMN message;
@@ -0,0 +1,21 @@
+-- migrate up
+-- the storage dates the changes with the clock of the monitor, so the updated_at set by the update is kept
+CREATE OR REPLACE FUNCTION monitor.function_updated_at()
+  RETURNS TRIGGER AS $$
+  BEGIN
+   IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+     NEW.updated_at = now();
+   END IF;
+   RETURN NEW;
+  END;
+  $$ LANGUAGE 'plpgsql';
+
+
+-- migrate down
+CREATE OR REPLACE FUNCTION monitor.function_updated_at()
+  RETURNS TRIGGER AS $$
+  BEGIN
+   NEW.updated_at = now();
+   RETURN NEW;
+  END;
+  $$ LANGUAGE 'plpgsql';
@@ -0,0 +1,84 @@
+-- migrate up
+-- the version and the updated_at are set by the storage, the sqlite triggers can't change the updated row
+CREATE TABLE process (
+  id_process              TEXT NOT NULL,
+  "type"                  TEXT NOT NULL,
+  name                    TEXT NOT NULL,
+  description             TEXT,
+  time_from               TEXT,
+  time_to                 TEXT,
+  date_from               TEXT,
+  date_to                 TEXT,
+  days_off                TEXT,
+  monitor                 TEXT,
+  status                  TEXT,
+  created_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at              TIMESTAMP,
+  version                 INTEGER NOT NULL DEFAULT 1,
+  message                 TEXT,
+  CONSTRAINT monitor_id_process_pkey PRIMARY KEY (id_process)
+);
+
+CREATE INDEX index_process_deleted_at ON process (deleted_at);
+
+
+-- HEARTBEAT
+-- the heartbeats are kept apart, so they don't change the version nor the history of the process
+CREATE TABLE process_heartbeat (
+  id_process              TEXT NOT NULL,
+  heartbeat_at            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT process_heartbeat_id_process_pkey PRIMARY KEY (id_process),
+  CONSTRAINT process_heartbeat_id_process_fkey FOREIGN KEY (id_process) REFERENCES process (id_process) ON DELETE CASCADE
+);
+
+
+-- HISTORY
+CREATE TABLE process_history (
+  id_process              TEXT NOT NULL,
+  "type"                  TEXT NOT NULL,
+  name                    TEXT NOT NULL,
+  description             TEXT,
+  time_from               TEXT,
+  time_to                 TEXT,
+  date_from               TEXT,
+  date_to                 TEXT,
+  days_off                TEXT,
+  monitor                 TEXT,
+  status                  TEXT,
+  created_at              TIMESTAMP,
+  updated_at              TIMESTAMP,
+  deleted_at              TIMESTAMP,
+  version                 INTEGER,
+  message                 TEXT,
+  _operation              TEXT NOT NULL,
+  _operation_at           TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER trigger_process_history_insert AFTER INSERT ON process
+BEGIN
+    INSERT INTO process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation)
+    VALUES(NEW.id_process, NEW."type", NEW.name, NEW.description, NEW.time_from, NEW.time_to, NEW.date_from, NEW.date_to, NEW.days_off, NEW.monitor, NEW.status, NEW.created_at, NEW.updated_at, NEW.deleted_at, NEW.version, NEW.message, 'I');
+END;
+
+CREATE TRIGGER trigger_process_history_update AFTER UPDATE ON process
+BEGIN
+    INSERT INTO process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation)
+    VALUES(NEW.id_process, NEW."type", NEW.name, NEW.description, NEW.time_from, NEW.time_to, NEW.date_from, NEW.date_to, NEW.days_off, NEW.monitor, NEW.status, NEW.created_at, NEW.updated_at, NEW.deleted_at, NEW.version, NEW.message, 'U');
+END;
+
+CREATE TRIGGER trigger_process_history_delete AFTER DELETE ON process
+BEGIN
+    INSERT INTO process_history(id_process, "type", name, description, time_from, time_to, date_from, date_to, days_off, monitor, status, created_at, updated_at, deleted_at, version, message, _operation)
+    VALUES(OLD.id_process, OLD."type", OLD.name, OLD.description, OLD.time_from, OLD.time_to, OLD.date_from, OLD.date_to, OLD.days_off, OLD.monitor, OLD.status, OLD.created_at, OLD.updated_at, OLD.deleted_at, OLD.version, OLD.message, 'D');
+END;
+
+
+-- migrate down
+DROP TRIGGER trigger_process_history_delete;
+DROP TRIGGER trigger_process_history_update;
+DROP TRIGGER trigger_process_history_insert;
+
+DROP TABLE process_history;
+DROP TABLE process_heartbeat;
+DROP TABLE process;
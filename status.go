@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joaosoft/errors"
+)
+
+const ContentTypeSVG = "image/svg+xml"
+
+const (
+	badgeColorLabel   = "#555"
+	badgeColorRunning = "#007ec6"
+	badgeColorStopped = "#4c1"
+	badgeColorFailed  = "#e05d44"
+	badgeColorNone    = "#9f9f9f"
+)
+
+// StatusPage is the public read-only page with the status of the processes of each group.
+// Without groups, all the processes are shown on a single group
+type StatusPage struct {
+	title  string
+	uptime time.Duration
+	groups []*StatusPageGroup
+}
+
+// NewStatusPage ...
+func (monitor *Monitor) NewStatusPage() (*StatusPage, error) {
+	page := &StatusPage{
+		title:  DefaultStatusPageTitle,
+		uptime: DefaultStatusPageUptime,
+	}
+
+	if monitor.config != nil {
+		config := monitor.config.StatusPage
+
+		if config.Title != "" {
+			page.title = config.Title
+		}
+
+		if config.Uptime != "" {
+			uptime, err := time.ParseDuration(config.Uptime)
+			if err != nil || uptime <= 0 {
+				return nil, errors.New(errors.LevelError, 0, "invalid status page uptime %s", config.Uptime)
+			}
+			page.uptime = uptime
+		}
+
+		page.groups = config.Groups
+	}
+
+	if len(page.groups) == 0 {
+		page.groups = []*StatusPageGroup{{Name: DefaultStatusPageGroup}}
+	}
+
+	return page, nil
+}
+
+// processStatus returns the public status of the process, with its last finished run and success
+// found on the history and the uptime since the date
+func processStatus(process *Process, history ListProcessHistory, since, now time.Time) *ProcessStatus {
+	status := &ProcessStatus{
+		IdProcess: process.IdProcess,
+		Name:      process.Name,
+		Status:    process.Status,
+		Uptime:    processUptime(history, since, now),
+	}
+
+	for _, run := range processRuns(history) {
+		if run.EndedAt == nil {
+			continue
+		}
+
+		if status.LastRunStatus == nil {
+			status.LastRunStatus, status.LastRunAt = &run.Status, run.EndedAt
+		}
+
+		if run.Status == StatusStopped {
+			status.LastSuccessAt = run.EndedAt
+			break
+		}
+	}
+
+	return status
+}
+
+// processUptime returns the percentage of the time between the dates the process wasn't failed, counting only
+// since it was created. A process that didn't exist on that time is up
+func processUptime(history ListProcessHistory, from, to time.Time) float64 {
+	var total, failed time.Duration
+
+	for i, change := range history {
+		start, end := change.OperationAt, to
+		if i+1 < len(history) && history[i+1].OperationAt.Before(to) {
+			end = history[i+1].OperationAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		total += end.Sub(start)
+		if change.Status != nil && *change.Status == StatusFailed {
+			failed += end.Sub(start)
+		}
+	}
+
+	if total == 0 {
+		return 100
+	}
+
+	return math.Round(float64(total-failed)/float64(total)*10000) / 100
+}
+
+// processBadge returns the label, value and color of the badge of the process, showing its status or last success
+func processBadge(status *ProcessStatus, show, label string, now time.Time) (string, string, string) {
+	if show == BadgeLastSuccess {
+		if label == "" {
+			label = "last success"
+		}
+		if status.LastSuccessAt == nil {
+			return label, "never", badgeColorNone
+		}
+		return label, ago(now.Sub(*status.LastSuccessAt)), badgeColorStopped
+	}
+
+	if label == "" {
+		label = status.Name
+	}
+	if status.Status == nil {
+		return label, "none", badgeColorNone
+	}
+
+	switch *status.Status {
+	case StatusRunning:
+		return label, string(StatusRunning), badgeColorRunning
+	case StatusFailed:
+		return label, string(StatusFailed), badgeColorFailed
+	default:
+		return label, string(*status.Status), badgeColorStopped
+	}
+}
+
+// ago returns the duration in the largest unit, like 3 hours ago
+func ago(duration time.Duration) string {
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, unit := range units {
+		if count := int64(duration / unit.duration); count == 1 {
+			return fmt.Sprintf("1 %s ago", unit.name)
+		} else if count > 1 {
+			return fmt.Sprintf("%d %ss ago", count, unit.name)
+		}
+	}
+
+	return "just now"
+}
+
+// badge renders a flat badge, like the ones of shields.io, with the label on grey and the value on the color.
+// The width of the texts is estimated from their length
+func badge(label, value, color string) []byte {
+	labelWidth := badgeTextWidth(label)
+	valueWidth := badgeTextWidth(value)
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">`+
+		`<title>%[3]s: %[4]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="%[5]s"/><rect x="%[2]d" width="%[6]d" height="20" fill="%[7]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[8]d" y="14">%[3]s</text>`+
+		`<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[9]d" y="14">%[4]s</text></g></svg>`,
+		width, labelWidth, label, value, badgeColorLabel, valueWidth, color, labelWidth/2, labelWidth+valueWidth/2))
+}
+
+func badgeTextWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessStatus(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	running, stopped, failed := StatusRunning, StatusStopped, StatusFailed
+	change := func(hours int, status *Status) *ProcessHistory {
+		return &ProcessHistory{Process: Process{Status: status}, OperationAt: now.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	tests := []struct {
+		name        string
+		history     ListProcessHistory
+		since       time.Time
+		lastRun     Status
+		lastSuccess bool
+		uptime      float64
+	}{
+		{name: "created", history: ListProcessHistory{change(-10, nil)}, since: now.Add(-20 * time.Hour), uptime: 100},
+		{name: "running", history: ListProcessHistory{change(-10, nil), change(-5, &running)}, since: now.Add(-20 * time.Hour), uptime: 100},
+		{name: "succeeded", history: ListProcessHistory{change(-10, nil), change(-5, &running), change(-4, &stopped)},
+			since: now.Add(-20 * time.Hour), lastRun: StatusStopped, lastSuccess: true, uptime: 100},
+		{name: "failed", history: ListProcessHistory{change(-10, nil), change(-5, &running), change(-4, &stopped), change(-3, &running), change(-2, &failed)},
+			since: now.Add(-20 * time.Hour), lastRun: StatusFailed, lastSuccess: true, uptime: 80},
+		{name: "failed before the period", history: ListProcessHistory{change(-10, nil), change(-9, &running), change(-8, &failed), change(-2, &running)},
+			since: now.Add(-4 * time.Hour), lastRun: StatusFailed, uptime: 50},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := processStatus(&Process{IdProcess: "job", Name: "job"}, test.history, test.since, now)
+
+			if (status.LastRunStatus == nil && test.lastRun != "") || (status.LastRunStatus != nil && *status.LastRunStatus != test.lastRun) {
+				t.Errorf("expected the last run %q, got %v", test.lastRun, status.LastRunStatus)
+			}
+			if (status.LastSuccessAt != nil) != test.lastSuccess {
+				t.Errorf("expected the last success %t, got %v", test.lastSuccess, status.LastSuccessAt)
+			}
+			if status.Uptime != test.uptime {
+				t.Errorf("expected the uptime %.2f, got %.2f", test.uptime, status.Uptime)
+			}
+		})
+	}
+}
+
+func TestProcessBadge(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	failed := StatusFailed
+	lastSuccess := now.Add(-3 * time.Hour)
+	status := &ProcessStatus{IdProcess: "job", Name: "backup <daily>", Status: &failed, LastSuccessAt: &lastSuccess}
+
+	if label, value, color := processBadge(status, "", "", now); label != "backup <daily>" || value != "failed" || color != badgeColorFailed {
+		t.Errorf("expected the failed status, got %s %s %s", label, value, color)
+	}
+	if label, value, _ := processBadge(status, BadgeLastSuccess, "", now); label != "last success" || value != "3 hours ago" {
+		t.Errorf("expected the last success 3 hours ago, got %s %s", label, value)
+	}
+
+	svg := string(badge("backup <daily>", "failed", badgeColorFailed))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "backup &lt;daily&gt;") || strings.Contains(svg, "<daily>") {
+		t.Errorf("expected the label to be escaped on the badge, got %s", svg)
+	}
+}
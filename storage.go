@@ -2,7 +2,11 @@ package monitor
 
 import (
 	"database/sql"
+	"encoding/json"
 	"github.com/joaosoft/logger"
+	"github.com/joaosoft/types"
+	"strings"
+	"time"
 
 	"fmt"
 
@@ -10,20 +14,77 @@ import (
 	manager "github.com/joaosoft/manager"
 )
 
+// processFilterColumns maps the allowed filters to their columns
+var processFilterColumns = map[string]string{
+	"id_process": `id_process`,
+	"type":       `"type"`,
+	"name":       `"name"`,
+	"monitor":    `monitor`,
+	"status":     `status`,
+}
+
+// executor is implemented by both the connection and a transaction
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// StoragePostgres keeps the processes on postgres, the changes are dated by the clock of the monitor
 type StoragePostgres struct {
-	conn   manager.IDB
-	logger logger.ILogger
+	conn    manager.IDB
+	tx      *sql.Tx
+	clock   Clock
+	metrics *metrics
+	logger  logger.ILogger
 }
 
 func (monitor *Monitor) NewStoragePostgres(connection manager.IDB) *StoragePostgres {
 	return &StoragePostgres{
-		conn:   connection,
-		logger: monitor.logger,
+		conn:    connection,
+		clock:   monitor.clock,
+		metrics: monitor.metrics,
+		logger:  monitor.logger,
 	}
 }
 
+func (storage *StoragePostgres) db() executor {
+	if storage.tx != nil {
+		return storage.tx
+	}
+	return storage.conn.Get()
+}
+
+// Transaction executes the handler with a storage bound to a transaction,
+// that is committed when the handler succeeds and rolled back otherwise
+func (storage *StoragePostgres) Transaction(handler func(storage IStorageDB) error) error {
+	if storage.tx != nil {
+		return handler(storage)
+	}
+
+	tx, err := storage.conn.Get().Begin()
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if err := handler(&StoragePostgres{conn: storage.conn, tx: tx, clock: storage.clock, metrics: storage.metrics, logger: storage.logger}); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			storage.logger.Errorf("error rolling back transaction %s", errRollback)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
 func (storage *StoragePostgres) GetProcess(idProcess string) (*Process, error) {
-	row := storage.conn.Get().QueryRow(`
+	defer storage.metrics.query("get_process", time.Now())
+
+	row := storage.db().QueryRow(`
 	    SELECT
 		    "type",
 			"name",
@@ -35,10 +96,14 @@ func (storage *StoragePostgres) GetProcess(idProcess string) (*Process, error) {
 			days_off,
 			monitor,
 			status,
+			COALESCE(message, ''),
+			(SELECT heartbeat_at FROM monitor.process_heartbeat heartbeat WHERE heartbeat.id_process = process.id_process),
+			version,
 			updated_at,
 			created_at
 		FROM monitor.process
 		WHERE id_process = $1
+		  AND deleted_at IS NULL
 	`, idProcess)
 
 	process := &Process{IdProcess: idProcess}
@@ -53,6 +118,9 @@ func (storage *StoragePostgres) GetProcess(idProcess string) (*Process, error) {
 		&process.DaysOff,
 		&process.Monitor,
 		&process.Status,
+		&process.Message,
+		&process.HeartbeatAt,
+		&process.Version,
 		&process.UpdatedAt,
 		&process.CreatedAt); err != nil {
 
@@ -67,6 +135,8 @@ func (storage *StoragePostgres) GetProcess(idProcess string) (*Process, error) {
 }
 
 func (storage *StoragePostgres) GetProcesses(values map[string][]string) (ListProcess, error) {
+	defer storage.metrics.query("get_processes", time.Now())
+
 	query := `
 	    SELECT
 			id_process,
@@ -80,29 +150,22 @@ func (storage *StoragePostgres) GetProcesses(values map[string][]string) (ListPr
 			days_off,
 			monitor,
 			status,
+			COALESCE(message, ''),
+			(SELECT heartbeat_at FROM monitor.process_heartbeat heartbeat WHERE heartbeat.id_process = process.id_process),
+			version,
 			updated_at,
 			created_at
 		FROM monitor.process
+		WHERE deleted_at IS NULL
 	`
 
-	index := 1
-	params := make([]interface{}, 0)
-
-	if values != nil {
-		for key, value := range values {
-			if index == 1 {
-				query += ` WHERE `
-			} else {
-				query += ` AND `
-			}
-			query += fmt.Sprintf(`%s = $%d`, key, index)
-			index = index + 1
-
-			params = append(params, value[0])
-		}
+	filter, params, err := processFilter(values, processFilterColumns, postgresPlaceholder)
+	if err != nil {
+		return nil, err
 	}
+	query += filter
 
-	rows, err := storage.conn.Get().Query(query, params...)
+	rows, err := storage.db().Query(query, params...)
 	if err != nil {
 		return nil, errors.New(errors.LevelError, 0, err)
 	}
@@ -124,6 +187,9 @@ func (storage *StoragePostgres) GetProcesses(values map[string][]string) (ListPr
 			&process.DaysOff,
 			&process.Monitor,
 			&process.Status,
+			&process.Message,
+			&process.HeartbeatAt,
+			&process.Version,
 			&process.UpdatedAt,
 			&process.CreatedAt); err != nil {
 
@@ -139,7 +205,9 @@ func (storage *StoragePostgres) GetProcesses(values map[string][]string) (ListPr
 }
 
 func (storage *StoragePostgres) CreateProcess(newProcess *Process) error {
-	if _, err := storage.conn.Get().Exec(`
+	defer storage.metrics.query("create_process", time.Now())
+
+	if _, err := storage.db().Exec(`
 		INSERT INTO monitor.process(
 			id_process, 
 			"type",
@@ -151,8 +219,10 @@ func (storage *StoragePostgres) CreateProcess(newProcess *Process) error {
 			time_to,
 			days_off,
 			monitor,
-			status)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			status,
+			created_at,
+			updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
 	`,
 		newProcess.IdProcess,
 		newProcess.Type,
@@ -164,16 +234,23 @@ func (storage *StoragePostgres) CreateProcess(newProcess *Process) error {
 		newProcess.TimeTo,
 		newProcess.DaysOff,
 		newProcess.Monitor,
-		newProcess.Status); err != nil {
-		fmt.Println(err)
+		newProcess.Status,
+		storage.now()); err != nil {
+		if isUniqueViolation(err) {
+			return ErrorProcessAlreadyExists
+		}
 		return errors.New(errors.LevelError, 0, err)
 	}
 
 	return nil
 }
 
+// UpdateProcess updates the process when it's on the expected version (any version when zero)
+// and sets the process with the new version, returns ErrorVersionMismatch when nothing was updated
 func (storage *StoragePostgres) UpdateProcess(updProcess *Process) error {
-	if _, err := storage.conn.Get().Exec(`
+	defer storage.metrics.query("update_process", time.Now())
+
+	if err := storage.db().QueryRow(`
 		UPDATE monitor.process SET 
 			"type" = $1, 
 			"name" = $2, 
@@ -185,8 +262,11 @@ func (storage *StoragePostgres) UpdateProcess(updProcess *Process) error {
 			days_off = $8,
 			monitor = $9,
 			status = $10,
-			updated_at = $11
-		WHERE id_process = $12
+			updated_at = $13
+		WHERE id_process = $11
+		  AND deleted_at IS NULL
+		  AND ($12::BIGINT = 0 OR version = $12)
+		RETURNING version
 	`, updProcess.Type,
 		updProcess.Name,
 		updProcess.Description,
@@ -197,43 +277,366 @@ func (storage *StoragePostgres) UpdateProcess(updProcess *Process) error {
 		updProcess.DaysOff,
 		updProcess.Monitor,
 		updProcess.Status,
-		updProcess.UpdatedAt,
-		updProcess.IdProcess); err != nil {
+		updProcess.IdProcess,
+		updProcess.Version,
+		storage.now()).Scan(&updProcess.Version); err != nil {
+
+		if err == sql.ErrNoRows {
+			return ErrorVersionMismatch
+		}
 		return errors.New(errors.LevelError, 0, err)
 	}
 
 	return nil
 }
 
-func (storage *StoragePostgres) UpdateProcessStatus(idProcess string, status Status) error {
-	if _, err := storage.conn.Get().Exec(`
-		UPDATE monitor.process SET 
-			status = $1
-		WHERE id_process = $2
-	`, status, idProcess); err != nil {
+// UpdateProcessStatus changes the status of the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was updated. A process is only started
+// when it isn't running, otherwise ErrorAlreadyRunning is returned, so concurrent starts can't both succeed.
+// The heartbeats are cleared, so the lease of the new status starts with its first heartbeat
+func (storage *StoragePostgres) UpdateProcessStatus(idProcess string, status Status, message string, version int64) error {
+	defer storage.metrics.query("update_process_status", time.Now())
+
+	query := `
+		WITH updated AS (
+			UPDATE monitor.process SET 
+				status = $1,
+				message = NULLIF($2, ''),
+				updated_at = $5
+			WHERE id_process = $3
+			  AND deleted_at IS NULL
+			  AND ($4::BIGINT = 0 OR version = $4)`
+	if status == StatusRunning {
+		query += `
+			  AND status IS DISTINCT FROM $1`
+	}
+	query += `
+			RETURNING id_process
+		), cleared AS (
+			DELETE FROM monitor.process_heartbeat
+			WHERE id_process IN (SELECT id_process FROM updated)
+		)
+		SELECT COUNT(*) FROM updated`
+
+	var updated int64
+	if err := storage.db().QueryRow(query, status, message, idProcess, version, storage.now()).Scan(&updated); err != nil {
 		return errors.New(errors.LevelError, 0, err)
 	}
 
+	if updated > 0 {
+		return nil
+	}
+
+	if version > 0 {
+		return ErrorVersionMismatch
+	}
+
+	return statusNotUpdated(storage, idProcess, status)
+}
+
+// now returns the time of the clock on UTC, as the created_at and updated_at are kept without time zone
+func (storage *StoragePostgres) now() time.Time {
+	return storage.clock.Now().UTC()
+}
+
+// statusNotUpdated returns ErrorAlreadyRunning when the update to start a process that exists didn't change it
+func statusNotUpdated(storage IStorageDB, idProcess string, status Status) error {
+	if status != StatusRunning {
+		return nil
+	}
+
+	if process, err := storage.GetProcess(idProcess); err != nil {
+		return err
+	} else if process != nil {
+		return ErrorAlreadyRunning
+	}
+
 	return nil
 }
 
-func (storage *StoragePostgres) DeleteProcess(idProcess string) error {
-	if _, err := storage.conn.Get().Exec(`
-	    DELETE 
+// HeartbeatProcess records the heartbeat of a running process, returns false when it isn't running.
+// The heartbeats are kept apart, so they don't change the version nor the history of the process
+func (storage *StoragePostgres) HeartbeatProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("heartbeat_process", time.Now())
+
+	result, err := storage.db().Exec(`
+		INSERT INTO monitor.process_heartbeat(id_process, heartbeat_at)
+		SELECT id_process, $3::TIMESTAMPTZ
 		FROM monitor.process
 		WHERE id_process = $1
-	`, idProcess); err != nil {
-		return errors.New(errors.LevelError, 0, err)
+		  AND deleted_at IS NULL
+		  AND status = $2
+		ON CONFLICT (id_process) DO UPDATE SET
+			heartbeat_at = excluded.heartbeat_at
+	`, idProcess, StatusRunning, storage.now())
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
 	}
 
-	return nil
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
 }
 
-func (storage *StoragePostgres) DeleteProcesses() error {
-	if _, err := storage.conn.Get().Exec(`
-	    DELETE FROM monitor.process`); err != nil {
+// ExpireProcess sets a running process as failed when its last heartbeat is older than heartbeatBefore,
+// returns false when it isn't running or its lease didn't expire
+func (storage *StoragePostgres) ExpireProcess(idProcess string, heartbeatBefore time.Time) (bool, error) {
+	defer storage.metrics.query("expire_process", time.Now())
+
+	var expired int64
+	if err := storage.db().QueryRow(`
+		WITH expired AS (
+			UPDATE monitor.process SET
+				status = $2,
+				message = $3,
+				updated_at = $6
+			WHERE id_process = $1
+			  AND deleted_at IS NULL
+			  AND status = $4
+			  AND id_process IN (SELECT id_process FROM monitor.process_heartbeat WHERE heartbeat_at < $5)
+			RETURNING id_process
+		), cleared AS (
+			DELETE FROM monitor.process_heartbeat
+			WHERE id_process IN (SELECT id_process FROM expired)
+		)
+		SELECT COUNT(*) FROM expired
+	`, idProcess, StatusFailed, MessageLeaseExpired, StatusRunning, heartbeatBefore, storage.now()).Scan(&expired); err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return expired > 0, nil
+}
+
+// DeleteProcess deletes the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when nothing was deleted
+func (storage *StoragePostgres) DeleteProcess(idProcess string, version int64) error {
+	defer storage.metrics.query("delete_process", time.Now())
+
+	now := storage.now()
+	result, err := storage.db().Exec(`
+	    UPDATE monitor.process SET
+			deleted_at = $3,
+			updated_at = $4
+		WHERE id_process = $1
+		  AND deleted_at IS NULL
+		  AND ($2::BIGINT = 0 OR version = $2)
+	`, idProcess, version, now, now)
+	if err != nil {
 		return errors.New(errors.LevelError, 0, err)
 	}
 
+	if affected, err := result.RowsAffected(); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	} else if affected == 0 {
+		return ErrorVersionMismatch
+	}
+
 	return nil
 }
+
+func (storage *StoragePostgres) DeleteProcesses(values map[string][]string) (int64, error) {
+	defer storage.metrics.query("delete_processes", time.Now())
+
+	query := `
+	    UPDATE monitor.process SET
+			deleted_at = $1,
+			updated_at = $2
+		WHERE deleted_at IS NULL
+	`
+
+	filter, params, err := processFilter(values, processFilterColumns, func(position int) string {
+		return postgresPlaceholder(position + 2)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	now := storage.now()
+	result, err := storage.db().Exec(query+filter, append([]interface{}{now, now}, params...)...)
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (storage *StoragePostgres) RestoreProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("restore_process", time.Now())
+
+	result, err := storage.db().Exec(`
+	    UPDATE monitor.process SET
+			deleted_at = NULL,
+			updated_at = $2
+		WHERE id_process = $1
+		  AND deleted_at IS NOT NULL
+	`, idProcess, storage.now())
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
+}
+
+func (storage *StoragePostgres) PurgeProcesses(deletedBefore time.Time) (int64, error) {
+	defer storage.metrics.query("purge_processes", time.Now())
+
+	result, err := storage.db().Exec(`
+	    DELETE
+		FROM monitor.process
+		WHERE deleted_at IS NOT NULL
+		  AND deleted_at < $1
+	`, deletedBefore)
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (storage *StoragePostgres) GetProcessHistory(idProcess string) (ListProcessHistory, error) {
+	defer storage.metrics.query("get_process_history", time.Now())
+
+	rows, err := storage.db().Query(`
+	    SELECT
+		    "type",
+			"name",
+			COALESCE(description, ''),
+			date_from,
+			date_to,
+			time_from,
+			time_to,
+			days_off,
+			monitor,
+			status,
+			COALESCE(message, ''),
+			COALESCE(version, 0),
+			updated_at,
+			created_at,
+			deleted_at,
+			_operation,
+			_operation_at
+		FROM monitor.process_history
+		WHERE id_process = $1
+		ORDER BY _operation_at, version
+	`, idProcess)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	defer rows.Close()
+
+	history := make(ListProcessHistory, 0)
+	for rows.Next() {
+		change := &ProcessHistory{Process: Process{IdProcess: idProcess}}
+		if err := rows.Scan(
+			&change.Type,
+			&change.Name,
+			&change.Description,
+			&change.DateFrom,
+			&change.DateTo,
+			&change.TimeFrom,
+			&change.TimeTo,
+			&change.DaysOff,
+			&change.Monitor,
+			&change.Status,
+			&change.Message,
+			&change.Version,
+			&change.UpdatedAt,
+			&change.CreatedAt,
+			&change.DeletedAt,
+			&change.Operation,
+			&change.OperationAt); err != nil {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return history, nil
+}
+
+// processFilter builds the conditions, on the columns of the filters, to append to a query already filtering
+// by deleted_at, a filter with several values matches any of them
+func processFilter(values map[string][]string, columns map[string]string, placeholder func(position int) string) (string, []interface{}, error) {
+	var query string
+	params := make([]interface{}, 0)
+
+	for key, value := range values {
+		if _, ok := columns[key]; !ok {
+			return "", nil, ErrorInvalidFilter
+		}
+
+		placeholders := make([]string, 0, len(value))
+		for _, item := range value {
+			params = append(params, item)
+			placeholders = append(placeholders, placeholder(len(params)))
+		}
+
+		if len(placeholders) == 0 {
+			continue
+		}
+		query += fmt.Sprintf(` AND %s IN (%s)`, columns[key], strings.Join(placeholders, ", "))
+	}
+
+	return query, params, nil
+}
+
+// postgresPlaceholder returns the placeholder of the parameter on the position, starting at 1
+func postgresPlaceholder(position int) string {
+	return fmt.Sprintf(`$%d`, position)
+}
+
+// questionPlaceholder returns the placeholder of any parameter, that are bound by their order
+func questionPlaceholder(position int) string {
+	return `?`
+}
+
+// jsonDaysOff returns the days off as a json array, or nil when they aren't set
+func jsonDaysOff(daysOff *types.ListDay) (interface{}, error) {
+	if daysOff == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(daysOff)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return string(data), nil
+}
+
+// toNullString returns the value as a string, or nil when it isn't set
+func toNullString[T ~string](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return string(*value)
+}
+
+func fromNullString[T ~string](value sql.NullString) *T {
+	if !value.Valid {
+		return nil
+	}
+	return pointer(T(value.String))
+}
+
+// isUniqueViolation checks if the error of the driver is an unique violation (sql state 23505)
+func isUniqueViolation(err error) bool {
+	if e, ok := err.(interface{ SQLState() string }); ok {
+		return e.SQLState() == "23505"
+	}
+	return false
+}
@@ -0,0 +1,473 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joaosoft/types"
+)
+
+// StorageMemory keeps the processes in memory, with the same semantics of the postgres storage:
+// versions, soft deletes, heartbeats and the history of the changes.
+// The transactions hold the storage while running, so they are isolated and the changes are
+// only seen when committed
+type StorageMemory struct {
+	db    *memoryDB
+	state *memoryState
+}
+
+type memoryDB struct {
+	state *memoryState
+	clock Clock
+	mux   sync.Mutex
+}
+
+type memoryState struct {
+	processes map[string]*memoryProcess
+	history   ListProcessHistory
+}
+
+type memoryProcess struct {
+	process   Process
+	deletedAt *time.Time
+}
+
+// NewStorageMemory creates the storage with the clock of the monitor, that dates the changes.
+// Without clock, the changes are dated by the system clock
+func NewStorageMemory(clock Clock) *StorageMemory {
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	return &StorageMemory{
+		db: &memoryDB{
+			state: &memoryState{
+				processes: make(map[string]*memoryProcess),
+				history:   make(ListProcessHistory, 0),
+			},
+			clock: clock,
+		},
+	}
+}
+
+// execute executes the handler with the state of the transaction, or holding the storage when outside of one
+func (storage *StorageMemory) execute(handler func(state *memoryState) error) error {
+	if storage.state != nil {
+		return handler(storage.state)
+	}
+
+	storage.db.mux.Lock()
+	defer storage.db.mux.Unlock()
+
+	return handler(storage.db.state)
+}
+
+// Transaction executes the handler with a copy of the storage,
+// that replaces the storage when the handler succeeds and is discarded otherwise
+func (storage *StorageMemory) Transaction(handler func(storage IStorageDB) error) error {
+	if storage.state != nil {
+		return handler(storage)
+	}
+
+	storage.db.mux.Lock()
+	defer storage.db.mux.Unlock()
+
+	state := storage.db.state.clone()
+	if err := handler(&StorageMemory{db: storage.db, state: state}); err != nil {
+		return err
+	}
+
+	storage.db.state = state
+	return nil
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (storage *StorageMemory) GetProcessHistory(idProcess string) (ListProcessHistory, error) {
+	history := make(ListProcessHistory, 0)
+
+	err := storage.execute(func(state *memoryState) error {
+		for _, change := range state.history {
+			if change.IdProcess == idProcess {
+				copied := *change
+				copied.Process = copyProcess(change.Process)
+				history = append(history, &copied)
+			}
+		}
+		return nil
+	})
+
+	return history, err
+}
+
+func (storage *StorageMemory) GetProcess(idProcess string) (*Process, error) {
+	var process *Process
+
+	err := storage.execute(func(state *memoryState) error {
+		if stored, ok := state.processes[idProcess]; ok && stored.deletedAt == nil {
+			copied := copyProcess(stored.process)
+			process = &copied
+		}
+		return nil
+	})
+
+	return process, err
+}
+
+func (storage *StorageMemory) GetProcesses(values map[string][]string) (ListProcess, error) {
+	processes := make(ListProcess, 0)
+
+	err := storage.execute(func(state *memoryState) error {
+		stored, err := state.find(values)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range stored {
+			copied := copyProcess(item.process)
+			processes = append(processes, &copied)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return processes, nil
+}
+
+func (storage *StorageMemory) CreateProcess(newProcess *Process) error {
+	return storage.execute(func(state *memoryState) error {
+		if _, ok := state.processes[newProcess.IdProcess]; ok {
+			return ErrorProcessAlreadyExists
+		}
+
+		now := storage.db.clock.Now()
+		stored := &memoryProcess{
+			process: copyProcess(Process{
+				IdProcess:   newProcess.IdProcess,
+				Type:        newProcess.Type,
+				Name:        newProcess.Name,
+				Description: newProcess.Description,
+				DateFrom:    newProcess.DateFrom,
+				DateTo:      newProcess.DateTo,
+				TimeFrom:    newProcess.TimeFrom,
+				TimeTo:      newProcess.TimeTo,
+				DaysOff:     newProcess.DaysOff,
+				Monitor:     newProcess.Monitor,
+				Status:      newProcess.Status,
+				Version:     1,
+				UpdatedAt:   now,
+				CreatedAt:   now,
+			}),
+		}
+
+		state.processes[newProcess.IdProcess] = stored
+		state.record(stored, HistoryInsert, now)
+		return nil
+	})
+}
+
+// UpdateProcess updates the process when it's on the expected version (any version when zero)
+// and sets the process with the new version, returns ErrorVersionMismatch when nothing was updated
+func (storage *StorageMemory) UpdateProcess(updProcess *Process) error {
+	return storage.execute(func(state *memoryState) error {
+		stored, ok := state.processes[updProcess.IdProcess]
+		if !ok || stored.deletedAt != nil || (updProcess.Version != 0 && updProcess.Version != stored.process.Version) {
+			return ErrorVersionMismatch
+		}
+
+		copied := copyProcess(*updProcess)
+		stored.process.Type = copied.Type
+		stored.process.Name = copied.Name
+		stored.process.Description = copied.Description
+		stored.process.DateFrom = copied.DateFrom
+		stored.process.DateTo = copied.DateTo
+		stored.process.TimeFrom = copied.TimeFrom
+		stored.process.TimeTo = copied.TimeTo
+		stored.process.DaysOff = copied.DaysOff
+		stored.process.Monitor = copied.Monitor
+		stored.process.Status = copied.Status
+		state.update(stored, storage.db.clock.Now())
+
+		updProcess.Version = stored.process.Version
+		return nil
+	})
+}
+
+// UpdateProcessStatus changes the status of the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was updated.
+// A process is only started when it isn't running, otherwise ErrorAlreadyRunning is returned.
+// The heartbeat is cleared, so the lease of the new status starts with its first heartbeat
+func (storage *StorageMemory) UpdateProcessStatus(idProcess string, status Status, message string, version int64) error {
+	return storage.execute(func(state *memoryState) error {
+		stored, ok := state.processes[idProcess]
+		if !ok || stored.deletedAt != nil || (version != 0 && version != stored.process.Version) {
+			if version > 0 {
+				return ErrorVersionMismatch
+			}
+			return nil
+		}
+
+		if status == StatusRunning && stored.process.Status != nil && *stored.process.Status == StatusRunning {
+			return ErrorAlreadyRunning
+		}
+
+		stored.process.Status = &status
+		stored.process.Message = message
+		stored.process.HeartbeatAt = nil
+		state.update(stored, storage.db.clock.Now())
+		return nil
+	})
+}
+
+// HeartbeatProcess records the heartbeat of a running process, returns false when it isn't running.
+// The heartbeats don't change the version nor the history of the process
+func (storage *StorageMemory) HeartbeatProcess(idProcess string) (bool, error) {
+	var alive bool
+
+	err := storage.execute(func(state *memoryState) error {
+		stored, ok := state.processes[idProcess]
+		if !ok || stored.deletedAt != nil || stored.process.Status == nil || *stored.process.Status != StatusRunning {
+			return nil
+		}
+
+		now := storage.db.clock.Now()
+		stored.process.HeartbeatAt = &now
+		alive = true
+		return nil
+	})
+
+	return alive, err
+}
+
+// ExpireProcess sets a running process as failed when its last heartbeat is older than heartbeatBefore,
+// returns false when it isn't running or its lease didn't expire
+func (storage *StorageMemory) ExpireProcess(idProcess string, heartbeatBefore time.Time) (bool, error) {
+	var expired bool
+
+	err := storage.execute(func(state *memoryState) error {
+		stored, ok := state.processes[idProcess]
+		if !ok || stored.deletedAt != nil || stored.process.Status == nil || *stored.process.Status != StatusRunning ||
+			stored.process.HeartbeatAt == nil || !stored.process.HeartbeatAt.Before(heartbeatBefore) {
+			return nil
+		}
+
+		stored.process.Status = pointer(StatusFailed)
+		stored.process.Message = MessageLeaseExpired
+		stored.process.HeartbeatAt = nil
+		state.update(stored, storage.db.clock.Now())
+		expired = true
+		return nil
+	})
+
+	return expired, err
+}
+
+// DeleteProcess deletes the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was deleted
+func (storage *StorageMemory) DeleteProcess(idProcess string, version int64) error {
+	return storage.execute(func(state *memoryState) error {
+		stored, ok := state.processes[idProcess]
+		if !ok || stored.deletedAt != nil || (version != 0 && version != stored.process.Version) {
+			if version > 0 {
+				return ErrorVersionMismatch
+			}
+			return nil
+		}
+
+		state.delete(stored, storage.db.clock.Now())
+		return nil
+	})
+}
+
+func (storage *StorageMemory) DeleteProcesses(values map[string][]string) (int64, error) {
+	var deleted int64
+
+	err := storage.execute(func(state *memoryState) error {
+		stored, err := state.find(values)
+		if err != nil {
+			return err
+		}
+
+		now := storage.db.clock.Now()
+		for _, item := range stored {
+			state.delete(item, now)
+		}
+		deleted = int64(len(stored))
+		return nil
+	})
+
+	return deleted, err
+}
+
+func (storage *StorageMemory) RestoreProcess(idProcess string) (bool, error) {
+	var restored bool
+
+	err := storage.execute(func(state *memoryState) error {
+		if stored, ok := state.processes[idProcess]; ok && stored.deletedAt != nil {
+			stored.deletedAt = nil
+			state.update(stored, storage.db.clock.Now())
+			restored = true
+		}
+		return nil
+	})
+
+	return restored, err
+}
+
+func (storage *StorageMemory) PurgeProcesses(deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := storage.execute(func(state *memoryState) error {
+		now := storage.db.clock.Now()
+		for _, id := range state.ids() {
+			stored := state.processes[id]
+			if stored.deletedAt != nil && stored.deletedAt.Before(deletedBefore) {
+				delete(state.processes, id)
+				state.record(stored, HistoryDelete, now)
+				purged++
+			}
+		}
+		return nil
+	})
+
+	return purged, err
+}
+
+// clone copies the state, the processes are copied so the changes on the copy don't change the state
+func (state *memoryState) clone() *memoryState {
+	cloned := &memoryState{
+		processes: make(map[string]*memoryProcess, len(state.processes)),
+		history:   append(make(ListProcessHistory, 0, len(state.history)), state.history...),
+	}
+
+	for id, stored := range state.processes {
+		cloned.processes[id] = &memoryProcess{process: copyProcess(stored.process), deletedAt: stored.deletedAt}
+	}
+
+	return cloned
+}
+
+// copyProcess copies the process with the values of its pointers,
+// so the stored processes don't share them with the callers
+func copyProcess(process Process) Process {
+	if process.DateFrom != nil {
+		process.DateFrom = pointer(*process.DateFrom)
+	}
+	if process.DateTo != nil {
+		process.DateTo = pointer(*process.DateTo)
+	}
+	if process.TimeFrom != nil {
+		process.TimeFrom = pointer(*process.TimeFrom)
+	}
+	if process.TimeTo != nil {
+		process.TimeTo = pointer(*process.TimeTo)
+	}
+	if process.DaysOff != nil {
+		process.DaysOff = pointer(append(make(types.ListDay, 0, len(*process.DaysOff)), *process.DaysOff...))
+	}
+	if process.Status != nil {
+		process.Status = pointer(*process.Status)
+	}
+	if process.HeartbeatAt != nil {
+		process.HeartbeatAt = pointer(*process.HeartbeatAt)
+	}
+
+	return process
+}
+
+// ids returns the ids of the processes, sorted
+func (state *memoryState) ids() []string {
+	ids := make([]string, 0, len(state.processes))
+	for id := range state.processes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// find returns the processes not deleted that match the filter, sorted by id,
+// a filter with several values matches any of them
+func (state *memoryState) find(values map[string][]string) ([]*memoryProcess, error) {
+	for key := range values {
+		if _, ok := processFilterColumns[key]; !ok {
+			return nil, ErrorInvalidFilter
+		}
+	}
+
+	found := make([]*memoryProcess, 0)
+	for _, id := range state.ids() {
+		stored := state.processes[id]
+		if stored.deletedAt == nil && stored.matches(values) {
+			found = append(found, stored)
+		}
+	}
+
+	return found, nil
+}
+
+func (stored *memoryProcess) matches(values map[string][]string) bool {
+	for key, allowed := range values {
+		if len(allowed) == 0 {
+			continue
+		}
+
+		var value *string
+		switch key {
+		case "id_process":
+			value = &stored.process.IdProcess
+		case "type":
+			value = &stored.process.Type
+		case "name":
+			value = &stored.process.Name
+		case "monitor":
+			value = &stored.process.Monitor
+		case "status":
+			if stored.process.Status != nil {
+				value = pointer(string(*stored.process.Status))
+			}
+		}
+
+		// like on sql, a process without the value doesn't match any value
+		found := false
+		for _, item := range allowed {
+			if value != nil && *value == item {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// update sets the new version of the process, like the triggers of the process table
+func (state *memoryState) update(stored *memoryProcess, now time.Time) {
+	stored.process.Version++
+	stored.process.UpdatedAt = now
+	state.record(stored, HistoryUpdate, now)
+}
+
+func (state *memoryState) delete(stored *memoryProcess, now time.Time) {
+	stored.deletedAt = &now
+	state.update(stored, now)
+}
+
+// record adds the process, as it is, to the history
+func (state *memoryState) record(stored *memoryProcess, operation HistoryOperation, now time.Time) {
+	process := copyProcess(stored.process)
+	process.HeartbeatAt = nil
+
+	state.history = append(state.history, &ProcessHistory{
+		Process:     process,
+		DeletedAt:   stored.deletedAt,
+		Operation:   operation,
+		OperationAt: now,
+	})
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joaosoft/types"
+)
+
+func TestStorageMemory(t *testing.T) {
+	storage := NewStorageMemory(nil)
+
+	if err := storage.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	// a failed transaction doesn't change the storage
+	err := storage.Transaction(func(tx IStorageDB) error {
+		tx.UpdateProcessStatus("job", StatusRunning, "", 0)
+		return fmt.Errorf("rollback")
+	})
+	if process, _ := storage.GetProcess("job"); err == nil || process.Status != nil || process.Version != 1 {
+		t.Fatalf("expected the transaction to be rolled back, got %v %+v", err, process)
+	}
+
+	if err := storage.UpdateProcessStatus("job", StatusStopped, "done", 0); err != nil {
+		t.Fatalf("error updating status: %s", err)
+	}
+	if err := storage.DeleteProcess("job", 1); err != ErrorVersionMismatch {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+	if err := storage.DeleteProcess("job", 2); err != nil {
+		t.Fatalf("error deleting process: %s", err)
+	}
+
+	var operations string
+	history, _ := storage.GetProcessHistory("job")
+	for _, change := range history {
+		operations += fmt.Sprintf("%s%d ", change.Operation, change.Version)
+	}
+	if operations != "I1 U2 U3 " {
+		t.Errorf("expected the history I1 U2 U3, got %s", operations)
+	}
+}
+
+func TestStorageMemoryCopies(t *testing.T) {
+	storage := NewStorageMemory(nil)
+
+	status := StatusStopped
+	days := types.ListDay{"saturday"}
+	process := &Process{IdProcess: "job", Type: "cron", Name: "job", Status: &status, DaysOff: &days}
+	if err := storage.CreateProcess(process); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+
+	// changing the values of the callers doesn't change the storage
+	status, days[0] = StatusRunning, "sunday"
+	got, _ := storage.GetProcess("job")
+	*got.Status = StatusFailed
+	(*got.DaysOff)[0] = "monday"
+
+	stored, _ := storage.GetProcess("job")
+	history, _ := storage.GetProcessHistory("job")
+	for _, stored := range []Process{*stored, history[0].Process} {
+		if *stored.Status != StatusStopped || (*stored.DaysOff)[0] != "saturday" {
+			t.Errorf("expected the stored process to be unchanged, got %s %v", *stored.Status, *stored.DaysOff)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package monitor
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+	"github.com/joaosoft/types"
+)
+
+//go:embed schema/db/mysql/*.sql
+var mysqlSchema embed.FS
+
+// mysqlMigrations are the migrations of mysql, embedded like the migrations of sqlite
+var mysqlMigrations, _ = fs.Sub(mysqlSchema, "schema/db/mysql")
+
+// mysqlFilterColumns maps the allowed filters to their columns on mysql
+var mysqlFilterColumns = map[string]string{
+	"id_process": "id_process",
+	"type":       "`type`",
+	"name":       "name",
+	"monitor":    "monitor",
+	"status":     "status",
+}
+
+// StorageMySQL keeps the processes on mysql, with the same semantics of the postgres storage.
+// The version and the history are kept by the table and its triggers, the days off are kept as a json array,
+// and the changes are dated by the clock of the monitor
+type StorageMySQL struct {
+	conn    manager.IDB
+	tx      *sql.Tx
+	clock   Clock
+	metrics *metrics
+	logger  logger.ILogger
+}
+
+func (monitor *Monitor) NewStorageMySQL(connection manager.IDB) *StorageMySQL {
+	return &StorageMySQL{
+		conn:    connection,
+		clock:   monitor.clock,
+		metrics: monitor.metrics,
+		logger:  monitor.logger,
+	}
+}
+
+// mysqlDataSource returns the data source with the options needed by the storage: the dates parsed,
+// on UTC on both the connection and the session, and, for the migrations, several statements on each query
+func mysqlDataSource(dataSource string, multiStatements bool) (string, error) {
+	config, err := mysql.ParseDSN(dataSource)
+	if err != nil {
+		return "", errors.New(errors.LevelError, 0, "invalid mysql datasource: %s", err)
+	}
+
+	config.ParseTime = true
+	config.Loc = time.UTC
+	config.MultiStatements = multiStatements
+	if config.Params == nil {
+		config.Params = make(map[string]string)
+	}
+	config.Params["time_zone"] = "'+00:00'"
+
+	return config.FormatDSN(), nil
+}
+
+func (storage *StorageMySQL) db() executor {
+	if storage.tx != nil {
+		return storage.tx
+	}
+	return storage.conn.Get()
+}
+
+// Transaction executes the handler with a storage bound to a transaction,
+// that is committed when the handler succeeds and rolled back otherwise
+func (storage *StorageMySQL) Transaction(handler func(storage IStorageDB) error) error {
+	if storage.tx != nil {
+		return handler(storage)
+	}
+
+	tx, err := storage.conn.Get().Begin()
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if err := handler(&StorageMySQL{conn: storage.conn, tx: tx, clock: storage.clock, metrics: storage.metrics, logger: storage.logger}); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			storage.logger.Errorf("error rolling back transaction %s", errRollback)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// mysqlProcess is the process as read from mysql
+type mysqlProcess struct {
+	process  Process
+	dateFrom sql.NullTime
+	dateTo   sql.NullTime
+	timeFrom sql.NullString
+	timeTo   sql.NullString
+	daysOff  sql.NullString
+	monitor  sql.NullString
+	status   sql.NullString
+}
+
+func (row *mysqlProcess) fields() []interface{} {
+	return []interface{}{
+		&row.process.IdProcess,
+		&row.process.Type,
+		&row.process.Name,
+		&row.process.Description,
+		&row.dateFrom,
+		&row.dateTo,
+		&row.timeFrom,
+		&row.timeTo,
+		&row.daysOff,
+		&row.monitor,
+		&row.status,
+		&row.process.Message,
+		&row.process.HeartbeatAt,
+		&row.process.Version,
+		&row.process.UpdatedAt,
+		&row.process.CreatedAt,
+	}
+}
+
+func (row *mysqlProcess) toProcess() (*Process, error) {
+	process := row.process
+	process.DateFrom = fromNullDate(row.dateFrom)
+	process.DateTo = fromNullDate(row.dateTo)
+	process.TimeFrom = fromNullString[types.Time](row.timeFrom)
+	process.TimeTo = fromNullString[types.Time](row.timeTo)
+	process.Monitor = row.monitor.String
+	process.Status = fromNullString[Status](row.status)
+
+	if row.daysOff.Valid {
+		daysOff := make(types.ListDay, 0)
+		if err := json.Unmarshal([]byte(row.daysOff.String), &daysOff); err != nil {
+			return nil, errors.New(errors.LevelError, 0, "invalid days off %s of the process %s: %s", row.daysOff.String, process.IdProcess, err)
+		}
+		process.DaysOff = &daysOff
+	}
+
+	return &process, nil
+}
+
+const mysqlSelectProcess = `
+    SELECT
+		id_process,
+		` + "`type`" + `,
+		name,
+		COALESCE(description, ''),
+		date_from,
+		date_to,
+		time_from,
+		time_to,
+		days_off,
+		monitor,
+		status,
+		COALESCE(message, ''),
+		(SELECT heartbeat_at FROM process_heartbeat heartbeat WHERE heartbeat.id_process = process.id_process),
+		version,
+		updated_at,
+		created_at
+	FROM process
+	WHERE deleted_at IS NULL
+`
+
+func (storage *StorageMySQL) GetProcess(idProcess string) (*Process, error) {
+	defer storage.metrics.query("get_process", time.Now())
+
+	row := &mysqlProcess{}
+	if err := storage.db().QueryRow(mysqlSelectProcess+` AND id_process = ?`, idProcess).Scan(row.fields()...); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		return nil, nil
+	}
+
+	return row.toProcess()
+}
+
+func (storage *StorageMySQL) GetProcesses(values map[string][]string) (ListProcess, error) {
+	defer storage.metrics.query("get_processes", time.Now())
+
+	filter, params, err := processFilter(values, mysqlFilterColumns, questionPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := storage.db().Query(mysqlSelectProcess+filter+` ORDER BY id_process`, params...)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	defer rows.Close()
+
+	processes := make(ListProcess, 0)
+	for rows.Next() {
+		row := &mysqlProcess{}
+		if err := rows.Scan(row.fields()...); err != nil {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		process, err := row.toProcess()
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, process)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return processes, nil
+}
+
+func (storage *StorageMySQL) CreateProcess(newProcess *Process) error {
+	defer storage.metrics.query("create_process", time.Now())
+
+	daysOff, err := jsonDaysOff(newProcess.DaysOff)
+	if err != nil {
+		return err
+	}
+
+	now := storage.now()
+	if _, err := storage.db().Exec(`
+		INSERT INTO process(
+			id_process,
+			`+"`type`"+`,
+			name,
+			description,
+			date_from,
+			date_to,
+			time_from,
+			time_to,
+			days_off,
+			monitor,
+			status,
+			created_at,
+			updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		newProcess.IdProcess,
+		newProcess.Type,
+		newProcess.Name,
+		newProcess.Description,
+		toNullDate(newProcess.DateFrom),
+		toNullDate(newProcess.DateTo),
+		toNullString(newProcess.TimeFrom),
+		toNullString(newProcess.TimeTo),
+		daysOff,
+		newProcess.Monitor,
+		toNullString(newProcess.Status),
+		now,
+		now); err != nil {
+		if isMySQLUniqueViolation(err) {
+			return ErrorProcessAlreadyExists
+		}
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// UpdateProcess updates the process when it's on the expected version (any version when zero)
+// and sets the process with the new version, returns ErrorVersionMismatch when nothing was updated
+func (storage *StorageMySQL) UpdateProcess(updProcess *Process) error {
+	defer storage.metrics.query("update_process", time.Now())
+
+	daysOff, err := jsonDaysOff(updProcess.DaysOff)
+	if err != nil {
+		return err
+	}
+
+	// mysql doesn't return the updated rows, so the update and the read of the version are on a transaction
+	return storage.Transaction(func(tx IStorageDB) error {
+		db := tx.(*StorageMySQL).db()
+
+		result, err := db.Exec(`
+			UPDATE process SET
+				`+"`type`"+` = ?,
+				name = ?,
+				description = ?,
+				date_from = ?,
+				date_to = ?,
+				time_from = ?,
+				time_to = ?,
+				days_off = ?,
+				monitor = ?,
+				status = ?,
+				updated_at = ?
+			WHERE id_process = ?
+			  AND deleted_at IS NULL
+			  AND (? = 0 OR version = ?)
+		`, updProcess.Type,
+			updProcess.Name,
+			updProcess.Description,
+			toNullDate(updProcess.DateFrom),
+			toNullDate(updProcess.DateTo),
+			toNullString(updProcess.TimeFrom),
+			toNullString(updProcess.TimeTo),
+			daysOff,
+			updProcess.Monitor,
+			toNullString(updProcess.Status),
+			storage.now(),
+			updProcess.IdProcess,
+			updProcess.Version,
+			updProcess.Version)
+		if err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		// the version always changes, so an updated process is always an affected row
+		if affected, err := result.RowsAffected(); err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		} else if affected == 0 {
+			return ErrorVersionMismatch
+		}
+
+		if err := db.QueryRow(`SELECT version FROM process WHERE id_process = ?`, updProcess.IdProcess).Scan(&updProcess.Version); err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		return nil
+	})
+}
+
+// UpdateProcessStatus changes the status of the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was updated.
+// A process is only started when it isn't running, otherwise ErrorAlreadyRunning is returned.
+// The heartbeat is cleared, so the lease of the new status starts with its first heartbeat
+func (storage *StorageMySQL) UpdateProcessStatus(idProcess string, status Status, message string, version int64) error {
+	defer storage.metrics.query("update_process_status", time.Now())
+
+	query := `
+		UPDATE process SET
+			status = ?,
+			message = NULLIF(?, ''),
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND (? = 0 OR version = ?)`
+	params := []interface{}{status, message, storage.now(), idProcess, version, version}
+	if status == StatusRunning {
+		query += `
+		  AND NOT (status <=> ?)`
+		params = append(params, status)
+	}
+
+	// mysql can't change several tables on a statement, so the update and the heartbeat are on a transaction
+	return storage.Transaction(func(tx IStorageDB) error {
+		result, err := tx.(*StorageMySQL).db().Exec(query, params...)
+		if err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		// the version always changes, so an updated process is always an affected row
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		if affected > 0 {
+			return tx.(*StorageMySQL).clearHeartbeat(idProcess)
+		}
+
+		if version > 0 {
+			return ErrorVersionMismatch
+		}
+
+		return statusNotUpdated(tx, idProcess, status)
+	})
+}
+
+// HeartbeatProcess records the heartbeat of a running process, returns false when it isn't running.
+// The heartbeats are kept apart, so they don't change the version nor the history of the process
+func (storage *StorageMySQL) HeartbeatProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("heartbeat_process", time.Now())
+
+	result, err := storage.db().Exec(`
+		INSERT INTO process_heartbeat(id_process, heartbeat_at)
+		SELECT id_process, ?
+		FROM process
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND status = ?
+		ON DUPLICATE KEY UPDATE
+			heartbeat_at = VALUES(heartbeat_at)
+	`, storage.now(), idProcess, StatusRunning)
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
+}
+
+// ExpireProcess sets a running process as failed when its last heartbeat is older than heartbeatBefore,
+// returns false when it isn't running or its lease didn't expire
+func (storage *StorageMySQL) ExpireProcess(idProcess string, heartbeatBefore time.Time) (bool, error) {
+	defer storage.metrics.query("expire_process", time.Now())
+
+	var expired bool
+	err := storage.Transaction(func(tx IStorageDB) error {
+		result, err := tx.(*StorageMySQL).db().Exec(`
+			UPDATE process SET
+				status = ?,
+				message = ?,
+				updated_at = ?
+			WHERE id_process = ?
+			  AND deleted_at IS NULL
+			  AND status = ?
+			  AND id_process IN (SELECT id_process FROM process_heartbeat WHERE heartbeat_at < ?)
+		`, StatusFailed, MessageLeaseExpired, storage.now(), idProcess, StatusRunning, heartbeatBefore.UTC())
+		if err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		}
+
+		if expired = affected > 0; !expired {
+			return nil
+		}
+
+		return tx.(*StorageMySQL).clearHeartbeat(idProcess)
+	})
+
+	return expired, err
+}
+
+// clearHeartbeat deletes the heartbeat of the process
+func (storage *StorageMySQL) clearHeartbeat(idProcess string) error {
+	if _, err := storage.db().Exec(`
+	    DELETE
+		FROM process_heartbeat
+		WHERE id_process = ?
+	`, idProcess); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// DeleteProcess deletes the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was deleted
+func (storage *StorageMySQL) DeleteProcess(idProcess string, version int64) error {
+	defer storage.metrics.query("delete_process", time.Now())
+
+	now := storage.now()
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = ?,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND (? = 0 OR version = ?)
+	`, now, now, idProcess, version, version)
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if version > 0 {
+		if affected, err := result.RowsAffected(); err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		} else if affected == 0 {
+			return ErrorVersionMismatch
+		}
+	}
+
+	return nil
+}
+
+func (storage *StorageMySQL) DeleteProcesses(values map[string][]string) (int64, error) {
+	defer storage.metrics.query("delete_processes", time.Now())
+
+	filter, params, err := processFilter(values, mysqlFilterColumns, questionPlaceholder)
+	if err != nil {
+		return 0, err
+	}
+
+	now := storage.now()
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = ?,
+			updated_at = ?
+		WHERE deleted_at IS NULL
+	`+filter, append([]interface{}{now, now}, params...)...)
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (storage *StorageMySQL) RestoreProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("restore_process", time.Now())
+
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = NULL,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NOT NULL
+	`, storage.now(), idProcess)
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
+}
+
+func (storage *StorageMySQL) PurgeProcesses(deletedBefore time.Time) (int64, error) {
+	defer storage.metrics.query("purge_processes", time.Now())
+
+	result, err := storage.db().Exec(`
+	    DELETE
+		FROM process
+		WHERE deleted_at IS NOT NULL
+		  AND deleted_at < ?
+	`, deletedBefore.UTC())
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+// now returns the time of the clock on UTC, like the dates of the connection
+func (storage *StorageMySQL) now() time.Time {
+	return storage.clock.Now().UTC()
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (storage *StorageMySQL) GetProcessHistory(idProcess string) (ListProcessHistory, error) {
+	defer storage.metrics.query("get_process_history", time.Now())
+
+	rows, err := storage.db().Query(`
+	    SELECT
+			id_process,
+			`+"`type`"+`,
+			name,
+			COALESCE(description, ''),
+			date_from,
+			date_to,
+			time_from,
+			time_to,
+			days_off,
+			monitor,
+			status,
+			COALESCE(message, ''),
+			NULL,
+			COALESCE(version, 0),
+			updated_at,
+			created_at,
+			deleted_at,
+			_operation,
+			_operation_at
+		FROM process_history
+		WHERE id_process = ?
+		ORDER BY _operation_at, version
+	`, idProcess)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	defer rows.Close()
+
+	history := make(ListProcessHistory, 0)
+	for rows.Next() {
+		row := &mysqlProcess{}
+		change := &ProcessHistory{}
+		if err := rows.Scan(append(row.fields(), &change.DeletedAt, &change.Operation, &change.OperationAt)...); err != nil {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		process, err := row.toProcess()
+		if err != nil {
+			return nil, err
+		}
+		change.Process = *process
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return history, nil
+}
+
+// toNullDate returns the date on the format of mysql, or nil when it isn't set
+func toNullDate(value *types.Date) interface{} {
+	if value == nil {
+		return nil
+	}
+	return sortableDate(*value)
+}
+
+func fromNullDate(value sql.NullTime) *types.Date {
+	if !value.Valid {
+		return nil
+	}
+	return pointer(types.Date(value.Time.Format(dateLayout)))
+}
+
+// isMySQLUniqueViolation checks if the error of the driver is a duplicated key (error 1062)
+func isMySQLUniqueViolation(err error) bool {
+	if e, ok := err.(*mysql.MySQLError); ok {
+		return e.Number == 1062
+	}
+	return false
+}
@@ -0,0 +1,585 @@
+package monitor
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/joaosoft/errors"
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+	"github.com/joaosoft/types"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed schema/db/sqlite/*.sql
+var sqliteSchema embed.FS
+
+// sqliteMigrations are the migrations of sqlite, embedded so a single binary is enough to run the monitor
+var sqliteMigrations, _ = fs.Sub(sqliteSchema, "schema/db/sqlite")
+
+// StorageSQLite keeps the processes on sqlite, with the same semantics of the postgres storage.
+// The dates, times and days off are kept as text, with the days off as a json array,
+// and the version and updated_at are set on each update, as the history is kept by triggers.
+// The changes are dated by the clock of the monitor
+type StorageSQLite struct {
+	conn    manager.IDB
+	tx      *sql.Tx
+	clock   Clock
+	metrics *metrics
+	logger  logger.ILogger
+}
+
+func (monitor *Monitor) NewStorageSQLite(connection manager.IDB) *StorageSQLite {
+	return &StorageSQLite{
+		conn:    connection,
+		clock:   monitor.clock,
+		metrics: monitor.metrics,
+		logger:  monitor.logger,
+	}
+}
+
+func (storage *StorageSQLite) db() executor {
+	if storage.tx != nil {
+		return storage.tx
+	}
+	return storage.conn.Get()
+}
+
+// Transaction executes the handler with a storage bound to a transaction,
+// that is committed when the handler succeeds and rolled back otherwise
+func (storage *StorageSQLite) Transaction(handler func(storage IStorageDB) error) error {
+	if storage.tx != nil {
+		return handler(storage)
+	}
+
+	tx, err := storage.conn.Get().Begin()
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if err := handler(&StorageSQLite{conn: storage.conn, tx: tx, clock: storage.clock, metrics: storage.metrics, logger: storage.logger}); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			storage.logger.Errorf("error rolling back transaction %s", errRollback)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// sqliteProcess is the process as read from sqlite
+type sqliteProcess struct {
+	process  Process
+	dateFrom sql.NullString
+	dateTo   sql.NullString
+	timeFrom sql.NullString
+	timeTo   sql.NullString
+	daysOff  sql.NullString
+	monitor  sql.NullString
+	status   sql.NullString
+}
+
+func (row *sqliteProcess) fields() []interface{} {
+	return []interface{}{
+		&row.process.IdProcess,
+		&row.process.Type,
+		&row.process.Name,
+		&row.process.Description,
+		&row.dateFrom,
+		&row.dateTo,
+		&row.timeFrom,
+		&row.timeTo,
+		&row.daysOff,
+		&row.monitor,
+		&row.status,
+		&row.process.Message,
+		&row.process.HeartbeatAt,
+		&row.process.Version,
+		&row.process.UpdatedAt,
+		&row.process.CreatedAt,
+	}
+}
+
+func (row *sqliteProcess) toProcess() (*Process, error) {
+	process := row.process
+	process.DateFrom = fromNullString[types.Date](row.dateFrom)
+	process.DateTo = fromNullString[types.Date](row.dateTo)
+	process.TimeFrom = fromNullString[types.Time](row.timeFrom)
+	process.TimeTo = fromNullString[types.Time](row.timeTo)
+	process.Monitor = row.monitor.String
+	process.Status = fromNullString[Status](row.status)
+
+	if row.daysOff.Valid {
+		daysOff := make(types.ListDay, 0)
+		if err := json.Unmarshal([]byte(row.daysOff.String), &daysOff); err != nil {
+			return nil, errors.New(errors.LevelError, 0, "invalid days off %s of the process %s: %s", row.daysOff.String, process.IdProcess, err)
+		}
+		process.DaysOff = &daysOff
+	}
+
+	return &process, nil
+}
+
+const sqliteSelectProcess = `
+    SELECT
+		id_process,
+		"type",
+		name,
+		COALESCE(description, ''),
+		date_from,
+		date_to,
+		time_from,
+		time_to,
+		days_off,
+		monitor,
+		status,
+		COALESCE(message, ''),
+		heartbeat.heartbeat_at,
+		version,
+		updated_at,
+		created_at
+	FROM process
+	LEFT JOIN (SELECT id_process AS id_heartbeat, heartbeat_at FROM process_heartbeat) heartbeat ON heartbeat.id_heartbeat = process.id_process
+	WHERE deleted_at IS NULL
+`
+
+func (storage *StorageSQLite) GetProcess(idProcess string) (*Process, error) {
+	defer storage.metrics.query("get_process", time.Now())
+
+	row := &sqliteProcess{}
+	if err := storage.db().QueryRow(sqliteSelectProcess+` AND id_process = ?`, idProcess).Scan(row.fields()...); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		return nil, nil
+	}
+
+	return row.toProcess()
+}
+
+func (storage *StorageSQLite) GetProcesses(values map[string][]string) (ListProcess, error) {
+	defer storage.metrics.query("get_processes", time.Now())
+
+	filter, params, err := processFilter(values, processFilterColumns, questionPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := storage.db().Query(sqliteSelectProcess+filter+` ORDER BY id_process`, params...)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	defer rows.Close()
+
+	processes := make(ListProcess, 0)
+	for rows.Next() {
+		row := &sqliteProcess{}
+		if err := rows.Scan(row.fields()...); err != nil {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		process, err := row.toProcess()
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, process)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return processes, nil
+}
+
+func (storage *StorageSQLite) CreateProcess(newProcess *Process) error {
+	defer storage.metrics.query("create_process", time.Now())
+
+	daysOff, err := jsonDaysOff(newProcess.DaysOff)
+	if err != nil {
+		return err
+	}
+
+	now := storage.clock.Now().UTC()
+	if _, err := storage.db().Exec(`
+		INSERT INTO process(
+			id_process,
+			"type",
+			name,
+			description,
+			date_from,
+			date_to,
+			time_from,
+			time_to,
+			days_off,
+			monitor,
+			status,
+			created_at,
+			updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		newProcess.IdProcess,
+		newProcess.Type,
+		newProcess.Name,
+		newProcess.Description,
+		toNullString(newProcess.DateFrom),
+		toNullString(newProcess.DateTo),
+		toNullString(newProcess.TimeFrom),
+		toNullString(newProcess.TimeTo),
+		daysOff,
+		newProcess.Monitor,
+		toNullString(newProcess.Status),
+		now,
+		now); err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return ErrorProcessAlreadyExists
+		}
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// UpdateProcess updates the process when it's on the expected version (any version when zero)
+// and sets the process with the new version, returns ErrorVersionMismatch when nothing was updated
+func (storage *StorageSQLite) UpdateProcess(updProcess *Process) error {
+	defer storage.metrics.query("update_process", time.Now())
+
+	daysOff, err := jsonDaysOff(updProcess.DaysOff)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.db().QueryRow(`
+		UPDATE process SET
+			"type" = ?,
+			name = ?,
+			description = ?,
+			date_from = ?,
+			date_to = ?,
+			time_from = ?,
+			time_to = ?,
+			days_off = ?,
+			monitor = ?,
+			status = ?,
+			version = version + 1,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND (? = 0 OR version = ?)
+		RETURNING version
+	`, updProcess.Type,
+		updProcess.Name,
+		updProcess.Description,
+		toNullString(updProcess.DateFrom),
+		toNullString(updProcess.DateTo),
+		toNullString(updProcess.TimeFrom),
+		toNullString(updProcess.TimeTo),
+		daysOff,
+		updProcess.Monitor,
+		toNullString(updProcess.Status),
+		storage.clock.Now().UTC(),
+		updProcess.IdProcess,
+		updProcess.Version,
+		updProcess.Version).Scan(&updProcess.Version); err != nil {
+
+		if err == sql.ErrNoRows {
+			return ErrorVersionMismatch
+		}
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// UpdateProcessStatus changes the status of the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was updated.
+// A process is only started when it isn't running, otherwise ErrorAlreadyRunning is returned.
+// The heartbeat is cleared, so the lease of the new status starts with its first heartbeat
+func (storage *StorageSQLite) UpdateProcessStatus(idProcess string, status Status, message string, version int64) error {
+	defer storage.metrics.query("update_process_status", time.Now())
+
+	query := `
+		UPDATE process SET
+			status = ?,
+			message = NULLIF(?, ''),
+			version = version + 1,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND (? = 0 OR version = ?)`
+	params := []interface{}{status, message, storage.clock.Now().UTC(), idProcess, version, version}
+	if status == StatusRunning {
+		query += `
+		  AND status IS NOT ?`
+		params = append(params, status)
+	}
+
+	result, err := storage.db().Exec(query, params...)
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if affected > 0 {
+		return storage.clearHeartbeat(idProcess)
+	}
+
+	if version > 0 {
+		return ErrorVersionMismatch
+	}
+
+	return statusNotUpdated(storage, idProcess, status)
+}
+
+// HeartbeatProcess records the heartbeat of a running process, returns false when it isn't running.
+// The heartbeats are kept apart, so they don't change the version nor the history of the process
+func (storage *StorageSQLite) HeartbeatProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("heartbeat_process", time.Now())
+
+	result, err := storage.db().Exec(`
+		INSERT INTO process_heartbeat(id_process, heartbeat_at)
+		SELECT id_process, ?
+		FROM process
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND status = ?
+		ON CONFLICT (id_process) DO UPDATE SET
+			heartbeat_at = excluded.heartbeat_at
+	`, storage.clock.Now().UTC(), idProcess, StatusRunning)
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
+}
+
+// ExpireProcess sets a running process as failed when its last heartbeat is older than heartbeatBefore,
+// returns false when it isn't running or its lease didn't expire
+func (storage *StorageSQLite) ExpireProcess(idProcess string, heartbeatBefore time.Time) (bool, error) {
+	defer storage.metrics.query("expire_process", time.Now())
+
+	result, err := storage.db().Exec(`
+		UPDATE process SET
+			status = ?,
+			message = ?,
+			version = version + 1,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND status = ?
+		  AND id_process IN (SELECT id_process FROM process_heartbeat WHERE heartbeat_at < ?)
+	`, StatusFailed, MessageLeaseExpired, storage.clock.Now().UTC(), idProcess, StatusRunning, heartbeatBefore.UTC())
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	return true, storage.clearHeartbeat(idProcess)
+}
+
+// clearHeartbeat deletes the heartbeat of the process, as sqlite can't change several tables on a statement
+func (storage *StorageSQLite) clearHeartbeat(idProcess string) error {
+	if _, err := storage.db().Exec(`
+	    DELETE
+		FROM process_heartbeat
+		WHERE id_process = ?
+	`, idProcess); err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	return nil
+}
+
+// DeleteProcess deletes the process when it's on the expected version (any version when zero),
+// returns ErrorVersionMismatch when a version was expected and nothing was deleted
+func (storage *StorageSQLite) DeleteProcess(idProcess string, version int64) error {
+	defer storage.metrics.query("delete_process", time.Now())
+
+	now := storage.clock.Now().UTC()
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = ?,
+			version = version + 1,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NULL
+		  AND (? = 0 OR version = ?)
+	`, now, now, idProcess, version, version)
+	if err != nil {
+		return errors.New(errors.LevelError, 0, err)
+	}
+
+	if version > 0 {
+		if affected, err := result.RowsAffected(); err != nil {
+			return errors.New(errors.LevelError, 0, err)
+		} else if affected == 0 {
+			return ErrorVersionMismatch
+		}
+	}
+
+	return nil
+}
+
+func (storage *StorageSQLite) DeleteProcesses(values map[string][]string) (int64, error) {
+	defer storage.metrics.query("delete_processes", time.Now())
+
+	filter, params, err := processFilter(values, processFilterColumns, questionPlaceholder)
+	if err != nil {
+		return 0, err
+	}
+
+	now := storage.clock.Now().UTC()
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = ?,
+			version = version + 1,
+			updated_at = ?
+		WHERE deleted_at IS NULL
+	`+filter, append([]interface{}{now, now}, params...)...)
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (storage *StorageSQLite) RestoreProcess(idProcess string) (bool, error) {
+	defer storage.metrics.query("restore_process", time.Now())
+
+	result, err := storage.db().Exec(`
+	    UPDATE process SET
+			deleted_at = NULL,
+			version = version + 1,
+			updated_at = ?
+		WHERE id_process = ?
+		  AND deleted_at IS NOT NULL
+	`, storage.clock.Now().UTC(), idProcess)
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New(errors.LevelError, 0, err)
+	}
+
+	return affected > 0, nil
+}
+
+// PurgeProcesses deletes the processes deleted before the date, with their heartbeats,
+// as the foreign keys of sqlite are only enforced when enabled on the connection
+func (storage *StorageSQLite) PurgeProcesses(deletedBefore time.Time) (int64, error) {
+	defer storage.metrics.query("purge_processes", time.Now())
+
+	if _, err := storage.db().Exec(`
+	    DELETE
+		FROM process_heartbeat
+		WHERE id_process IN (SELECT id_process FROM process WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, deletedBefore.UTC()); err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	result, err := storage.db().Exec(`
+	    DELETE
+		FROM process
+		WHERE deleted_at IS NOT NULL
+		  AND deleted_at < ?
+	`, deletedBefore.UTC())
+	if err != nil {
+		return 0, errors.New(errors.LevelError, 0, err)
+	}
+
+	return result.RowsAffected()
+}
+
+// isSQLiteUniqueViolation checks if the error of the driver is a violation of the primary key or of an unique constraint.
+// The message is checked, as the error codes of the driver are only built with cgo
+func isSQLiteUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// GetProcessHistory returns the changes of the process, by the order they were made
+func (storage *StorageSQLite) GetProcessHistory(idProcess string) (ListProcessHistory, error) {
+	defer storage.metrics.query("get_process_history", time.Now())
+
+	rows, err := storage.db().Query(`
+	    SELECT
+			id_process,
+			"type",
+			name,
+			COALESCE(description, ''),
+			date_from,
+			date_to,
+			time_from,
+			time_to,
+			days_off,
+			monitor,
+			status,
+			COALESCE(message, ''),
+			NULL,
+			COALESCE(version, 0),
+			updated_at,
+			created_at,
+			deleted_at,
+			_operation,
+			_operation_at
+		FROM process_history
+		WHERE id_process = ?
+		ORDER BY rowid
+	`, idProcess)
+	if err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	defer rows.Close()
+
+	history := make(ListProcessHistory, 0)
+	for rows.Next() {
+		row := &sqliteProcess{}
+		change := &ProcessHistory{}
+		if err := rows.Scan(append(row.fields(), &change.DeletedAt, &change.Operation, &change.OperationAt)...); err != nil {
+			return nil, errors.New(errors.LevelError, 0, err)
+		}
+
+		process, err := row.toProcess()
+		if err != nil {
+			return nil, err
+		}
+		change.Process = *process
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(errors.LevelError, 0, err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/logger"
+	"github.com/joaosoft/manager"
+)
+
+func TestStorageSQLite(t *testing.T) {
+	m := newTestMonitor(t, &MonitorConfig{Db: manager.DBConfig{Driver: DriverSQLite, DataSource: filepath.Join(t.TempDir(), "monitor.db")}})
+
+	if ready := m.health.Ready(); ready.Status != HealthUp {
+		t.Fatalf("expected the database to be ready, got %+v %+v", ready.Components["database"], ready.Components["migrations"])
+	}
+
+	storage := m.storage
+	if err := storage.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	if err := storage.UpdateProcessStatus("job", StatusRunning, "started", 0); err != nil {
+		t.Fatalf("error updating status: %s", err)
+	}
+	if alive, err := storage.HeartbeatProcess("job"); err != nil || !alive {
+		t.Fatalf("expected the heartbeat to be recorded, got %v %v", alive, err)
+	}
+	if err := storage.DeleteProcess("job", 0); err != nil {
+		t.Fatalf("error deleting process: %s", err)
+	}
+	if restored, err := storage.RestoreProcess("job"); err != nil || !restored {
+		t.Fatalf("expected the process to be restored, got %v %v", restored, err)
+	}
+
+	// the heartbeats aren't on the history
+	var operations string
+	rows, err := m.pm.GetDB("db_sqlite").Get().Query(`SELECT _operation || version FROM process_history WHERE id_process = 'job' ORDER BY rowid`)
+	if err != nil {
+		t.Fatalf("error getting the history: %s", err)
+	}
+	for rows.Next() {
+		var operation string
+		if err := rows.Scan(&operation); err != nil {
+			t.Fatalf("error reading the history: %s", err)
+		}
+		operations += operation + " "
+	}
+	rows.Close()
+
+	if operations != "I1 U2 U3 U4 " {
+		t.Errorf("expected the history I1 U2 U3 U4, got %s", operations)
+	}
+}
+
+func TestStorageSQLiteClock(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC))
+	m, err := NewMonitor(
+		WithConfiguration(&MonitorConfig{Host: "localhost:0", Db: manager.DBConfig{Driver: DriverSQLite, DataSource: filepath.Join(t.TempDir(), "monitor.db")}}),
+		WithManager(manager.NewManager(manager.WithRunInBackground(true))),
+		WithLogLevel(logger.ErrorLevel),
+		WithClock(fake),
+	)
+	if err != nil {
+		t.Fatalf("error creating the monitor: %s", err)
+	}
+	t.Cleanup(func() { m.pm.GetDB("db_sqlite").Stop() })
+
+	// the changes are dated by the clock of the monitor
+	storage := m.storage
+	if err := storage.CreateProcess(&Process{IdProcess: "job", Type: "cron", Name: "job"}); err != nil {
+		t.Fatalf("error creating process: %s", err)
+	}
+	fake.Advance(time.Hour)
+	if err := storage.UpdateProcessStatus("job", StatusRunning, "", 0); err != nil {
+		t.Fatalf("error updating status: %s", err)
+	}
+	if _, err := storage.HeartbeatProcess("job"); err != nil {
+		t.Fatalf("error sending heartbeat: %s", err)
+	}
+
+	process, err := storage.GetProcess("job")
+	if err != nil {
+		t.Fatalf("error getting process: %s", err)
+	}
+	if !process.CreatedAt.Equal(fake.Now().Add(-time.Hour)) || !process.UpdatedAt.Equal(fake.Now()) || !process.HeartbeatAt.Equal(fake.Now()) {
+		t.Errorf("expected the process dated by the clock, got %s %s %v", process.CreatedAt, process.UpdatedAt, process.HeartbeatAt)
+	}
+
+	if err := storage.DeleteProcess("job", 0); err != nil {
+		t.Fatalf("error deleting process: %s", err)
+	}
+	if purged, err := storage.PurgeProcesses(fake.Now()); err != nil || purged != 0 {
+		t.Errorf("expected the process deleted now to be kept, got %d %v", purged, err)
+	}
+	fake.Advance(time.Second)
+	if purged, err := storage.PurgeProcesses(fake.Now()); err != nil || purged != 1 {
+		t.Errorf("expected the process to be purged, got %d %v", purged, err)
+	}
+}
@@ -0,0 +1,56 @@
+package monitor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joaosoft/manager"
+	migration "github.com/joaosoft/migration/services"
+	"github.com/joaosoft/monitor"
+	"github.com/joaosoft/monitor/storagetest"
+)
+
+// TestStorages runs the storage tests on every storage, mysql and postgres are tested when
+// MONITOR_MYSQL_DATASOURCE and MONITOR_POSTGRES_DATASOURCE have the datasource of a database that can be cleaned
+func TestStorages(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) monitor.IStorageDB { return monitor.NewStorageMemory(nil) })
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) monitor.IStorageDB {
+			return monitor.NewTestMonitor(t, &monitor.MonitorConfig{
+				Db: manager.DBConfig{Driver: monitor.DriverSQLite, DataSource: filepath.Join(t.TempDir(), "monitor.db")},
+			}).Storage()
+		})
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		dataSource := os.Getenv("MONITOR_MYSQL_DATASOURCE")
+		if dataSource == "" {
+			t.Skip("MONITOR_MYSQL_DATASOURCE isn't set")
+		}
+
+		storage := monitor.NewTestMonitor(t, &monitor.MonitorConfig{
+			Db: manager.DBConfig{Driver: monitor.DriverMySQL, DataSource: dataSource},
+		}).Storage()
+		storagetest.Run(t, func(t *testing.T) monitor.IStorageDB { return storage })
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dataSource := os.Getenv("MONITOR_POSTGRES_DATASOURCE")
+		if dataSource == "" {
+			t.Skip("MONITOR_POSTGRES_DATASOURCE isn't set")
+		}
+
+		config := &monitor.MonitorConfig{
+			Db:        manager.DBConfig{Driver: monitor.DriverPostgres, DataSource: dataSource},
+			Migration: &migration.MigrationConfig{Db: &migration.DBConfig{DBConfig: manager.DBConfig{Driver: monitor.DriverPostgres, DataSource: dataSource}, Schema: "monitor"}},
+		}
+		config.Migration.Path.Database = "schema/db/postgres"
+
+		storage := monitor.NewTestMonitor(t, config).Storage()
+		storagetest.Run(t, func(t *testing.T) monitor.IStorageDB { return storage })
+	})
+}
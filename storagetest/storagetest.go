@@ -0,0 +1,548 @@
+package storagetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joaosoft/monitor"
+	"github.com/joaosoft/types"
+)
+
+// Factory returns the storage to test. It can return the same storage on every call,
+// as the processes are deleted and purged before each test
+type Factory func(t *testing.T) monitor.IStorageDB
+
+// Run tests every method of the storage, with the semantics of the postgres storage,
+// so other storages can prove they are compatible with the monitor
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, storage monitor.IStorageDB)
+	}{
+		{name: "GetProcess", test: testGetProcess},
+		{name: "GetProcesses", test: testGetProcesses},
+		{name: "CreateProcess", test: testCreateProcess},
+		{name: "UpdateProcess", test: testUpdateProcess},
+		{name: "UpdateProcessStatus", test: testUpdateProcessStatus},
+		{name: "ConcurrentStarts", test: testConcurrentStarts},
+		{name: "HeartbeatProcess", test: testHeartbeatProcess},
+		{name: "ExpireProcess", test: testExpireProcess},
+		{name: "DeleteProcess", test: testDeleteProcess},
+		{name: "DeleteProcesses", test: testDeleteProcesses},
+		{name: "RestoreProcess", test: testRestoreProcess},
+		{name: "PurgeProcesses", test: testPurgeProcesses},
+		{name: "GetProcessHistory", test: testGetProcessHistory},
+		{name: "Transaction", test: testTransaction},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage := factory(t)
+			clean(t, storage)
+			test.test(t, storage)
+		})
+	}
+}
+
+// clean deletes and purges every process
+func clean(t *testing.T, storage monitor.IStorageDB) {
+	if _, err := storage.DeleteProcesses(nil); err != nil {
+		t.Fatalf("error deleting the processes: %s", err)
+	}
+	if _, err := storage.PurgeProcesses(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("error purging the processes: %s", err)
+	}
+}
+
+// newProcess returns a process with every field of the definition set
+func newProcess(id string) *monitor.Process {
+	daysOff := types.ListDay{"saturday", "sunday"}
+	dateFrom, dateTo := types.Date("01-01-2024"), types.Date("31-12-2024")
+	timeFrom, timeTo := types.Time("08:00:00"), types.Time("18:00:00")
+	status := monitor.StatusStopped
+
+	return &monitor.Process{
+		IdProcess:   id,
+		Type:        "cron",
+		Name:        "name of " + id,
+		Description: "description of " + id,
+		DateFrom:    &dateFrom,
+		DateTo:      &dateTo,
+		TimeFrom:    &timeFrom,
+		TimeTo:      &timeTo,
+		DaysOff:     &daysOff,
+		Monitor:     "ops",
+		Status:      &status,
+	}
+}
+
+func create(t *testing.T, storage monitor.IStorageDB, processes ...*monitor.Process) {
+	for _, process := range processes {
+		if err := storage.CreateProcess(process); err != nil {
+			t.Fatalf("error creating the process %s: %s", process.IdProcess, err)
+		}
+	}
+}
+
+func get(t *testing.T, storage monitor.IStorageDB, id string) *monitor.Process {
+	process, err := storage.GetProcess(id)
+	if err != nil {
+		t.Fatalf("error getting the process %s: %s", id, err)
+	}
+	return process
+}
+
+// ids returns the ids of the processes of the filter, sorted, failing when the list is nil
+func ids(t *testing.T, storage monitor.IStorageDB, filter map[string][]string) string {
+	processes, err := storage.GetProcesses(filter)
+	if err != nil {
+		t.Fatalf("error getting the processes with the filter %v: %s", filter, err)
+	}
+	if processes == nil {
+		t.Fatalf("expected an empty list instead of nil with the filter %v", filter)
+	}
+
+	ids := make([]string, 0, len(processes))
+	for _, process := range processes {
+		ids = append(ids, process.IdProcess)
+	}
+	sort.Strings(ids)
+
+	return strings.Join(ids, " ")
+}
+
+func testGetProcess(t *testing.T, storage monitor.IStorageDB) {
+	if process := get(t, storage, "job"); process != nil {
+		t.Fatalf("expected no process, got %+v", process)
+	}
+
+	create(t, storage, newProcess("job"))
+
+	process := get(t, storage, "job")
+	if process == nil {
+		t.Fatal("expected the process")
+	}
+	if process.Message != "" || process.HeartbeatAt != nil || process.Version != 1 || process.CreatedAt.IsZero() || process.UpdatedAt.IsZero() {
+		t.Errorf("unexpected runtime fields of a new process %+v", process)
+	}
+}
+
+func testGetProcesses(t *testing.T, storage monitor.IStorageDB) {
+	if got := ids(t, storage, nil); got != "" {
+		t.Fatalf("expected no processes, got %s", got)
+	}
+
+	backup := newProcess("backup")
+	backup.Type, backup.Monitor, backup.Status, backup.DaysOff = "systemd", "", nil, nil
+	create(t, storage, newProcess("job"), newProcess("report"), backup)
+	storage.UpdateProcessStatus("report", monitor.StatusRunning, "", 0)
+
+	for _, test := range []struct {
+		filter   map[string][]string
+		expected []string
+	}{
+		{filter: map[string][]string{}, expected: []string{"backup", "job", "report"}},
+		{filter: map[string][]string{"id_process": {"job"}}, expected: []string{"job"}},
+		{filter: map[string][]string{"type": {"cron", "systemd"}}, expected: []string{"backup", "job", "report"}},
+		{filter: map[string][]string{"type": {"cron"}, "status": {"stopped"}}, expected: []string{"job"}},
+		{filter: map[string][]string{"name": {"name of backup"}}, expected: []string{"backup"}},
+		{filter: map[string][]string{"monitor": {"ops"}}, expected: []string{"job", "report"}},
+		{filter: map[string][]string{"status": {"running", "failed"}}, expected: []string{"report"}},
+		{filter: map[string][]string{"status": {"failed"}}, expected: []string{}},
+		{filter: map[string][]string{"type": {}}, expected: []string{"backup", "job", "report"}},
+	} {
+		if got := ids(t, storage, test.filter); got != strings.Join(test.expected, " ") {
+			t.Errorf("expected the processes %v with the filter %v, got %s", test.expected, test.filter, got)
+		}
+	}
+
+	if _, err := storage.GetProcesses(map[string][]string{"description": {"any"}}); err != monitor.ErrorInvalidFilter {
+		t.Errorf("expected an invalid filter, got %v", err)
+	}
+}
+
+func testCreateProcess(t *testing.T, storage monitor.IStorageDB) {
+	expected := newProcess("job")
+	create(t, storage, expected)
+
+	process := get(t, storage, "job")
+	if process.Type != expected.Type || process.Name != expected.Name || process.Description != expected.Description ||
+		*process.DateFrom != *expected.DateFrom || *process.DateTo != *expected.DateTo ||
+		*process.TimeFrom != *expected.TimeFrom || *process.TimeTo != *expected.TimeTo ||
+		fmt.Sprint(*process.DaysOff) != fmt.Sprint(*expected.DaysOff) || process.Monitor != expected.Monitor || *process.Status != *expected.Status {
+		t.Errorf("expected the process %+v, got %+v", expected, process)
+	}
+
+	create(t, storage, &monitor.Process{IdProcess: "minimal", Type: "cron", Name: "minimal"})
+	minimal := get(t, storage, "minimal")
+	if minimal.DateFrom != nil || minimal.DateTo != nil || minimal.TimeFrom != nil || minimal.TimeTo != nil || minimal.DaysOff != nil || minimal.Status != nil {
+		t.Errorf("expected the fields not set to be nil, got %+v", minimal)
+	}
+
+	if err := storage.CreateProcess(newProcess("job")); err != monitor.ErrorProcessAlreadyExists {
+		t.Errorf("expected the process to already exist, got %v", err)
+	}
+
+	// a deleted process keeps the id until purged
+	storage.DeleteProcess("job", 0)
+	if err := storage.CreateProcess(newProcess("job")); err != monitor.ErrorProcessAlreadyExists {
+		t.Errorf("expected the deleted process to already exist, got %v", err)
+	}
+}
+
+func testUpdateProcess(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+
+	process := get(t, storage, "job")
+	process.Name = "changed"
+	process.DaysOff = nil
+	if err := storage.UpdateProcess(process); err != nil || process.Version != 2 {
+		t.Fatalf("expected the process to be updated to the version 2, got %d %v", process.Version, err)
+	}
+
+	updated := get(t, storage, "job")
+	if updated.Name != "changed" || updated.DaysOff != nil || updated.Version != 2 || updated.UpdatedAt.Before(updated.CreatedAt) {
+		t.Errorf("unexpected updated process %+v", updated)
+	}
+
+	process.Version = 1
+	if err := storage.UpdateProcess(process); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch, got %v", err)
+	}
+
+	process.Version = 0
+	if err := storage.UpdateProcess(process); err != nil || process.Version != 3 {
+		t.Errorf("expected any version to be updated to the version 3, got %d %v", process.Version, err)
+	}
+
+	if err := storage.UpdateProcess(newProcess("unknown")); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch updating an unknown process, got %v", err)
+	}
+
+	storage.DeleteProcess("job", 0)
+	if err := storage.UpdateProcess(newProcess("job")); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch updating a deleted process, got %v", err)
+	}
+}
+
+func testUpdateProcessStatus(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+
+	if err := storage.UpdateProcessStatus("job", monitor.StatusFailed, "exit status 1", 0); err != nil {
+		t.Fatalf("error updating the status: %s", err)
+	}
+	if process := get(t, storage, "job"); *process.Status != monitor.StatusFailed || process.Message != "exit status 1" || process.Version != 2 {
+		t.Errorf("unexpected process after the status change %+v", process)
+	}
+
+	if err := storage.UpdateProcessStatus("job", monitor.StatusStopped, "", 1); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch, got %v", err)
+	}
+	if err := storage.UpdateProcessStatus("job", monitor.StatusStopped, "", 2); err != nil {
+		t.Fatalf("error updating the status: %s", err)
+	}
+	if process := get(t, storage, "job"); *process.Status != monitor.StatusStopped || process.Message != "" || process.Version != 3 {
+		t.Errorf("expected the message to be cleared, got %+v", process)
+	}
+
+	if err := storage.UpdateProcessStatus("job", monitor.StatusRunning, "started", 0); err != nil {
+		t.Fatalf("error starting the process: %s", err)
+	}
+	if err := storage.UpdateProcessStatus("job", monitor.StatusRunning, "", 0); err != monitor.ErrorAlreadyRunning {
+		t.Errorf("expected the process to be already running, got %v", err)
+	}
+	if process := get(t, storage, "job"); process.Message != "started" || process.Version != 4 {
+		t.Errorf("expected the process to be unchanged starting it again, got %+v", process)
+	}
+
+	// a new status clears the heartbeat of the previous one
+	if alive, err := storage.HeartbeatProcess("job"); err != nil || !alive {
+		t.Fatalf("expected the heartbeat to be recorded, got %v %v", alive, err)
+	}
+	if err := storage.UpdateProcessStatus("job", monitor.StatusStopped, "", 0); err != nil {
+		t.Fatalf("error updating the status: %s", err)
+	}
+	if process := get(t, storage, "job"); process.HeartbeatAt != nil {
+		t.Errorf("expected the heartbeat to be cleared, got %+v", process)
+	}
+
+	if err := storage.UpdateProcessStatus("unknown", monitor.StatusRunning, "", 0); err != nil {
+		t.Errorf("expected no error updating the status of an unknown process, got %v", err)
+	}
+	if err := storage.UpdateProcessStatus("unknown", monitor.StatusRunning, "", 1); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch updating an unknown process with a version, got %v", err)
+	}
+}
+
+// testConcurrentStarts starts the process on concurrent transactions, like the monitor does, only one can succeed
+func testConcurrentStarts(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+
+	var started, running int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := storage.Transaction(func(tx monitor.IStorageDB) error {
+				return tx.UpdateProcessStatus("job", monitor.StatusRunning, "", 0)
+			})
+			switch err {
+			case nil:
+				atomic.AddInt32(&started, 1)
+			case monitor.ErrorAlreadyRunning:
+				atomic.AddInt32(&running, 1)
+			default:
+				t.Errorf("error starting the process: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if started != 1 || running != 19 {
+		t.Errorf("expected the process to be started once and already running 19 times, got %d and %d", started, running)
+	}
+	if process := get(t, storage, "job"); *process.Status != monitor.StatusRunning || process.Version != 2 {
+		t.Errorf("expected the process to be changed once, got %+v", process)
+	}
+}
+
+func testHeartbeatProcess(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+
+	if alive, err := storage.HeartbeatProcess("job"); err != nil || alive {
+		t.Fatalf("expected no heartbeat of a stopped process, got %v %v", alive, err)
+	}
+
+	storage.UpdateProcessStatus("job", monitor.StatusRunning, "", 0)
+	for i := 0; i < 2; i++ {
+		if alive, err := storage.HeartbeatProcess("job"); err != nil || !alive {
+			t.Fatalf("expected the heartbeat to be recorded, got %v %v", alive, err)
+		}
+	}
+
+	// the heartbeats don't change the version
+	if process := get(t, storage, "job"); process.HeartbeatAt == nil || process.Version != 2 {
+		t.Errorf("unexpected process after the heartbeats %+v", process)
+	}
+
+	if alive, err := storage.HeartbeatProcess("unknown"); err != nil || alive {
+		t.Errorf("expected no heartbeat of an unknown process, got %v %v", alive, err)
+	}
+
+	storage.DeleteProcess("job", 0)
+	if alive, err := storage.HeartbeatProcess("job"); err != nil || alive {
+		t.Errorf("expected no heartbeat of a deleted process, got %v %v", alive, err)
+	}
+}
+
+func testExpireProcess(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+	storage.UpdateProcessStatus("job", monitor.StatusRunning, "", 0)
+
+	// the lease starts with the first heartbeat
+	if expired, err := storage.ExpireProcess("job", time.Now().Add(time.Minute)); err != nil || expired {
+		t.Fatalf("expected no lease before the first heartbeat, got %v %v", expired, err)
+	}
+
+	storage.HeartbeatProcess("job")
+	if expired, err := storage.ExpireProcess("job", time.Now().Add(-time.Minute)); err != nil || expired {
+		t.Fatalf("expected the lease to not be expired, got %v %v", expired, err)
+	}
+	if expired, err := storage.ExpireProcess("job", time.Now().Add(time.Minute)); err != nil || !expired {
+		t.Fatalf("expected the lease to be expired, got %v %v", expired, err)
+	}
+	if process := get(t, storage, "job"); *process.Status != monitor.StatusFailed || process.Message != monitor.MessageLeaseExpired ||
+		process.HeartbeatAt != nil || process.Version != 3 {
+		t.Errorf("unexpected process after the lease expired %+v", process)
+	}
+
+	if expired, err := storage.ExpireProcess("job", time.Now().Add(time.Minute)); err != nil || expired {
+		t.Errorf("expected no lease of a process that isn't running, got %v %v", expired, err)
+	}
+	if expired, err := storage.ExpireProcess("unknown", time.Now().Add(time.Minute)); err != nil || expired {
+		t.Errorf("expected no lease of an unknown process, got %v %v", expired, err)
+	}
+}
+
+func testDeleteProcess(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"), newProcess("report"))
+
+	if err := storage.DeleteProcess("job", 2); err != monitor.ErrorVersionMismatch {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+	if err := storage.DeleteProcess("job", 1); err != nil {
+		t.Fatalf("error deleting the process: %s", err)
+	}
+	if process := get(t, storage, "job"); process != nil {
+		t.Fatalf("expected the process to be deleted, got %+v", process)
+	}
+	if got := ids(t, storage, nil); got != "report" {
+		t.Errorf("expected only the process report, got %s", got)
+	}
+
+	// without a version, deleting a deleted or unknown process isn't an error
+	if err := storage.DeleteProcess("job", 0); err != nil {
+		t.Errorf("expected no error deleting a deleted process, got %v", err)
+	}
+	if err := storage.DeleteProcess("unknown", 0); err != nil {
+		t.Errorf("expected no error deleting an unknown process, got %v", err)
+	}
+	if err := storage.DeleteProcess("unknown", 1); err != monitor.ErrorVersionMismatch {
+		t.Errorf("expected a version mismatch deleting an unknown process with a version, got %v", err)
+	}
+}
+
+func testDeleteProcesses(t *testing.T, storage monitor.IStorageDB) {
+	backup := newProcess("backup")
+	backup.Type = "systemd"
+	create(t, storage, newProcess("job"), newProcess("report"), backup)
+
+	if _, err := storage.DeleteProcesses(map[string][]string{"description": {"any"}}); err != monitor.ErrorInvalidFilter {
+		t.Fatalf("expected an invalid filter, got %v", err)
+	}
+	if deleted, err := storage.DeleteProcesses(map[string][]string{"type": {"unknown"}}); err != nil || deleted != 0 {
+		t.Fatalf("expected no processes deleted, got %d %v", deleted, err)
+	}
+	if deleted, err := storage.DeleteProcesses(map[string][]string{"type": {"cron"}}); err != nil || deleted != 2 {
+		t.Fatalf("expected 2 processes deleted, got %d %v", deleted, err)
+	}
+	if got := ids(t, storage, nil); got != "backup" {
+		t.Errorf("expected only the process backup, got %s", got)
+	}
+
+	// the deleted processes aren't counted again
+	if deleted, err := storage.DeleteProcesses(nil); err != nil || deleted != 1 {
+		t.Errorf("expected 1 process deleted, got %d %v", deleted, err)
+	}
+}
+
+func testRestoreProcess(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"))
+
+	if restored, err := storage.RestoreProcess("job"); err != nil || restored {
+		t.Fatalf("expected a process not deleted not to be restored, got %v %v", restored, err)
+	}
+
+	storage.DeleteProcess("job", 0)
+	if restored, err := storage.RestoreProcess("job"); err != nil || !restored {
+		t.Fatalf("expected the process to be restored, got %v %v", restored, err)
+	}
+	if process := get(t, storage, "job"); process == nil || process.Name != "name of job" {
+		t.Errorf("expected the process to be restored, got %+v", process)
+	}
+
+	if restored, err := storage.RestoreProcess("unknown"); err != nil || restored {
+		t.Errorf("expected an unknown process not to be restored, got %v %v", restored, err)
+	}
+}
+
+func testPurgeProcesses(t *testing.T, storage monitor.IStorageDB) {
+	create(t, storage, newProcess("job"), newProcess("report"), newProcess("backup"))
+	storage.DeleteProcess("job", 0)
+	storage.DeleteProcess("report", 0)
+
+	if purged, err := storage.PurgeProcesses(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("expected no processes deleted an hour ago, got %d %v", purged, err)
+	}
+	if purged, err := storage.PurgeProcesses(time.Now().Add(time.Hour)); err != nil || purged != 2 {
+		t.Fatalf("expected 2 processes purged, got %d %v", purged, err)
+	}
+
+	if restored, err := storage.RestoreProcess("job"); err != nil || restored {
+		t.Errorf("expected a purged process not to be restored, got %v %v", restored, err)
+	}
+	if got := ids(t, storage, nil); got != "backup" {
+		t.Errorf("expected only the process backup, got %s", got)
+	}
+
+	// a purged process can be created again
+	create(t, storage, newProcess("job"))
+}
+
+func testGetProcessHistory(t *testing.T, storage monitor.IStorageDB) {
+	// the history is kept after purging, so the process is new on every run
+	id := fmt.Sprintf("history-%d", time.Now().UnixNano())
+
+	if history, err := storage.GetProcessHistory(id); err != nil || history == nil || len(history) != 0 {
+		t.Fatalf("expected an empty history, got %v, %v", history, err)
+	}
+
+	create(t, storage, newProcess(id))
+	if err := storage.UpdateProcessStatus(id, monitor.StatusRunning, "started", 0); err != nil {
+		t.Fatalf("error updating the status: %s", err)
+	}
+	if _, err := storage.HeartbeatProcess(id); err != nil {
+		t.Fatalf("error sending a heartbeat: %s", err)
+	}
+	if err := storage.DeleteProcess(id, 0); err != nil {
+		t.Fatalf("error deleting the process: %s", err)
+	}
+	if _, err := storage.RestoreProcess(id); err != nil {
+		t.Fatalf("error restoring the process: %s", err)
+	}
+
+	history, err := storage.GetProcessHistory(id)
+	if err != nil {
+		t.Fatalf("error getting the history: %s", err)
+	}
+
+	// the heartbeats aren't on the history
+	var operations string
+	for _, change := range history {
+		operations += fmt.Sprintf("%s%d ", change.Operation, change.Version)
+	}
+	if operations != "I1 U2 U3 U4 " {
+		t.Fatalf("expected the history I1 U2 U3 U4, got %s", operations)
+	}
+
+	if change := history[0]; change.IdProcess != id || change.Name != "name of "+id || change.DaysOff == nil || len(*change.DaysOff) != 2 || change.TimeFrom == nil {
+		t.Errorf("expected the created process on the history, got %+v", change.Process)
+	}
+	if change := history[1]; change.Status == nil || *change.Status != monitor.StatusRunning || change.Message != "started" || change.HeartbeatAt != nil {
+		t.Errorf("expected the running process on the history, got %+v", change.Process)
+	}
+	if history[2].DeletedAt == nil || history[3].DeletedAt != nil {
+		t.Errorf("expected the process deleted and restored, got %v and %v", history[2].DeletedAt, history[3].DeletedAt)
+	}
+	for i, change := range history {
+		if change.OperationAt.IsZero() || (i > 0 && change.OperationAt.Before(history[i-1].OperationAt)) {
+			t.Errorf("expected the changes by the order they were made, got %s", change.OperationAt)
+		}
+	}
+}
+
+func testTransaction(t *testing.T, storage monitor.IStorageDB) {
+	err := storage.Transaction(func(tx monitor.IStorageDB) error {
+		create(t, tx, newProcess("job"))
+		if process, _ := tx.GetProcess("job"); process == nil {
+			t.Error("expected the process to be seen on the transaction")
+		}
+		return fmt.Errorf("rollback")
+	})
+	if err == nil || err.Error() != "rollback" {
+		t.Fatalf("expected the error of the handler, got %v", err)
+	}
+	if process := get(t, storage, "job"); process != nil {
+		t.Fatalf("expected the transaction to be rolled back, got %+v", process)
+	}
+
+	if err := storage.Transaction(func(tx monitor.IStorageDB) error {
+		create(t, tx, newProcess("job"))
+
+		// a transaction inside a transaction is the same transaction
+		return tx.Transaction(func(nested monitor.IStorageDB) error {
+			return nested.UpdateProcessStatus("job", monitor.StatusRunning, "", 0)
+		})
+	}); err != nil {
+		t.Fatalf("error committing the transaction: %s", err)
+	}
+	if process := get(t, storage, "job"); process == nil || *process.Status != monitor.StatusRunning {
+		t.Fatalf("expected the transaction to be committed, got %+v", process)
+	}
+}
@@ -1,6 +1,8 @@
 package monitor
 
 import (
+	"encoding/json"
+
 	"github.com/joaosoft/types"
 	"github.com/joaosoft/web"
 
@@ -9,56 +11,242 @@ import (
 
 type Status string
 
-type ErrorResponse struct {
-	Code    web.Status `json:"code,omitempty"`
-	Message string     `json:"message,omitempty"`
-	Cause   string     `json:"cause,omitempty"`
-}
+type BatchMode string
+
+type BatchOperationType string
+
+type ChangeOperation string
+
+// HistoryOperation is the operation that changed a process, like on the history table
+type HistoryOperation string
+
+// HealthStatus is the status of the monitor or of a component
+type HealthStatus string
+
+// FileFormat is the format of the exported and imported processes
+type FileFormat string
+
+// ConflictPolicy tells what to do when an imported process already exists
+type ConflictPolicy string
 
 type GetProcessRequest struct {
-	IdProcess string `json:"id" validate:"notzero"`
+	IdProcess string `json:"id" validate:"not-empty, error={{required}}"`
+	Include   string `json:"include" validate:"options=schedule, error={{options:schedule}}"`
+}
+
+type GetProcessesRequest struct {
+	Filter  map[string][]string
+	Include string `json:"include" validate:"options=schedule, error={{options:schedule}}"`
 }
 
 type CreateProcessRequest struct {
-	Body struct {
-		IdProcess   string         `json:"id_process" validate:"notzero"`
-		Type        string         `json:"type" validate:"notzero"`
-		Name        string         `json:"name" validate:"notzero"`
-		Description string         `json:"description"`
-		DateFrom    *types.Date    `json:"date_from" validate:"special={date}"`
-		DateTo      *types.Date    `json:"date_to" validate:"special={date}"`
-		TimeFrom    *types.Time    `json:"time_from" validate:"special={time}"`
-		TimeTo      *types.Time    `json:"time_to" validate:"special={time}"`
-		DaysOff     *types.ListDay `json:"days_off" validate:"options=monday;tuesday;wednesday;thursday;friday;saturday;sunday"`
-		Monitor     string         `json:"monitor"`
-		Status      *Status        `json:"status" validate:"options=stopped;running"`
-	}
+	Body ProcessDefinition
+}
+
+// ProcessDefinition is a process as created, also used to declare the processes to apply
+type ProcessDefinition struct {
+	IdProcess   string         `json:"id_process" validate:"not-empty, error={{required}}"`
+	Type        string         `json:"type" validate:"not-empty, error={{required}}"`
+	Name        string         `json:"name" validate:"not-empty, error={{required}}"`
+	Description string         `json:"description"`
+	DateFrom    *types.Date    `json:"date_from" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+	DateTo      *types.Date    `json:"date_to" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+	TimeFrom    *types.Time    `json:"time_from" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+	TimeTo      *types.Time    `json:"time_to" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+	DaysOff     *types.ListDay `json:"days_off" validate:"options=monday;tuesday;wednesday;thursday;friday;saturday;sunday, error={{options:monday;tuesday;wednesday;thursday;friday;saturday;sunday}}"`
+	Monitor     string         `json:"monitor"`
+	Status      *Status        `json:"status" validate:"options=stopped;running;failed, error={{options:stopped;running;failed}}"`
 }
 
 type UpdateProcessRequest struct {
-	IdProcess string `json:"id_process" validate:"notzero"`
+	IdProcess string `json:"id_process" validate:"not-empty, error={{required}}"`
 
 	Body struct {
-		Type        string         `json:"type" validate:"notzero"`
-		Name        string         `json:"name" validate:"notzero"`
+		Type        string         `json:"type" validate:"not-empty, error={{required}}"`
+		Name        string         `json:"name" validate:"not-empty, error={{required}}"`
 		Description string         `json:"description"`
-		DateFrom    *types.Date    `json:"date_from" validate:"special={date}"`
-		DateTo      *types.Date    `json:"date_to" validate:"special={date}"`
-		TimeFrom    *types.Time    `json:"time_from" validate:"special={time}"`
-		TimeTo      *types.Time    `json:"time_to" validate:"special={time}"`
-		DaysOff     *types.ListDay `json:"days_off" validate:"options=monday;tuesday;wednesday;thursday;friday;saturday;sunday"`
+		DateFrom    *types.Date    `json:"date_from" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+		DateTo      *types.Date    `json:"date_to" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+		TimeFrom    *types.Time    `json:"time_from" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+		TimeTo      *types.Time    `json:"time_to" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+		DaysOff     *types.ListDay `json:"days_off" validate:"options=monday;tuesday;wednesday;thursday;friday;saturday;sunday, error={{options:monday;tuesday;wednesday;thursday;friday;saturday;sunday}}"`
 		Monitor     string         `json:"monitor"`
-		Status      *Status        `json:"status" validate:"options=stopped;running"`
+		Status      *Status        `json:"status" validate:"options=stopped;running;failed, error={{options:stopped;running;failed}}"`
 	}
 }
 
+type PatchProcessRequest struct {
+	IdProcess   string `json:"id_process" validate:"not-empty, error={{required}}"`
+	ContentType string `json:"content_type" validate:"not-empty, error={{required}}"`
+	Patch       []byte `json:"patch" validate:"not-empty, error={{required}}"`
+}
+
 type UpdateProcessStatusRequest struct {
-	IdProcess string `json:"id_process" validate:"notzero"`
-	Status    Status `json:"status" validate:"options=stopped;running"`
+	IdProcess string `json:"id_process" validate:"not-empty, error={{required}}"`
+	Status    Status `json:"status" validate:"options=stopped;running;failed, error={{options:stopped;running;failed}}"`
+
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+type HeartbeatProcessRequest struct {
+	IdProcess string `json:"id_process" validate:"not-empty, error={{required}}"`
 }
 
 type DeleteProcessRequest struct {
-	IdProcess string `json:"id_process" validate:"notzero"`
+	IdProcess string `json:"id_process" validate:"not-empty, error={{required}}"`
+}
+
+type DeleteProcessesRequest struct {
+	Filter       map[string][]string
+	Confirmation string `json:"confirmation"`
+}
+
+type DeleteProcessesResponse struct {
+	Count        int64  `json:"count"`
+	Confirmation string `json:"confirmation,omitempty"`
+}
+
+type RestoreProcessRequest struct {
+	IdProcess string `json:"id_process" validate:"not-empty, error={{required}}"`
+}
+
+type BatchProcessesRequest struct {
+	Body struct {
+		Mode       BatchMode                `json:"mode" validate:"options=atomic;best_effort, error={{options:atomic;best_effort}}"`
+		Operations []*BatchOperationRequest `json:"operations" validate:"not-empty, error={{required}}"`
+	}
+}
+
+type BatchOperationRequest struct {
+	Op        BatchOperationType `json:"op" validate:"options=create;update;status, error={{options:create;update;status}}"`
+	IdProcess string             `json:"id_process"`
+	Version   int64              `json:"version"`
+	Status    Status             `json:"status"`
+	Message   string             `json:"message"`
+	Body      json.RawMessage    `json:"body"`
+}
+
+type BatchOperation struct {
+	Op      BatchOperationType
+	Process *Process
+	Error   error
+}
+
+type BatchOperationResult struct {
+	Index     int                `json:"index"`
+	Op        BatchOperationType `json:"op"`
+	IdProcess string             `json:"id_process,omitempty"`
+	Status    web.Status         `json:"status"`
+	Version   int64              `json:"version,omitempty"`
+	Error     *Problem           `json:"error,omitempty"`
+}
+
+type BatchProcessesResponse struct {
+	Mode      BatchMode               `json:"mode"`
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Results   []*BatchOperationResult `json:"results"`
+}
+
+type ApplyProcessesRequest struct {
+	Body struct {
+		Processes []*ProcessDefinition `json:"processes" validate:"not-empty, error={{required}}"`
+		Prune     bool                 `json:"prune"`
+		DryRun    bool                 `json:"dry_run"`
+	}
+}
+
+type ApplyProcessesResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Deleted int              `json:"deleted"`
+	Changes []*ProcessChange `json:"changes"`
+}
+
+type ExportProcessesRequest struct {
+	Filter map[string][]string
+	Format FileFormat `json:"format" validate:"options=json;yaml;csv, error={{options:json;yaml;csv}}"`
+}
+
+type ImportProcessesRequest struct {
+	Format     FileFormat     `json:"format" validate:"options=json;yaml;csv, error={{options:json;yaml;csv}}"`
+	OnConflict ConflictPolicy `json:"on_conflict" validate:"options=skip;overwrite;fail, error={{options:skip;overwrite;fail}}"`
+	DryRun     bool           `json:"dry_run"`
+	Processes  []*ProcessDefinition
+}
+
+type ImportProcessesResponse struct {
+	OnConflict ConflictPolicy   `json:"on_conflict"`
+	DryRun     bool             `json:"dry_run"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	Skipped    []string         `json:"skipped"`
+	Changes    []*ProcessChange `json:"changes"`
+}
+
+type SimulateProcessRequest struct {
+	Body struct {
+		IdProcess string         `json:"id_process"`
+		Process   *ProcessWindow `json:"process"`
+		From      time.Time      `json:"from"`
+		To        time.Time      `json:"to"`
+	}
+}
+
+// ProcessWindow is the window of a process, when it can be started
+type ProcessWindow struct {
+	DateFrom *types.Date    `json:"date_from" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+	DateTo   *types.Date    `json:"date_to" validate:"regex=^[0-9]{2}-[0-9]{2}-[0-9]{4}$, error={{format:dd-mm-yyyy}}"`
+	TimeFrom *types.Time    `json:"time_from" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+	TimeTo   *types.Time    `json:"time_to" validate:"regex=^[0-9]{2}:[0-9]{2}:[0-9]{2}$, error={{format:hh:mm:ss}}"`
+	DaysOff  *types.ListDay `json:"days_off" validate:"options=monday;tuesday;wednesday;thursday;friday;saturday;sunday, error={{options:monday;tuesday;wednesday;thursday;friday;saturday;sunday}}"`
+}
+
+type SimulateProcessResponse struct {
+	IdProcess string              `json:"id_process,omitempty"`
+	Process   *ProcessWindow      `json:"process"`
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Allowed   []*ScheduleInterval `json:"allowed"`
+	Blocked   []*ScheduleInterval `json:"blocked"`
+}
+
+// ScheduleInterval is an interval where a process can be started, or can't with the reasons
+type ScheduleInterval struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+type HealthResponse struct {
+	Status     HealthStatus                `json:"status"`
+	Components map[string]*ComponentHealth `json:"components,omitempty"`
+}
+
+type ComponentHealth struct {
+	Status HealthStatus `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+// ProcessChange is a change needed to match the definitions, with the version of the process
+// (the new version after applying an update)
+type ProcessChange struct {
+	Op        ChangeOperation `json:"op"`
+	IdProcess string          `json:"id_process"`
+	Version   int64           `json:"version,omitempty"`
+	Fields    []*FieldChange  `json:"fields,omitempty"`
+
+	definition *ProcessDefinition
+	process    *Process
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
 }
 
 type Process struct {
@@ -73,8 +261,67 @@ type Process struct {
 	DaysOff     *types.ListDay `json:"days_off"`
 	Monitor     string         `json:"monitor"`
 	Status      *Status        `json:"status"`
+	Message     string         `json:"message"`
+	HeartbeatAt *time.Time     `json:"heartbeat_at"`
+	Version     int64          `json:"version"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	CreatedAt   time.Time      `json:"created_at"`
+
+	// computed when including the schedule
+	CanExecuteNow   *bool      `json:"can_execute_now,omitempty"`
+	NextWindowStart *time.Time `json:"next_window_start,omitempty"`
+	NextWindowEnd   *time.Time `json:"next_window_end,omitempty"`
 }
 
 type ListProcess []*Process
+
+// ProcessHistory is a version of a process, recorded when it was inserted, updated or deleted
+type ProcessHistory struct {
+	Process
+	DeletedAt   *time.Time       `json:"deleted_at"`
+	Operation   HistoryOperation `json:"operation"`
+	OperationAt time.Time        `json:"operation_at"`
+}
+
+type ListProcessHistory []*ProcessHistory
+
+// ProcessRun is a run of a process, from when it was running until it was stopped or failed
+type ProcessRun struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Status    Status     `json:"status"`
+	Message   string     `json:"message"`
+}
+
+type ListProcessRun []*ProcessRun
+
+// StatusPageResponse is the public status of the processes of each group of the status page
+type StatusPageResponse struct {
+	Title       string                     `json:"title"`
+	Groups      []*StatusPageGroupResponse `json:"groups"`
+	UptimeSince time.Time                  `json:"uptime_since"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+}
+
+type StatusPageGroupResponse struct {
+	Name      string           `json:"name"`
+	Processes []*ProcessStatus `json:"processes"`
+}
+
+// ProcessStatus is the public status of a process, with the outcome of its last finished run
+// and the percentage of the uptime period it wasn't failed
+type ProcessStatus struct {
+	IdProcess     string     `json:"id_process"`
+	Name          string     `json:"name"`
+	Status        *Status    `json:"status"`
+	LastRunStatus *Status    `json:"last_run_status"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	Uptime        float64    `json:"uptime"`
+}
+
+type GetBadgeRequest struct {
+	IdProcess string `json:"id" validate:"not-empty, error={{required}}"`
+	Show      string `json:"show" validate:"options=status;last_success, error={{options:status;last_success}}"`
+	Label     string `json:"label"`
+}
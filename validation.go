@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joaosoft/validator"
+)
+
+const (
+	validationRequired = "required"
+	validationOptions  = "options"
+	validationFormat   = "format"
+	validationRange    = "range"
+)
+
+// requestValidator validates every field of the requests, returning the errors
+// set on the error tag, as error={{code:arguments}}
+var requestValidator = validator.NewValidator().
+	SetValidateAll(true).
+	SetErrorCodeHandler(fieldError)
+
+// FieldError is a validation error of a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error ...
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// ValidationError has the validation errors of a request
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error ...
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// validate validates a request, returning a *ValidationError when it's invalid
+func validate(obj interface{}) error {
+	errs := requestValidator.Validate(obj)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	validation := &ValidationError{}
+	for _, err := range errs {
+		if field, ok := err.(*FieldError); ok {
+			validation.Fields = append(validation.Fields, field)
+		} else {
+			validation.Fields = append(validation.Fields, &FieldError{Code: "invalid", Message: err.Error()})
+		}
+	}
+
+	return validation
+}
+
+func fieldError(context *validator.ValidatorContext, data *validator.ValidationData) error {
+	var arguments []string
+	for _, argument := range data.ErrorData.Arguments {
+		arguments = append(arguments, fmt.Sprint(argument))
+	}
+
+	field := &FieldError{
+		Field: data.Name,
+		Code:  data.ErrorData.Code,
+	}
+
+	switch field.Code {
+	case validationRequired:
+		field.Message = "is required"
+	case validationOptions:
+		field.Message = fmt.Sprintf("must be one of %s", strings.Join(arguments, ", "))
+	case validationFormat:
+		field.Message = fmt.Sprintf("must have the format %s", strings.Join(arguments, ";"))
+	default:
+		field.Message = "is invalid"
+	}
+
+	return field
+}